@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -17,7 +18,11 @@ import (
 )
 
 var (
-	configPath = flag.String("config", "", "path to config file")
+	configPath  = flag.String("config", "", "path to config file")
+	rotateKeys  = flag.Bool("rotate-keys", false, "re-encrypt all stored data with the current encryption key and exit")
+	backupPath  = flag.String("backup", "", "write a backup archive of all data to the path and exit (passphrase: LLM_AGENT_BACKUP_PASSPHRASE)")
+	restorePath = flag.String("restore", "", "replace all data with the backup archive at the path and exit (passphrase: LLM_AGENT_BACKUP_PASSPHRASE)")
+	reembed     = flag.Bool("reembed", false, "rebuild the vector store with the configured embedding model and exit")
 )
 
 func main() {
@@ -36,6 +41,57 @@ func main() {
 	}
 	defer deps.Close()
 
+	if *rotateKeys {
+		rotated, err := deps.ProviderService.RotateAPIKeys()
+		if err != nil {
+			log.Fatalf("Key rotation failed after %d API keys: %v", rotated, err)
+		}
+		content, err := deps.DB.EncryptContent(true)
+		if err != nil {
+			log.Fatalf("Key rotation failed after %d values: %v", content, err)
+		}
+		if err := deps.VectorStore.Rewrite(); err != nil {
+			log.Fatalf("Key rotation failed for the vector store: %v", err)
+		}
+		fmt.Printf("Re-encrypted %d API keys and %d values with key %s, previous keys can now be removed\n", rotated, content, deps.Encryptor.CurrentKeyID())
+		return
+	}
+
+	if *reembed {
+		count, err := deps.MemoryManager.Reembed(context.Background())
+		if err != nil {
+			log.Fatalf("Re-embedding failed: %v", err)
+		}
+		fmt.Printf("Re-embedded %d knowledge entries\n", count)
+		return
+	}
+
+	if *backupPath != "" {
+		file, err := os.Create(*backupPath)
+		if err != nil {
+			log.Fatalf("Backup failed: %v", err)
+		}
+		manifest, err := deps.BackupService.Backup(file, os.Getenv("LLM_AGENT_BACKUP_PASSPHRASE"))
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(*backupPath)
+			log.Fatalf("Backup failed: %v", err)
+		}
+		fmt.Printf("Wrote backup with %d documents to %s (key %s)\n", manifest.Documents, *backupPath, manifest.KeyID)
+		return
+	}
+
+	if *restorePath != "" {
+		result, err := deps.BackupService.Restore(*restorePath, os.Getenv("LLM_AGENT_BACKUP_PASSPHRASE"))
+		if err != nil {
+			log.Fatalf("Restore failed: %v", err)
+		}
+		fmt.Printf("Restored backup of %s with %d documents\n", result.Manifest.CreatedAt.Format(time.RFC3339), result.Documents)
+		return
+	}
+
 	// Setup Gin
 	if cfg.Server.Mode == "release" {
 		gin.SetMode(gin.ReleaseMode)
@@ -44,16 +100,14 @@ func main() {
 	r := gin.Default()
 
 	// CORS middleware
-	r.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
+	r.Use(server.CORS(cfg.Server.CORSOrigins))
+
+	if !cfg.Auth.Enabled && !isLoopback(cfg.Server.Host) {
+		if cfg.Server.Mode == "release" {
+			log.Fatalf("Refusing to listen on %q without authentication in release mode, anyone on the network could use the API. Set auth.enabled or bind to 127.0.0.1.", cfg.Server.Host)
 		}
-		c.Next()
-	})
+		log.Printf("WARNING: Listening on %q without authentication, anyone on the network can use the API. Set auth.enabled or bind to 127.0.0.1.", cfg.Server.Host)
+	}
 
 	// Serve static files for frontend
 	r.Static("/assets", "./web/dist/assets")
@@ -101,3 +155,12 @@ func main() {
 		log.Printf("Server shutdown error: %v", err)
 	}
 }
+
+// isLoopback returns true if the listen host only accepts local connections
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
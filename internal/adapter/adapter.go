@@ -2,8 +2,11 @@ package adapter
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/httpclient"
+	"github.com/allwaysyou/llm-agent/internal/pkg/jsonschema"
 )
 
 // LLMAdapter defines the interface for LLM providers
@@ -11,6 +14,11 @@ type LLMAdapter interface {
 	// Chat sends messages and returns a complete response
 	Chat(ctx context.Context, messages []model.Message) (*model.ChatResponse, error)
 
+	// ChatJSON sends messages and returns a response whose content is JSON following
+	// the format's schema, using the provider's native structured output mode.
+	// Callers should use ChatStructured, which validates and repairs the output.
+	ChatJSON(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error)
+
 	// ChatStream sends messages and returns a channel for streaming response
 	ChatStream(ctx context.Context, messages []model.Message) (<-chan model.StreamChunk, error)
 
@@ -27,13 +35,29 @@ type LLMAdapter interface {
 	Provider() model.LLMProvider
 }
 
+// ModelLister is implemented by adapters that can list the models offered by their provider
+type ModelLister interface {
+	// ListModels returns the models available to the configured credentials
+	ListModels(ctx context.Context) ([]model.ModelInfo, error)
+}
+
 // AdapterConfig holds configuration for creating an adapter
 type AdapterConfig struct {
-	APIKey      string
-	BaseURL     string
-	Model       string
-	MaxTokens   int
-	Temperature float64
+	APIKey     string
+	BaseURL    string
+	Model      string
+	MaxTokens  int
+	Sampling   model.SamplingParams  // Unset fields use the provider's defaults
+	HTTPClient *http.Client          // Optional: defaults to httpclient.Default()
+	Options    model.ProviderOptions // Custom provider settings
+}
+
+// httpClient returns the configured HTTP client or the shared default one
+func (c AdapterConfig) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return httpclient.Default()
 }
 
 // AdapterFactory creates adapters based on provider type
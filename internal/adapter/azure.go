@@ -1,7 +1,6 @@
 package adapter
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -11,12 +10,11 @@ import (
 	"strings"
 
 	"github.com/allwaysyou/llm-agent/internal/model"
-	"github.com/google/uuid"
+	"github.com/allwaysyou/llm-agent/internal/pkg/jsonschema"
+	"github.com/allwaysyou/llm-agent/internal/pkg/modelinfo"
 )
 
-const (
-	azureAPIVersion = "2024-02-01"
-)
+const azureAPIVersion = "2024-10-21" // First GA version with json_schema structured outputs
 
 // AzureAdapter implements LLMAdapter for Azure OpenAI API
 type AzureAdapter struct {
@@ -24,7 +22,8 @@ type AzureAdapter struct {
 	baseURL      string // Azure endpoint: https://{resource}.openai.azure.com
 	deploymentID string // Azure deployment name
 	maxTokens    int
-	temperature  float64
+	sampling     model.SamplingParams
+	reasoning    bool // Deployed model accepts reasoning_effort
 	client       *http.Client
 }
 
@@ -43,18 +42,14 @@ func NewAzureAdapter(cfg AdapterConfig) (LLMAdapter, error) {
 		maxTokens = 4096
 	}
 
-	temperature := cfg.Temperature
-	if temperature == 0 {
-		temperature = 0.7
-	}
-
 	return &AzureAdapter{
 		apiKey:       cfg.APIKey,
 		baseURL:      strings.TrimSuffix(cfg.BaseURL, "/"),
 		deploymentID: cfg.Model,
 		maxTokens:    maxTokens,
-		temperature:  temperature,
-		client:       &http.Client{},
+		sampling:     cfg.Sampling,
+		reasoning:    cfg.Options.Capabilities.Reasoning,
+		client:       cfg.httpClient(),
 	}, nil
 }
 
@@ -64,12 +59,22 @@ func (a *AzureAdapter) buildURL(endpoint string) string {
 }
 
 func (a *AzureAdapter) Chat(ctx context.Context, messages []model.Message) (*model.ChatResponse, error) {
+	return a.chat(ctx, messages, nil)
+}
+
+// ChatJSON requests structured output through response_format json_schema
+func (a *AzureAdapter) ChatJSON(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error) {
+	return a.chat(ctx, messages, format)
+}
+
+func (a *AzureAdapter) chat(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error) {
 	reqBody := openaiRequest{
-		Model:       a.deploymentID,
-		Messages:    convertToOpenAIMessages(messages),
-		MaxTokens:   a.maxTokens,
-		Temperature: a.temperature,
-		Stream:      false,
+		Model:          a.deploymentID,
+		Messages:       convertToOpenAIMessages(messages),
+		MaxTokens:      a.maxTokens,
+		openaiSampling: newOpenAISampling(a.sampling, a.reasoning),
+		Stream:         false,
+		ResponseFormat: newOpenAIResponseFormat(format),
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -93,39 +98,19 @@ func (a *AzureAdapter) Chat(ctx context.Context, messages []model.Message) (*mod
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var openaiResp openaiResponse
-	if err := json.NewDecoder(resp.Body).Decode(&openaiResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if len(openaiResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
+		return nil, newAPIError(model.ProviderAzure, resp, bodyBytes)
 	}
 
-	return &model.ChatResponse{
-		ID: openaiResp.ID,
-		Message: model.Message{
-			Role:    model.MessageRole(openaiResp.Choices[0].Message.Role),
-			Content: openaiResp.Choices[0].Message.Content,
-		},
-		Usage: &model.Usage{
-			PromptTokens:     openaiResp.Usage.PromptTokens,
-			CompletionTokens: openaiResp.Usage.CompletionTokens,
-			TotalTokens:      openaiResp.Usage.TotalTokens,
-		},
-	}, nil
+	return decodeOpenAIResponse(resp.Body)
 }
 
 func (a *AzureAdapter) ChatStream(ctx context.Context, messages []model.Message) (<-chan model.StreamChunk, error) {
 	reqBody := openaiRequest{
-		Model:       a.deploymentID,
-		Messages:    convertToOpenAIMessages(messages),
-		MaxTokens:   a.maxTokens,
-		Temperature: a.temperature,
-		Stream:      true,
+		Model:          a.deploymentID,
+		Messages:       convertToOpenAIMessages(messages),
+		MaxTokens:      a.maxTokens,
+		openaiSampling: newOpenAISampling(a.sampling, a.reasoning),
+		Stream:         true,
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -150,72 +135,11 @@ func (a *AzureAdapter) ChatStream(ctx context.Context, messages []model.Message)
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(model.ProviderAzure, resp, bodyBytes)
 	}
 
 	ch := make(chan model.StreamChunk, 100)
-
-	go func() {
-		defer close(ch)
-		defer resp.Body.Close()
-
-		reader := bufio.NewReader(resp.Body)
-		id := uuid.New().String()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
-
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err != io.EOF {
-					ch <- model.StreamChunk{ID: id, Done: true}
-				}
-				return
-			}
-
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
-
-			if !strings.HasPrefix(line, "data: ") {
-				continue
-			}
-
-			data := strings.TrimPrefix(line, "data: ")
-			if data == "[DONE]" {
-				ch <- model.StreamChunk{ID: id, Done: true}
-				return
-			}
-
-			var streamResp openaiStreamResponse
-			if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
-				continue
-			}
-
-			if len(streamResp.Choices) > 0 {
-				chunk := model.StreamChunk{
-					ID:    id,
-					Delta: streamResp.Choices[0].Delta.Content,
-					Done:  streamResp.Choices[0].FinishReason != "",
-				}
-
-				if streamResp.Usage != nil {
-					chunk.Usage = &model.Usage{
-						PromptTokens:     streamResp.Usage.PromptTokens,
-						CompletionTokens: streamResp.Usage.CompletionTokens,
-						TotalTokens:      streamResp.Usage.TotalTokens,
-					}
-				}
-
-				ch <- chunk
-			}
-		}
-	}()
+	go streamOpenAIResponse(ctx, resp.Body, ch)
 
 	return ch, nil
 }
@@ -247,7 +171,7 @@ func (a *AzureAdapter) GetEmbedding(ctx context.Context, text string) ([]float32
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(model.ProviderAzure, resp, bodyBytes)
 	}
 
 	var embResp openaiEmbeddingResponse
@@ -262,6 +186,46 @@ func (a *AzureAdapter) GetEmbedding(ctx context.Context, text string) ([]float32
 	return embResp.Data[0].Embedding, nil
 }
 
+// ListModels returns the models the Azure OpenAI resource can serve. The data plane
+// no longer lists deployments, so a model config still needs the deployment name,
+// which is often the model name.
+func (a *AzureAdapter) ListModels(ctx context.Context) ([]model.ModelInfo, error) {
+	url := fmt.Sprintf("%s/openai/models?api-version=%s", a.baseURL, azureAPIVersion)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("api-key", a.apiKey)
+
+	var list struct {
+		Data []struct {
+			ID           string `json:"id"`
+			Capabilities struct {
+				ChatCompletion bool `json:"chat_completion"`
+				Embeddings     bool `json:"embeddings"`
+				Inference      bool `json:"inference"`
+			} `json:"capabilities"`
+		} `json:"data"`
+	}
+	if err := fetchModelList(a.client, req, model.ProviderAzure, &list); err != nil {
+		return nil, err
+	}
+
+	models := make([]model.ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		// Models that cannot be deployed (such as fine-tuning bases) are of no use here
+		if !m.Capabilities.Inference {
+			continue
+		}
+		info := modelinfo.Describe(m.ID)
+		info.Chat = m.Capabilities.ChatCompletion
+		info.Embedding = m.Capabilities.Embeddings
+		models = append(models, info)
+	}
+	sortModels(models)
+	return models, nil
+}
+
 func (a *AzureAdapter) CountTokens(text string) int {
 	return len(text) / 4
 }
@@ -11,6 +11,8 @@ import (
 	"strings"
 
 	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/jsonschema"
+	"github.com/allwaysyou/llm-agent/internal/pkg/modelinfo"
 	"github.com/google/uuid"
 )
 
@@ -22,12 +24,12 @@ const (
 
 // ClaudeAdapter implements LLMAdapter for Anthropic Claude API
 type ClaudeAdapter struct {
-	apiKey      string
-	baseURL     string
-	model       string
-	maxTokens   int
-	temperature float64
-	client      *http.Client
+	apiKey    string
+	baseURL   string
+	model     string
+	maxTokens int
+	sampling  model.SamplingParams
+	client    *http.Client
 }
 
 // NewClaudeAdapter creates a new Claude adapter
@@ -47,31 +49,56 @@ func NewClaudeAdapter(cfg AdapterConfig) (LLMAdapter, error) {
 		maxTokens = 4096
 	}
 
-	temperature := cfg.Temperature
-	if temperature == 0 {
-		temperature = 0.7
-	}
-
 	return &ClaudeAdapter{
-		apiKey:      cfg.APIKey,
-		baseURL:     strings.TrimSuffix(baseURL, "/"),
-		model:       modelName,
-		maxTokens:   maxTokens,
-		temperature: temperature,
-		client:      &http.Client{},
+		apiKey:    cfg.APIKey,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		model:     modelName,
+		maxTokens: maxTokens,
+		sampling:  cfg.Sampling,
+		client:    cfg.httpClient(),
 	}, nil
 }
 
 // claudeRequest represents a Claude API request
 type claudeRequest struct {
-	Model       string          `json:"model"`
-	MaxTokens   int             `json:"max_tokens"`
-	Messages    []claudeMessage `json:"messages"`
-	System      string          `json:"system,omitempty"`
-	Temperature float64         `json:"temperature,omitempty"`
-	Stream      bool            `json:"stream,omitempty"`
+	Model         string            `json:"model"`
+	MaxTokens     int               `json:"max_tokens"`
+	Messages      []claudeMessage   `json:"messages"`
+	System        string            `json:"system,omitempty"`
+	Temperature   *float64          `json:"temperature,omitempty"`
+	TopP          *float64          `json:"top_p,omitempty"`
+	TopK          *int              `json:"top_k,omitempty"`
+	StopSequences []string          `json:"stop_sequences,omitempty"`
+	Thinking      *claudeThinking   `json:"thinking,omitempty"`
+	Tools         []claudeTool      `json:"tools,omitempty"`
+	ToolChoice    *claudeToolChoice `json:"tool_choice,omitempty"`
+	Stream        bool              `json:"stream,omitempty"`
+}
+
+// claudeTool defines a tool the model may call; used to force structured output
+type claudeTool struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	InputSchema *jsonschema.Schema `json:"input_schema"`
+}
+
+type claudeToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
+// claudeThinking enables extended thinking
+type claudeThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// claudeMinThinkingBudget is the smallest thinking budget the API accepts
+const claudeMinThinkingBudget = 1024
+
+// claudeMinThinkingTopP is the smallest top_p the API accepts with thinking enabled
+const claudeMinThinkingTopP = 0.95
+
 type claudeMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
@@ -83,8 +110,11 @@ type claudeResponse struct {
 	Type    string `json:"type"`
 	Role    string `json:"role"`
 	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
+		Type     string          `json:"type"`
+		Text     string          `json:"text"`
+		Thinking string          `json:"thinking,omitempty"` // thinking blocks
+		Name     string          `json:"name,omitempty"`     // tool_use blocks
+		Input    json.RawMessage `json:"input,omitempty"`    // tool_use blocks
 	} `json:"content"`
 	StopReason string `json:"stop_reason"`
 	Usage      struct {
@@ -98,8 +128,9 @@ type claudeStreamEvent struct {
 	Type  string `json:"type"`
 	Index int    `json:"index,omitempty"`
 	Delta struct {
-		Type string `json:"type,omitempty"`
-		Text string `json:"text,omitempty"`
+		Type     string `json:"type,omitempty"`
+		Text     string `json:"text,omitempty"`
+		Thinking string `json:"thinking,omitempty"`
 	} `json:"delta,omitempty"`
 	Message *claudeResponse `json:"message,omitempty"`
 	Usage   *struct {
@@ -107,18 +138,71 @@ type claudeStreamEvent struct {
 	} `json:"usage,omitempty"`
 }
 
-func (a *ClaudeAdapter) Chat(ctx context.Context, messages []model.Message) (*model.ChatResponse, error) {
+// buildRequest creates the request body, mapping sampling params to Claude's field names.
+// Penalties and seed are not supported by Claude and are left out.
+func (a *ClaudeAdapter) buildRequest(messages []model.Message, stream bool) claudeRequest {
 	claudeMessages, systemPrompt := convertToClaudeMessages(messages)
 
 	reqBody := claudeRequest{
-		Model:       a.model,
-		MaxTokens:   a.maxTokens,
-		Messages:    claudeMessages,
-		System:      systemPrompt,
-		Temperature: a.temperature,
-		Stream:      false,
+		Model:         a.model,
+		MaxTokens:     a.maxTokens,
+		Messages:      claudeMessages,
+		System:        systemPrompt,
+		Temperature:   a.sampling.Temperature,
+		TopP:          a.sampling.TopP,
+		TopK:          a.sampling.TopK,
+		StopSequences: a.sampling.Stop,
+		Stream:        stream,
 	}
 
+	if budget := a.sampling.ThinkingTokens(); budget > 0 {
+		if budget < claudeMinThinkingBudget {
+			budget = claudeMinThinkingBudget
+		}
+		reqBody.Thinking = &claudeThinking{Type: "enabled", BudgetTokens: budget}
+		// Thinking counts against max_tokens, is incompatible with temperature and top_k
+		// and only accepts top_p between 0.95 and 1
+		if reqBody.MaxTokens <= budget {
+			reqBody.MaxTokens = budget + a.maxTokens
+		}
+		reqBody.Temperature = nil
+		reqBody.TopK = nil
+		if reqBody.TopP != nil && *reqBody.TopP < claudeMinThinkingTopP {
+			topP := claudeMinThinkingTopP
+			reqBody.TopP = &topP
+		}
+	}
+
+	return reqBody
+}
+
+func (a *ClaudeAdapter) Chat(ctx context.Context, messages []model.Message) (*model.ChatResponse, error) {
+	return a.chat(ctx, a.buildRequest(messages, false), "")
+}
+
+// ChatJSON forces a call to a tool whose input schema is the requested format and
+// returns the tool input as the message content
+func (a *ClaudeAdapter) ChatJSON(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error) {
+	reqBody := a.buildRequest(messages, false)
+	reqBody.Tools = []claudeTool{{
+		Name:        format.Name,
+		Description: format.Description,
+		InputSchema: format.Schema,
+	}}
+	reqBody.ToolChoice = &claudeToolChoice{Type: "tool", Name: format.Name}
+
+	// Forced tool use is incompatible with extended thinking
+	reqBody.Thinking = nil
+	reqBody.MaxTokens = a.maxTokens
+	reqBody.Temperature = a.sampling.Temperature
+	reqBody.TopK = a.sampling.TopK
+
+	return a.chat(ctx, reqBody, format.Name)
+}
+
+// chat sends a non-streaming request. When toolName is set, the input of that
+// tool call is returned as the content instead of the text blocks.
+func (a *ClaudeAdapter) chat(ctx context.Context, reqBody claudeRequest, toolName string) (*model.ChatResponse, error) {
 	body, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -141,7 +225,7 @@ func (a *ClaudeAdapter) Chat(ctx context.Context, messages []model.Message) (*mo
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(model.ProviderClaude, resp, bodyBytes)
 	}
 
 	var claudeResp claudeResponse
@@ -149,18 +233,24 @@ func (a *ClaudeAdapter) Chat(ctx context.Context, messages []model.Message) (*mo
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	content := ""
+	content, reasoning := "", ""
 	for _, c := range claudeResp.Content {
-		if c.Type == "text" {
+		switch {
+		case toolName != "" && c.Type == "tool_use" && c.Name == toolName:
+			content = string(c.Input)
+		case toolName == "" && c.Type == "text":
 			content += c.Text
+		case c.Type == "thinking":
+			reasoning += c.Thinking
 		}
 	}
 
 	return &model.ChatResponse{
 		ID: claudeResp.ID,
 		Message: model.Message{
-			Role:    model.RoleAssistant,
-			Content: content,
+			Role:      model.RoleAssistant,
+			Content:   content,
+			Reasoning: reasoning,
 		},
 		Usage: &model.Usage{
 			PromptTokens:     claudeResp.Usage.InputTokens,
@@ -171,16 +261,7 @@ func (a *ClaudeAdapter) Chat(ctx context.Context, messages []model.Message) (*mo
 }
 
 func (a *ClaudeAdapter) ChatStream(ctx context.Context, messages []model.Message) (<-chan model.StreamChunk, error) {
-	claudeMessages, systemPrompt := convertToClaudeMessages(messages)
-
-	reqBody := claudeRequest{
-		Model:       a.model,
-		MaxTokens:   a.maxTokens,
-		Messages:    claudeMessages,
-		System:      systemPrompt,
-		Temperature: a.temperature,
-		Stream:      true,
-	}
+	reqBody := a.buildRequest(messages, true)
 
 	body, err := json.Marshal(reqBody)
 	if err != nil {
@@ -205,7 +286,7 @@ func (a *ClaudeAdapter) ChatStream(ctx context.Context, messages []model.Message
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(model.ProviderClaude, resp, bodyBytes)
 	}
 
 	ch := make(chan model.StreamChunk, 100)
@@ -216,6 +297,7 @@ func (a *ClaudeAdapter) ChatStream(ctx context.Context, messages []model.Message
 
 		reader := bufio.NewReader(resp.Body)
 		id := uuid.New().String()
+		usage := &model.Usage{}
 
 		for {
 			select {
@@ -249,16 +331,28 @@ func (a *ClaudeAdapter) ChatStream(ctx context.Context, messages []model.Message
 			}
 
 			switch event.Type {
+			case "message_start":
+				if event.Message != nil {
+					usage.PromptTokens = event.Message.Usage.InputTokens
+				}
+			case "message_delta":
+				if event.Usage != nil {
+					usage.CompletionTokens = event.Usage.OutputTokens
+				}
 			case "content_block_delta":
-				if event.Delta.Type == "text_delta" {
+				switch event.Delta.Type {
+				case "text_delta":
 					ch <- model.StreamChunk{
 						ID:    id,
 						Delta: event.Delta.Text,
 						Done:  false,
 					}
+				case "thinking_delta":
+					ch <- model.StreamChunk{ID: id, Reasoning: event.Delta.Thinking}
 				}
 			case "message_stop":
-				ch <- model.StreamChunk{ID: id, Done: true}
+				usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+				ch <- model.StreamChunk{ID: id, Done: true, Usage: usage}
 				return
 			}
 		}
@@ -273,6 +367,35 @@ func (a *ClaudeAdapter) GetEmbedding(ctx context.Context, text string) ([]float3
 	return nil, fmt.Errorf("Claude does not support embeddings, use OpenAI embedding provider instead")
 }
 
+// ListModels returns the models available from the /models endpoint
+func (a *ClaudeAdapter) ListModels(ctx context.Context) ([]model.ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.baseURL+"/models?limit=1000", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("x-api-key", a.apiKey)
+	req.Header.Set("anthropic-version", claudeAPIVersion)
+
+	var list struct {
+		Data []struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"display_name"`
+		} `json:"data"`
+	}
+	if err := fetchModelList(a.client, req, model.ProviderClaude, &list); err != nil {
+		return nil, err
+	}
+
+	models := make([]model.ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		info := modelinfo.Describe(m.ID)
+		info.DisplayName = m.DisplayName
+		models = append(models, info)
+	}
+	sortModels(models)
+	return models, nil
+}
+
 func (a *ClaudeAdapter) CountTokens(text string) int {
 	// Rough estimation for Claude
 	return len(text) / 4
@@ -0,0 +1,283 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/jsonschema"
+)
+
+const (
+	defaultCustomChatPath       = "/chat/completions"
+	defaultCustomEmbeddingsPath = "/embeddings"
+	defaultCustomModelsPath     = "/models"
+)
+
+// CustomAdapter implements LLMAdapter for arbitrary OpenAI-compatible endpoints
+// (vLLM, LM Studio, DeepSeek, Moonshot, ...), configured through model.ProviderOptions
+type CustomAdapter struct {
+	apiKey    string
+	baseURL   string
+	model     string
+	maxTokens int
+	sampling  model.SamplingParams
+	options   model.ProviderOptions
+	client    *http.Client
+}
+
+// NewCustomAdapter creates a new custom OpenAI-compatible adapter
+func NewCustomAdapter(cfg AdapterConfig) (LLMAdapter, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("custom provider base_url is required")
+	}
+
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("custom provider model is required")
+	}
+
+	options := cfg.Options
+	if options.AuthScheme == "" {
+		options.AuthScheme = model.AuthSchemeBearer
+	}
+	if options.AuthScheme == model.AuthSchemeHeader && options.AuthHeader == "" {
+		return nil, fmt.Errorf("auth_header is required for the header auth scheme")
+	}
+	if options.ChatPath == "" {
+		options.ChatPath = defaultCustomChatPath
+	}
+	if options.EmbeddingsPath == "" {
+		options.EmbeddingsPath = defaultCustomEmbeddingsPath
+	}
+	if options.ModelsPath == "" {
+		// A chat_path such as /api/v1/chat/completions has the models next to it
+		prefix, _ := strings.CutSuffix(options.ChatPath, defaultCustomChatPath)
+		if prefix == options.ChatPath {
+			prefix = ""
+		}
+		options.ModelsPath = prefix + defaultCustomModelsPath
+	}
+
+	maxTokens := cfg.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = 4096
+	}
+
+	return &CustomAdapter{
+		apiKey:    cfg.APIKey,
+		baseURL:   strings.TrimSuffix(cfg.BaseURL, "/"),
+		model:     cfg.Model,
+		maxTokens: maxTokens,
+		sampling:  cfg.Sampling,
+		options:   options,
+		client:    cfg.httpClient(),
+	}, nil
+}
+
+// openaiSampling maps the sampling params, passing top_k through as most
+// OpenAI-compatible servers accept it
+func (a *CustomAdapter) openaiSampling() openaiSampling {
+	sampling := newOpenAISampling(a.sampling, a.options.Capabilities.Reasoning)
+	sampling.TopK = a.sampling.TopK
+	return sampling
+}
+
+// newRequest creates a request with the configured auth and extra headers
+func (a *CustomAdapter) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range a.options.Headers {
+		req.Header.Set(key, value)
+	}
+
+	if a.apiKey != "" {
+		switch a.options.AuthScheme {
+		case model.AuthSchemeBearer:
+			req.Header.Set("Authorization", "Bearer "+a.apiKey)
+		case model.AuthSchemeHeader:
+			req.Header.Set(a.options.AuthHeader, a.apiKey)
+		}
+	}
+
+	return req, nil
+}
+
+func (a *CustomAdapter) Chat(ctx context.Context, messages []model.Message) (*model.ChatResponse, error) {
+	return a.chat(ctx, messages, nil)
+}
+
+// ChatJSON requests structured output through response_format json_schema, or through a
+// forced call of a function taking the schema if the endpoint declares tool support, as
+// compatible servers support function calling more widely
+func (a *CustomAdapter) ChatJSON(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error) {
+	return a.chat(ctx, messages, format)
+}
+
+func (a *CustomAdapter) chat(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error) {
+	reqBody := openaiRequest{
+		Model:          a.model,
+		Messages:       convertToOpenAIMessages(messages),
+		MaxTokens:      a.maxTokens,
+		openaiSampling: a.openaiSampling(),
+		Stream:         false,
+		ResponseFormat: newOpenAIResponseFormat(format),
+	}
+	if format != nil && a.options.Capabilities.Tools {
+		function := openaiFunction{Name: format.Name, Description: format.Description, Parameters: format.Schema}
+		reqBody.ResponseFormat = nil
+		reqBody.Tools = []openaiTool{{Type: "function", Function: function}}
+		reqBody.ToolChoice = &openaiToolChoice{Type: "function", Function: openaiFunction{Name: format.Name}}
+	}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := a.newRequest(ctx, "POST", a.options.ChatPath, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(model.ProviderCustom, resp, bodyBytes)
+	}
+
+	return decodeOpenAIResponse(resp.Body)
+}
+
+func (a *CustomAdapter) ChatStream(ctx context.Context, messages []model.Message) (<-chan model.StreamChunk, error) {
+	reqBody := openaiRequest{
+		Model:          a.model,
+		Messages:       convertToOpenAIMessages(messages),
+		MaxTokens:      a.maxTokens,
+		openaiSampling: a.openaiSampling(),
+		Stream:         true,
+	}
+	// Many compatible servers reject unknown fields, so only ask for usage when supported
+	if a.options.Capabilities.StreamUsage {
+		reqBody.StreamOptions = &openaiStreamOptions{IncludeUsage: true}
+	}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := a.newRequest(ctx, "POST", a.options.ChatPath, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, newAPIError(model.ProviderCustom, resp, bodyBytes)
+	}
+
+	ch := make(chan model.StreamChunk, 100)
+	go streamOpenAIResponse(ctx, resp.Body, ch)
+
+	return ch, nil
+}
+
+func (a *CustomAdapter) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	if !a.options.Capabilities.Embeddings {
+		return nil, fmt.Errorf("custom provider does not support embeddings (enable capabilities.embeddings)")
+	}
+
+	embeddingModel := a.options.EmbeddingModel
+	if embeddingModel == "" {
+		embeddingModel = a.model
+	}
+
+	body, err := json.Marshal(openaiEmbeddingRequest{Model: embeddingModel, Input: text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := a.newRequest(ctx, "POST", a.options.EmbeddingsPath, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(model.ProviderCustom, resp, bodyBytes)
+	}
+
+	var embResp openaiEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(embResp.Data) == 0 {
+		return nil, fmt.Errorf("no embedding data in response")
+	}
+
+	return embResp.Data[0].Embedding, nil
+}
+
+// ListModels returns the models available from the OpenAI-compatible models endpoint
+func (a *CustomAdapter) ListModels(ctx context.Context) ([]model.ModelInfo, error) {
+	req, err := a.newRequest(ctx, "GET", a.options.ModelsPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var list openaiModelList
+	if err := fetchModelList(a.client, req, model.ProviderCustom, &list); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		ids = append(ids, m.ID)
+	}
+	return describeModels(ids), nil
+}
+
+func (a *CustomAdapter) CountTokens(text string) int {
+	// Rough estimation: ~4 characters per token
+	return len(text) / 4
+}
+
+func (a *CustomAdapter) Name() string {
+	return "custom"
+}
+
+func (a *CustomAdapter) Provider() model.LLMProvider {
+	return model.ProviderCustom
+}
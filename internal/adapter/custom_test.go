@@ -0,0 +1,121 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/jsonschema"
+)
+
+// stubCustom starts a stub OpenAI-compatible endpoint answering every request with body,
+// and records the path and decoded body of the last request
+func stubCustom(t *testing.T, body string) (*httptest.Server, *http.Request, map[string]any) {
+	t.Helper()
+	last := &http.Request{}
+	sent := map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*last = *r.Clone(context.Background())
+		data, _ := io.ReadAll(r.Body)
+		clear(sent)
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &sent); err != nil {
+				t.Errorf("request body: %v", err)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, last, sent
+}
+
+var answerFormat = &jsonschema.Format{
+	Name: "answer",
+	Schema: &jsonschema.Schema{
+		Type:       "object",
+		Properties: map[string]*jsonschema.Schema{"city": {Type: "string"}},
+		Required:   []string{"city"},
+	},
+}
+
+func TestCustomChatJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		tools bool
+		body  string
+	}{
+		{"response format", false, `{"id":"1","choices":[{"message":{"role":"assistant","content":"{\"city\":\"Paris\"}"}}]}`},
+		{"forced function call", true, `{"id":"1","choices":[{"message":{"role":"assistant","content":null,"tool_calls":[{"id":"c1","type":"function","function":{"name":"answer","arguments":"{\"city\":\"Paris\"}"}}]}}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _, sent := stubCustom(t, tt.body)
+			llm, err := NewCustomAdapter(AdapterConfig{
+				BaseURL: server.URL,
+				Model:   "qwen",
+				Options: model.ProviderOptions{Capabilities: model.ProviderCapabilities{Tools: tt.tools}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out struct {
+				City string `json:"city"`
+			}
+			if err := ChatStructured(context.Background(), llm, geminiPrompt, answerFormat, &out); err != nil {
+				t.Fatal(err)
+			}
+			if out.City != "Paris" {
+				t.Errorf("City = %q", out.City)
+			}
+
+			_, hasFormat := sent["response_format"]
+			_, hasTools := sent["tools"]
+			if hasFormat == tt.tools || hasTools != tt.tools {
+				t.Errorf("sent response_format=%v tools=%v, want tools=%v only", hasFormat, hasTools, tt.tools)
+			}
+		})
+	}
+}
+
+func TestCustomListModelsPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  model.ProviderOptions
+		wantPath string
+	}{
+		{"default", model.ProviderOptions{}, "/models"},
+		{"next to chat path", model.ProviderOptions{ChatPath: "/api/v1/chat/completions"}, "/api/v1/models"},
+		{"unrelated chat path", model.ProviderOptions{ChatPath: "/generate"}, "/models"},
+		{"override", model.ProviderOptions{ChatPath: "/api/v1/chat/completions", ModelsPath: "/catalog"}, "/catalog"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, last, _ := stubCustom(t, `{"data":[{"id":"qwen"}]}`)
+			tt.options.AuthScheme = model.AuthSchemeNone
+			llm, err := NewCustomAdapter(AdapterConfig{BaseURL: server.URL, Model: "qwen", Options: tt.options})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			models, err := llm.(ModelLister).ListModels(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(models) != 1 || models[0].ID != "qwen" {
+				t.Errorf("models = %+v", models)
+			}
+			if last.URL.Path != tt.wantPath {
+				t.Errorf("path = %s, want %s", last.URL.Path, tt.wantPath)
+			}
+			if auth := last.Header.Get("Authorization"); auth != "" {
+				t.Errorf("Authorization = %q, want none", auth)
+			}
+		})
+	}
+}
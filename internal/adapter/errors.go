@@ -0,0 +1,197 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/httpclient"
+)
+
+// ErrorKind classifies why a provider rejected a request
+type ErrorKind string
+
+const (
+	ErrorKindAuth           ErrorKind = "auth"            // Invalid or missing API key
+	ErrorKindPermission     ErrorKind = "permission"      // Key lacks access to the model/resource
+	ErrorKindNotFound       ErrorKind = "not_found"       // Unknown model, deployment or endpoint
+	ErrorKindRateLimit      ErrorKind = "rate_limit"      // Rate limited, may succeed later
+	ErrorKindQuota          ErrorKind = "quota"           // Out of credits or billing quota, fails until topped up
+	ErrorKindContextLength  ErrorKind = "context_length"  // Prompt exceeds the model's context window
+	ErrorKindContentFilter  ErrorKind = "content_filter"  // Rejected by the provider's safety system
+	ErrorKindInvalidRequest ErrorKind = "invalid_request" // Malformed request or unsupported parameter
+	ErrorKindServer         ErrorKind = "server"          // Provider-side failure or overload
+	ErrorKindUnknown        ErrorKind = "unknown"
+)
+
+// APIError is returned when a provider responds with a non-success status
+type APIError struct {
+	Provider   model.LLMProvider
+	StatusCode int
+	Kind       ErrorKind
+	Code       string        // Provider error code or type, e.g. "context_length_exceeded"
+	Message    string        // Provider error message
+	RetryAfter time.Duration // From the Retry-After headers, 0 if not given
+	RawBody    string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.RawBody
+	}
+	return fmt.Sprintf("%s API error (status %d, %s): %s", e.Provider, e.StatusCode, e.Kind, msg)
+}
+
+// IsRetryable reports whether a failed call may succeed when retried or sent to another
+// provider: network errors, rate limiting and provider-side failures
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind == ErrorKindRateLimit || apiErr.Kind == ErrorKindServer
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// CanFailover reports whether a failed call may succeed with another provider: retryable
+// errors and an exhausted quota of this provider
+func CanFailover(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Kind == ErrorKindQuota {
+		return true
+	}
+	return IsRetryable(err)
+}
+
+// IsLimitError reports whether a call failed because a usage limit enforced before the
+// call (see UsageLimit), the provider's rate limit or its quota was reached. Background
+// work should stop on such errors rather than carry on without the call.
+func IsLimitError(err error) bool {
+	var limit interface{ UsageLimit() bool }
+	if errors.As(err, &limit) && limit.UsageLimit() {
+		return true
+	}
+
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.Kind == ErrorKindRateLimit || apiErr.Kind == ErrorKindQuota)
+}
+
+// providerErrorBody covers the error payloads of the supported providers:
+// OpenAI/Azure {"error":{"message","type","code"}}, Claude {"type":"error","error":{"type","message"}},
+// Gemini {"error":{"code":400,"message","status"}} and Ollama {"error":"message"}
+type providerErrorBody struct {
+	Error json.RawMessage `json:"error"`
+}
+
+type providerErrorDetail struct {
+	Message    string          `json:"message"`
+	Type       string          `json:"type"`
+	Code       json.RawMessage `json:"code"`
+	Status     string          `json:"status"` // Google RPC status, e.g. RESOURCE_EXHAUSTED
+	InnerError *struct {
+		Code string `json:"code"`
+	} `json:"innererror"`
+}
+
+// newAPIError builds an APIError from a failed provider response
+func newAPIError(provider model.LLMProvider, resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		RetryAfter: httpclient.ParseRetryAfter(resp.Header),
+		RawBody:    string(body),
+	}
+
+	var parsed providerErrorBody
+	if err := json.Unmarshal(body, &parsed); err == nil && len(parsed.Error) > 0 {
+		var message string
+		var detail providerErrorDetail
+		if err := json.Unmarshal(parsed.Error, &message); err == nil {
+			apiErr.Message = message
+		} else if err := json.Unmarshal(parsed.Error, &detail); err == nil {
+			apiErr.Message = detail.Message
+			apiErr.Code = rawString(detail.Code)
+			if detail.InnerError != nil && detail.InnerError.Code != "" {
+				apiErr.Code = detail.InnerError.Code
+			}
+			if detail.Status != "" {
+				apiErr.Code = detail.Status
+			}
+			if apiErr.Code == "" {
+				apiErr.Code = detail.Type
+			}
+		}
+	}
+
+	apiErr.Kind = classifyError(apiErr)
+	return apiErr
+}
+
+// classifyError derives the error kind from the provider code, message and status
+func classifyError(e *APIError) ErrorKind {
+	code := strings.ToLower(e.Code)
+	msg := strings.ToLower(e.Message)
+
+	switch {
+	case code == "context_length_exceeded" || code == "string_above_max_length" ||
+		strings.Contains(msg, "context length") || strings.Contains(msg, "context window") ||
+		strings.Contains(msg, "prompt is too long") || strings.Contains(msg, "maximum context") ||
+		strings.Contains(msg, "exceeds the maximum number of tokens"):
+		return ErrorKindContextLength
+	case code == "content_filter" || code == "content_policy_violation" ||
+		code == "responsibleaipolicyviolation" || strings.Contains(msg, "content management policy"):
+		return ErrorKindContentFilter
+	case code == "overloaded_error" || code == "api_error":
+		return ErrorKindServer
+	case code == "insufficient_quota" || strings.Contains(msg, "credit balance is too low"):
+		return ErrorKindQuota
+	case code == "rate_limit_exceeded" || code == "rate_limit_error" || code == "resource_exhausted":
+		return ErrorKindRateLimit
+	case code == "unauthenticated" || strings.Contains(msg, "api key not valid"):
+		return ErrorKindAuth
+	case code == "permission_denied":
+		return ErrorKindPermission
+	}
+
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrorKindAuth
+	case e.StatusCode == http.StatusForbidden:
+		return ErrorKindPermission
+	case e.StatusCode == http.StatusNotFound:
+		return ErrorKindNotFound
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrorKindRateLimit
+	case e.StatusCode == http.StatusRequestEntityTooLarge:
+		return ErrorKindContextLength
+	case e.StatusCode == http.StatusRequestTimeout || e.StatusCode >= http.StatusInternalServerError:
+		return ErrorKindServer
+	case e.StatusCode >= http.StatusBadRequest:
+		return ErrorKindInvalidRequest
+	}
+	return ErrorKindUnknown
+}
+
+// rawString returns a JSON string or number as plain text
+func rawString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
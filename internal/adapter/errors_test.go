@@ -0,0 +1,38 @@
+package adapter
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+)
+
+func TestNewAPIErrorKind(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		kind     ErrorKind
+		retry    bool
+		failover bool
+	}{
+		{"openai quota", http.StatusTooManyRequests, `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`, ErrorKindQuota, false, true},
+		{"claude credit", http.StatusBadRequest, `{"type":"error","error":{"type":"invalid_request_error","message":"Your credit balance is too low to access the Anthropic API."}}`, ErrorKindQuota, false, true},
+		{"openai rate limit", http.StatusTooManyRequests, `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`, ErrorKindRateLimit, true, true},
+		{"unauthorized", http.StatusUnauthorized, `{"error":{"message":"Incorrect API key provided","code":"invalid_api_key"}}`, ErrorKindAuth, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newAPIError(model.ProviderOpenAI, &http.Response{StatusCode: tt.status, Header: http.Header{}}, []byte(tt.body))
+			if err.Kind != tt.kind {
+				t.Errorf("Kind = %s, want %s", err.Kind, tt.kind)
+			}
+			if got := IsRetryable(err); got != tt.retry {
+				t.Errorf("IsRetryable = %v, want %v", got, tt.retry)
+			}
+			if got := CanFailover(err); got != tt.failover {
+				t.Errorf("CanFailover = %v, want %v", got, tt.failover)
+			}
+		})
+	}
+}
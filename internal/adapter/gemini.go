@@ -0,0 +1,542 @@
+package adapter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/jsonschema"
+	"github.com/allwaysyou/llm-agent/internal/pkg/modelinfo"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultGeminiBaseURL        = "https://generativelanguage.googleapis.com/v1beta"
+	defaultGeminiModel          = "gemini-2.0-flash"
+	defaultGeminiEmbeddingModel = "text-embedding-004"
+)
+
+// GeminiAdapter implements LLMAdapter for the Google Gemini API
+type GeminiAdapter struct {
+	apiKey    string
+	baseURL   string
+	model     string
+	maxTokens int
+	sampling  model.SamplingParams
+	client    *http.Client
+}
+
+// NewGeminiAdapter creates a new Gemini adapter
+func NewGeminiAdapter(cfg AdapterConfig) (LLMAdapter, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultGeminiBaseURL
+	}
+
+	modelName := cfg.Model
+	if modelName == "" {
+		modelName = defaultGeminiModel
+	}
+
+	maxTokens := cfg.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = 4096
+	}
+
+	return &GeminiAdapter{
+		apiKey:    cfg.APIKey,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		model:     strings.TrimPrefix(modelName, "models/"),
+		maxTokens: maxTokens,
+		sampling:  cfg.Sampling,
+		client:    cfg.httpClient(),
+	}, nil
+}
+
+// geminiRequest represents a generateContent request
+type geminiRequest struct {
+	Contents          []geminiContent        `json:"contents"`
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text    string `json:"text"`
+	Thought bool   `json:"thought,omitempty"` // thought summary, returned when includeThoughts is set
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens  int                   `json:"maxOutputTokens,omitempty"`
+	Temperature      *float64              `json:"temperature,omitempty"`
+	TopP             *float64              `json:"topP,omitempty"`
+	TopK             *int                  `json:"topK,omitempty"`
+	StopSequences    []string              `json:"stopSequences,omitempty"`
+	PresencePenalty  *float64              `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64              `json:"frequencyPenalty,omitempty"`
+	Seed             *int64                `json:"seed,omitempty"`
+	ThinkingConfig   *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+	ResponseMIMEType string                `json:"responseMimeType,omitempty"`
+	ResponseSchema   *geminiSchema         `json:"responseSchema,omitempty"`
+}
+
+// geminiSchema is Gemini's OpenAPI-style response schema
+type geminiSchema struct {
+	Type        string                   `json:"type"`
+	Format      string                   `json:"format,omitempty"`
+	Description string                   `json:"description,omitempty"`
+	Properties  map[string]*geminiSchema `json:"properties,omitempty"`
+	Required    []string                 `json:"required,omitempty"`
+	Items       *geminiSchema            `json:"items,omitempty"`
+	Enum        []string                 `json:"enum,omitempty"`
+}
+
+// newGeminiSchema converts a JSON schema to Gemini's format, which uses upper-case
+// types and has no additionalProperties
+func newGeminiSchema(s *jsonschema.Schema) *geminiSchema {
+	if s == nil {
+		return nil
+	}
+	gs := &geminiSchema{
+		Type:        strings.ToUpper(s.Type),
+		Description: s.Description,
+		Required:    s.Required,
+		Items:       newGeminiSchema(s.Items),
+		Enum:        s.Enum,
+	}
+	if len(s.Enum) > 0 {
+		gs.Format = "enum"
+	}
+	if len(s.Properties) > 0 {
+		gs.Properties = make(map[string]*geminiSchema, len(s.Properties))
+		for name, prop := range s.Properties {
+			gs.Properties[name] = newGeminiSchema(prop)
+		}
+	}
+	return gs
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget  int  `json:"thinkingBudget"`
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+}
+
+// geminiResponse represents a generateContent response (also each SSE chunk when streaming)
+type geminiResponse struct {
+	ResponseID string `json:"responseId"`
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata,omitempty"`
+}
+
+// geminiEmbeddingRequest represents an embedContent request
+type geminiEmbeddingRequest struct {
+	Model   string        `json:"model"`
+	Content geminiContent `json:"content"`
+}
+
+// geminiEmbeddingResponse represents an embedContent response
+type geminiEmbeddingResponse struct {
+	Embedding struct {
+		Values []float32 `json:"values"`
+	} `json:"embedding"`
+}
+
+// text returns the concatenated answer text of the first candidate
+func (r *geminiResponse) text() string {
+	return r.parts(false)
+}
+
+// thoughts returns the concatenated thought summaries of the first candidate
+func (r *geminiResponse) thoughts() string {
+	return r.parts(true)
+}
+
+func (r *geminiResponse) parts(thought bool) string {
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	for _, part := range r.Candidates[0].Content.Parts {
+		if part.Thought == thought {
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// usage converts the usage metadata, or returns nil if the chunk has none
+func (r *geminiResponse) usage() *model.Usage {
+	if r.UsageMetadata == nil {
+		return nil
+	}
+	return &model.Usage{
+		PromptTokens:     r.UsageMetadata.PromptTokenCount,
+		CompletionTokens: r.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      r.UsageMetadata.TotalTokenCount,
+	}
+}
+
+// blockError returns a content_filter error if the prompt or the answer was blocked
+func (r *geminiResponse) blockError() *APIError {
+	reason := ""
+	if r.PromptFeedback != nil && r.PromptFeedback.BlockReason != "" {
+		reason = r.PromptFeedback.BlockReason
+	} else if len(r.Candidates) > 0 {
+		switch r.Candidates[0].FinishReason {
+		case "SAFETY", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "RECITATION", "IMAGE_SAFETY":
+			reason = r.Candidates[0].FinishReason
+		}
+	}
+	if reason == "" {
+		return nil
+	}
+	return &APIError{
+		Provider:   model.ProviderGemini,
+		StatusCode: http.StatusOK,
+		Kind:       ErrorKindContentFilter,
+		Code:       reason,
+		Message:    "response blocked by Gemini safety settings: " + reason,
+	}
+}
+
+// newRequest creates a POST request for a model method such as "generateContent"
+func (a *GeminiAdapter) newRequest(ctx context.Context, modelName, method string, query string, body []byte) (*http.Request, error) {
+	url := fmt.Sprintf("%s/models/%s:%s", a.baseURL, modelName, method)
+	if query != "" {
+		url += "?" + query
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", a.apiKey)
+	return req, nil
+}
+
+// buildRequest creates the request body, asking for JSON following format when set
+func (a *GeminiAdapter) buildRequest(messages []model.Message, format *jsonschema.Format) ([]byte, error) {
+	contents, system := convertToGeminiContents(messages)
+	reqBody := geminiRequest{
+		Contents:          contents,
+		SystemInstruction: system,
+		GenerationConfig: geminiGenerationConfig{
+			MaxOutputTokens:  a.maxTokens,
+			Temperature:      a.sampling.Temperature,
+			TopP:             a.sampling.TopP,
+			TopK:             a.sampling.TopK,
+			StopSequences:    a.sampling.Stop,
+			PresencePenalty:  a.sampling.PresencePenalty,
+			FrequencyPenalty: a.sampling.FrequencyPenalty,
+			Seed:             a.sampling.Seed,
+		},
+	}
+	if budget := a.sampling.ThinkingTokens(); budget > 0 {
+		reqBody.GenerationConfig.ThinkingConfig = &geminiThinkingConfig{ThinkingBudget: budget, IncludeThoughts: true}
+	}
+	if format != nil {
+		reqBody.GenerationConfig.ResponseMIMEType = "application/json"
+		reqBody.GenerationConfig.ResponseSchema = newGeminiSchema(format.Schema)
+	}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return body, nil
+}
+
+func (a *GeminiAdapter) Chat(ctx context.Context, messages []model.Message) (*model.ChatResponse, error) {
+	return a.chat(ctx, messages, nil)
+}
+
+// ChatJSON requests structured output through responseSchema
+func (a *GeminiAdapter) ChatJSON(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error) {
+	return a.chat(ctx, messages, format)
+}
+
+func (a *GeminiAdapter) chat(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error) {
+	body, err := a.buildRequest(messages, format)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := a.newRequest(ctx, a.model, "generateContent", "", body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(model.ProviderGemini, resp, bodyBytes)
+	}
+
+	var geminiResp geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&geminiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if blockErr := geminiResp.blockError(); blockErr != nil {
+		return nil, blockErr
+	}
+	if len(geminiResp.Candidates) == 0 {
+		return nil, fmt.Errorf("no candidates in response")
+	}
+
+	id := geminiResp.ResponseID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	return &model.ChatResponse{
+		ID: id,
+		Message: model.Message{
+			Role:      model.RoleAssistant,
+			Content:   geminiResp.text(),
+			Reasoning: geminiResp.thoughts(),
+		},
+		Usage: geminiResp.usage(),
+	}, nil
+}
+
+func (a *GeminiAdapter) ChatStream(ctx context.Context, messages []model.Message) (<-chan model.StreamChunk, error) {
+	body, err := a.buildRequest(messages, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := a.newRequest(ctx, a.model, "streamGenerateContent", "alt=sse", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, newAPIError(model.ProviderGemini, resp, bodyBytes)
+	}
+
+	ch := make(chan model.StreamChunk, 100)
+
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		reader := bufio.NewReader(resp.Body)
+		id := uuid.New().String()
+		var usage *model.Usage
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				// The SSE stream has no terminator, it simply ends after the last chunk
+				ch <- model.StreamChunk{ID: id, Done: true, Usage: usage}
+				return
+			}
+
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+
+			var chunk geminiResponse
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &chunk); err != nil {
+				continue
+			}
+
+			// Usage metadata is cumulative, the last one wins
+			if chunkUsage := chunk.usage(); chunkUsage != nil {
+				usage = chunkUsage
+			}
+
+			if thoughts := chunk.thoughts(); thoughts != "" {
+				ch <- model.StreamChunk{ID: id, Reasoning: thoughts}
+			}
+			if text := chunk.text(); text != "" {
+				ch <- model.StreamChunk{ID: id, Delta: text}
+			}
+
+			if blockErr := chunk.blockError(); blockErr != nil {
+				log.Printf("[GeminiAdapter:ChatStream] Stream stopped: %v", blockErr)
+				ch <- model.StreamChunk{ID: id, Done: true, Usage: usage, Error: blockErr.Message, ErrorKind: string(blockErr.Kind)}
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+func (a *GeminiAdapter) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embeddingModel := a.model
+	if !strings.Contains(embeddingModel, "embedding") {
+		embeddingModel = defaultGeminiEmbeddingModel
+	}
+
+	reqBody := geminiEmbeddingRequest{
+		Model:   "models/" + embeddingModel,
+		Content: geminiContent{Parts: []geminiPart{{Text: text}}},
+	}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := a.newRequest(ctx, embeddingModel, "embedContent", "", body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(model.ProviderGemini, resp, bodyBytes)
+	}
+
+	var embResp geminiEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(embResp.Embedding.Values) == 0 {
+		return nil, fmt.Errorf("no embedding data in response")
+	}
+
+	return embResp.Embedding.Values, nil
+}
+
+// ListModels returns the models available from the /models endpoint. Gemini
+// reports input limits and supported methods, which take precedence over the registry.
+func (a *GeminiAdapter) ListModels(ctx context.Context) ([]model.ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.baseURL+"/models?pageSize=1000", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("x-goog-api-key", a.apiKey)
+
+	var list struct {
+		Models []struct {
+			Name                       string   `json:"name"`
+			DisplayName                string   `json:"displayName"`
+			InputTokenLimit            int      `json:"inputTokenLimit"`
+			SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+		} `json:"models"`
+	}
+	if err := fetchModelList(a.client, req, model.ProviderGemini, &list); err != nil {
+		return nil, err
+	}
+
+	models := make([]model.ModelInfo, 0, len(list.Models))
+	for _, m := range list.Models {
+		info := modelinfo.Describe(strings.TrimPrefix(m.Name, "models/"))
+		info.DisplayName = m.DisplayName
+		if m.InputTokenLimit > 0 {
+			info.ContextWindow = m.InputTokenLimit
+		}
+		if len(m.SupportedGenerationMethods) > 0 {
+			info.Chat, info.Embedding = false, false
+			for _, method := range m.SupportedGenerationMethods {
+				switch method {
+				case "generateContent":
+					info.Chat = true
+				case "embedContent":
+					info.Embedding = true
+				}
+			}
+		}
+		models = append(models, info)
+	}
+	sortModels(models)
+	return models, nil
+}
+
+func (a *GeminiAdapter) CountTokens(text string) int {
+	// Rough estimation: ~4 characters per token
+	return len(text) / 4
+}
+
+func (a *GeminiAdapter) Name() string {
+	return "gemini"
+}
+
+func (a *GeminiAdapter) Provider() model.LLMProvider {
+	return model.ProviderGemini
+}
+
+// convertToGeminiContents converts model.Message to Gemini contents.
+// System prompts go to the separate system instruction, assistant turns use the
+// "model" role, and consecutive turns of the same role are merged.
+func convertToGeminiContents(messages []model.Message) ([]geminiContent, *geminiContent) {
+	var contents []geminiContent
+	var systemParts []geminiPart
+
+	for _, msg := range messages {
+		if msg.Role == model.RoleSystem {
+			systemParts = append(systemParts, geminiPart{Text: msg.Content})
+			continue
+		}
+
+		role := "user"
+		if msg.Role == model.RoleAssistant {
+			role = "model"
+		}
+
+		if n := len(contents); n > 0 && contents[n-1].Role == role {
+			contents[n-1].Parts = append(contents[n-1].Parts, geminiPart{Text: msg.Content})
+			continue
+		}
+		contents = append(contents, geminiContent{Role: role, Parts: []geminiPart{{Text: msg.Content}}})
+	}
+
+	if len(systemParts) == 0 {
+		return contents, nil
+	}
+	return contents, &geminiContent{Parts: systemParts}
+}
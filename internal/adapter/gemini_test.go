@@ -0,0 +1,148 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+)
+
+// replayGemini starts a stub Gemini API answering every request with a recorded
+// response from testdata/gemini
+func replayGemini(t *testing.T, status int, file string) LLMAdapter {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "gemini", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("x-goog-api-key"); got != "test-key" {
+			t.Errorf("api key header = %q, want test-key", got)
+		}
+		if strings.HasSuffix(file, ".sse") {
+			if !strings.HasSuffix(r.URL.Path, ":streamGenerateContent") || r.URL.Query().Get("alt") != "sse" {
+				t.Errorf("unexpected stream request %s", r.URL)
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	llm, err := NewGeminiAdapter(AdapterConfig{APIKey: "test-key", BaseURL: server.URL, Model: "gemini-2.5-flash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return llm
+}
+
+var geminiPrompt = []model.Message{{Role: model.RoleUser, Content: "What is the capital of France?"}}
+
+func TestGeminiChat(t *testing.T) {
+	resp, err := replayGemini(t, http.StatusOK, "generate_content.json").Chat(context.Background(), geminiPrompt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ID != "bq1NaJDmGZ2ggLUPq_m0oAs" {
+		t.Errorf("ID = %q", resp.ID)
+	}
+	if resp.Message.Content != "The capital of France is Paris." {
+		t.Errorf("Content = %q", resp.Message.Content)
+	}
+	if !strings.HasPrefix(resp.Message.Reasoning, "The user asks") {
+		t.Errorf("Reasoning = %q", resp.Message.Reasoning)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 8 || resp.Usage.CompletionTokens != 8 || resp.Usage.TotalTokens != 40 {
+		t.Errorf("Usage = %+v", resp.Usage)
+	}
+}
+
+func TestGeminiChatErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		file   string
+		kind   ErrorKind
+		code   string
+	}{
+		{"prompt blocked", http.StatusOK, "prompt_blocked.json", ErrorKindContentFilter, "SAFETY"},
+		{"rate limited", http.StatusTooManyRequests, "rate_limited.json", ErrorKindRateLimit, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := replayGemini(t, tt.status, tt.file).Chat(context.Background(), geminiPrompt)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want an APIError", err)
+			}
+			if apiErr.Kind != tt.kind {
+				t.Errorf("Kind = %s, want %s", apiErr.Kind, tt.kind)
+			}
+			if tt.code != "" && apiErr.Code != tt.code {
+				t.Errorf("Code = %q, want %q", apiErr.Code, tt.code)
+			}
+		})
+	}
+}
+
+// collect reads a stream to its end
+func collect(t *testing.T, llm LLMAdapter) (string, model.StreamChunk, int) {
+	t.Helper()
+	stream, err := llm.ChatStream(context.Background(), geminiPrompt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content strings.Builder
+	var last model.StreamChunk
+	done := 0
+	for chunk := range stream {
+		content.WriteString(chunk.Delta)
+		if chunk.Done {
+			done++
+			last = chunk
+		}
+	}
+	return content.String(), last, done
+}
+
+func TestGeminiChatStream(t *testing.T) {
+	content, last, done := collect(t, replayGemini(t, http.StatusOK, "stream.sse"))
+	if content != "Paris is the capital of France." {
+		t.Errorf("content = %q", content)
+	}
+	if done != 1 {
+		t.Errorf("got %d done chunks, want 1", done)
+	}
+	if last.Error != "" {
+		t.Errorf("Error = %q, want none", last.Error)
+	}
+	if last.Usage == nil || last.Usage.CompletionTokens != 7 || last.Usage.TotalTokens != 15 {
+		t.Errorf("Usage = %+v, want the last usage metadata", last.Usage)
+	}
+}
+
+func TestGeminiChatStreamBlocked(t *testing.T) {
+	content, last, done := collect(t, replayGemini(t, http.StatusOK, "stream_blocked.sse"))
+	if content != "Here is how you" {
+		t.Errorf("content = %q", content)
+	}
+	if done != 1 {
+		t.Errorf("got %d done chunks, want 1", done)
+	}
+	if last.ErrorKind != string(ErrorKindContentFilter) || !strings.Contains(last.Error, "SAFETY") {
+		t.Errorf("Error = %q, ErrorKind = %q, want a SAFETY content_filter error", last.Error, last.ErrorKind)
+	}
+	if last.Usage == nil || last.Usage.TotalTokens != 18 {
+		t.Errorf("Usage = %+v", last.Usage)
+	}
+}
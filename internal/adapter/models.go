@@ -0,0 +1,54 @@
+package adapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/modelinfo"
+)
+
+// openaiModelList is the response of the OpenAI-style GET /models endpoint
+type openaiModelList struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// fetchModelList sends a model listing request and decodes the JSON response into out
+func fetchModelList(client *http.Client, req *http.Request, provider model.LLMProvider, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return newAPIError(provider, resp, bodyBytes)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode model list: %w", err)
+	}
+	return nil
+}
+
+// describeModels annotates model IDs from the built-in registry and sorts them
+func describeModels(ids []string) []model.ModelInfo {
+	models := make([]model.ModelInfo, 0, len(ids))
+	for _, id := range ids {
+		models = append(models, modelinfo.Describe(id))
+	}
+	sortModels(models)
+	return models
+}
+
+func sortModels(models []model.ModelInfo) {
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].ID < models[j].ID
+	})
+}
@@ -1,7 +1,6 @@
 package adapter
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -11,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/jsonschema"
 	"github.com/google/uuid"
 )
 
@@ -21,12 +21,13 @@ const (
 
 // OllamaAdapter implements LLMAdapter for Ollama API (OpenAI-compatible)
 type OllamaAdapter struct {
-	apiKey      string
-	baseURL     string
-	model       string
-	maxTokens   int
-	temperature float64
-	client      *http.Client
+	apiKey    string
+	baseURL   string
+	model     string
+	maxTokens int
+	sampling  model.SamplingParams
+	reasoning bool // Model accepts reasoning_effort
+	client    *http.Client
 }
 
 // NewOllamaAdapter creates a new Ollama adapter
@@ -46,11 +47,6 @@ func NewOllamaAdapter(cfg AdapterConfig) (LLMAdapter, error) {
 		maxTokens = 4096
 	}
 
-	temperature := cfg.Temperature
-	if temperature == 0 {
-		temperature = 0.7
-	}
-
 	// Ollama doesn't require an API key, but we keep the field for compatibility
 	apiKey := cfg.APIKey
 	if apiKey == "" {
@@ -58,22 +54,25 @@ func NewOllamaAdapter(cfg AdapterConfig) (LLMAdapter, error) {
 	}
 
 	return &OllamaAdapter{
-		apiKey:      apiKey,
-		baseURL:     strings.TrimSuffix(baseURL, "/"),
-		model:       modelName,
-		maxTokens:   maxTokens,
-		temperature: temperature,
-		client:      &http.Client{},
+		apiKey:    apiKey,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		model:     modelName,
+		maxTokens: maxTokens,
+		sampling:  cfg.Sampling,
+		reasoning: cfg.Options.Capabilities.Reasoning,
+		client:    cfg.httpClient(),
 	}, nil
 }
 
 // ollamaRequest represents an Ollama chat completion request (OpenAI-compatible)
 type ollamaRequest struct {
-	Model       string          `json:"model"`
-	Messages    []ollamaMessage `json:"messages"`
-	MaxTokens   int             `json:"max_tokens,omitempty"`
-	Temperature float64         `json:"temperature,omitempty"`
-	Stream      bool            `json:"stream,omitempty"`
+	Model     string          `json:"model"`
+	Messages  []ollamaMessage `json:"messages"`
+	MaxTokens int             `json:"max_tokens,omitempty"`
+	Stream    bool            `json:"stream,omitempty"`
+	openaiSampling
+
+	StreamOptions *openaiStreamOptions `json:"stream_options,omitempty"`
 }
 
 type ollamaMessage struct {
@@ -81,40 +80,6 @@ type ollamaMessage struct {
 	Content string `json:"content"`
 }
 
-// ollamaResponse represents an Ollama chat completion response
-type ollamaResponse struct {
-	ID      string `json:"id"`
-	Choices []struct {
-		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
-		} `json:"message"`
-		FinishReason string `json:"finish_reason"`
-	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
-}
-
-// ollamaStreamResponse represents a streaming response chunk
-type ollamaStreamResponse struct {
-	ID      string `json:"id"`
-	Choices []struct {
-		Delta struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
-		} `json:"delta"`
-		FinishReason string `json:"finish_reason"`
-	} `json:"choices"`
-	Usage *struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage,omitempty"`
-}
-
 // ollamaNativeEmbeddingRequest represents Ollama's native embedding request
 type ollamaNativeEmbeddingRequest struct {
 	Model  string `json:"model"`
@@ -126,13 +91,57 @@ type ollamaNativeEmbeddingResponse struct {
 	Embedding []float32 `json:"embedding"`
 }
 
-func (a *OllamaAdapter) Chat(ctx context.Context, messages []model.Message) (*model.ChatResponse, error) {
-	reqBody := ollamaRequest{
-		Model:       a.model,
-		Messages:    convertToOllamaMessages(messages),
-		MaxTokens:   a.maxTokens,
-		Temperature: a.temperature,
-		Stream:      false,
+// ollamaNativeChatRequest represents Ollama's native chat request, used for structured output
+type ollamaNativeChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Format   *jsonschema.Schema     `json:"format,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// ollamaNativeChatResponse represents Ollama's native chat response
+type ollamaNativeChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+// nativeOptions maps sampling params to Ollama's native option names
+func (a *OllamaAdapter) nativeOptions() map[string]interface{} {
+	options := map[string]interface{}{"num_predict": a.maxTokens}
+	if a.sampling.Temperature != nil {
+		options["temperature"] = *a.sampling.Temperature
+	}
+	if a.sampling.TopP != nil {
+		options["top_p"] = *a.sampling.TopP
+	}
+	if a.sampling.TopK != nil {
+		options["top_k"] = *a.sampling.TopK
+	}
+	if len(a.sampling.Stop) > 0 {
+		options["stop"] = a.sampling.Stop
+	}
+	if a.sampling.PresencePenalty != nil {
+		options["presence_penalty"] = *a.sampling.PresencePenalty
+	}
+	if a.sampling.FrequencyPenalty != nil {
+		options["frequency_penalty"] = *a.sampling.FrequencyPenalty
+	}
+	if a.sampling.Seed != nil {
+		options["seed"] = *a.sampling.Seed
+	}
+	return options
+}
+
+// ChatJSON requests structured output through the native /api/chat format parameter
+func (a *OllamaAdapter) ChatJSON(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error) {
+	reqBody := ollamaNativeChatRequest{
+		Model:    a.model,
+		Messages: convertToOllamaMessages(messages),
+		Stream:   false,
+		Format:   format.Schema,
+		Options:  a.nativeOptions(),
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -140,7 +149,11 @@ func (a *OllamaAdapter) Chat(ctx context.Context, messages []model.Message) (*mo
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/chat/completions", bytes.NewReader(body))
+	// baseURL is like "http://localhost:11434/v1", we need "http://localhost:11434/api/chat"
+	baseURL := strings.TrimSuffix(a.baseURL, "/v1")
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/api/chat", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -158,39 +171,75 @@ func (a *OllamaAdapter) Chat(ctx context.Context, messages []model.Message) (*mo
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(model.ProviderOllama, resp, bodyBytes)
 	}
 
-	var ollamaResp ollamaResponse
+	var ollamaResp ollamaNativeChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if len(ollamaResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
-	}
-
 	return &model.ChatResponse{
-		ID: ollamaResp.ID,
+		ID: uuid.New().String(),
 		Message: model.Message{
-			Role:    model.MessageRole(ollamaResp.Choices[0].Message.Role),
-			Content: ollamaResp.Choices[0].Message.Content,
+			Role:    model.RoleAssistant,
+			Content: ollamaResp.Message.Content,
 		},
 		Usage: &model.Usage{
-			PromptTokens:     ollamaResp.Usage.PromptTokens,
-			CompletionTokens: ollamaResp.Usage.CompletionTokens,
-			TotalTokens:      ollamaResp.Usage.TotalTokens,
+			PromptTokens:     ollamaResp.PromptEvalCount,
+			CompletionTokens: ollamaResp.EvalCount,
+			TotalTokens:      ollamaResp.PromptEvalCount + ollamaResp.EvalCount,
 		},
 	}, nil
 }
 
+func (a *OllamaAdapter) Chat(ctx context.Context, messages []model.Message) (*model.ChatResponse, error) {
+	reqBody := ollamaRequest{
+		Model:          a.model,
+		Messages:       convertToOllamaMessages(messages),
+		MaxTokens:      a.maxTokens,
+		openaiSampling: newOpenAISampling(a.sampling, a.reasoning),
+		Stream:         false,
+	}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if a.apiKey != "" && a.apiKey != "ollama" {
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(model.ProviderOllama, resp, bodyBytes)
+	}
+
+	return decodeOpenAIResponse(resp.Body)
+}
+
 func (a *OllamaAdapter) ChatStream(ctx context.Context, messages []model.Message) (<-chan model.StreamChunk, error) {
 	reqBody := ollamaRequest{
-		Model:       a.model,
-		Messages:    convertToOllamaMessages(messages),
-		MaxTokens:   a.maxTokens,
-		Temperature: a.temperature,
-		Stream:      true,
+		Model:          a.model,
+		Messages:       convertToOllamaMessages(messages),
+		MaxTokens:      a.maxTokens,
+		openaiSampling: newOpenAISampling(a.sampling, a.reasoning),
+		Stream:         true,
+
+		StreamOptions: &openaiStreamOptions{IncludeUsage: true},
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -217,72 +266,11 @@ func (a *OllamaAdapter) ChatStream(ctx context.Context, messages []model.Message
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(model.ProviderOllama, resp, bodyBytes)
 	}
 
 	ch := make(chan model.StreamChunk, 100)
-
-	go func() {
-		defer close(ch)
-		defer resp.Body.Close()
-
-		reader := bufio.NewReader(resp.Body)
-		id := uuid.New().String()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
-
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err != io.EOF {
-					ch <- model.StreamChunk{ID: id, Done: true}
-				}
-				return
-			}
-
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
-
-			if !strings.HasPrefix(line, "data: ") {
-				continue
-			}
-
-			data := strings.TrimPrefix(line, "data: ")
-			if data == "[DONE]" {
-				ch <- model.StreamChunk{ID: id, Done: true}
-				return
-			}
-
-			var streamResp ollamaStreamResponse
-			if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
-				continue
-			}
-
-			if len(streamResp.Choices) > 0 {
-				chunk := model.StreamChunk{
-					ID:    id,
-					Delta: streamResp.Choices[0].Delta.Content,
-					Done:  streamResp.Choices[0].FinishReason != "",
-				}
-
-				if streamResp.Usage != nil {
-					chunk.Usage = &model.Usage{
-						PromptTokens:     streamResp.Usage.PromptTokens,
-						CompletionTokens: streamResp.Usage.CompletionTokens,
-						TotalTokens:      streamResp.Usage.TotalTokens,
-					}
-				}
-
-				ch <- chunk
-			}
-		}
-	}()
+	go streamOpenAIResponse(ctx, resp.Body, ch)
 
 	return ch, nil
 }
@@ -325,7 +313,7 @@ func (a *OllamaAdapter) GetEmbedding(ctx context.Context, text string) ([]float3
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(model.ProviderOllama, resp, bodyBytes)
 	}
 
 	var embResp ollamaNativeEmbeddingResponse
@@ -340,6 +328,36 @@ func (a *OllamaAdapter) GetEmbedding(ctx context.Context, text string) ([]float3
 	return embResp.Embedding, nil
 }
 
+// ListModels returns the locally pulled models from the native /api/tags endpoint
+func (a *OllamaAdapter) ListModels(ctx context.Context) ([]model.ModelInfo, error) {
+	// baseURL is like "http://localhost:11434/v1", we need "http://localhost:11434/api/tags"
+	baseURL := strings.TrimSuffix(a.baseURL, "/v1")
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if a.apiKey != "" && a.apiKey != "ollama" {
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+
+	var list struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := fetchModelList(a.client, req, model.ProviderOllama, &list); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(list.Models))
+	for _, m := range list.Models {
+		ids = append(ids, m.Name)
+	}
+	return describeModels(ids), nil
+}
+
 func (a *OllamaAdapter) CountTokens(text string) int {
 	// Rough estimation: ~4 characters per token
 	return len(text) / 4
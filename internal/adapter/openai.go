@@ -7,10 +7,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
 
 	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/jsonschema"
 	"github.com/google/uuid"
 )
 
@@ -21,12 +23,13 @@ const (
 
 // OpenAIAdapter implements LLMAdapter for OpenAI API
 type OpenAIAdapter struct {
-	apiKey      string
-	baseURL     string
-	model       string
-	maxTokens   int
-	temperature float64
-	client      *http.Client
+	apiKey    string
+	baseURL   string
+	model     string
+	maxTokens int
+	sampling  model.SamplingParams
+	reasoning bool // Model accepts reasoning_effort
+	client    *http.Client
 }
 
 // NewOpenAIAdapter creates a new OpenAI adapter
@@ -46,28 +49,111 @@ func NewOpenAIAdapter(cfg AdapterConfig) (LLMAdapter, error) {
 		maxTokens = 4096
 	}
 
-	temperature := cfg.Temperature
-	if temperature == 0 {
-		temperature = 0.7
-	}
-
 	return &OpenAIAdapter{
-		apiKey:      cfg.APIKey,
-		baseURL:     strings.TrimSuffix(baseURL, "/"),
-		model:       modelName,
-		maxTokens:   maxTokens,
-		temperature: temperature,
-		client:      &http.Client{},
+		apiKey:    cfg.APIKey,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		model:     modelName,
+		maxTokens: maxTokens,
+		sampling:  cfg.Sampling,
+		reasoning: cfg.Options.Capabilities.Reasoning,
+		client:    cfg.httpClient(),
 	}, nil
 }
 
 // openaiRequest represents an OpenAI chat completion request
 type openaiRequest struct {
-	Model       string          `json:"model"`
-	Messages    []openaiMessage `json:"messages"`
-	MaxTokens   int             `json:"max_tokens,omitempty"`
-	Temperature float64         `json:"temperature,omitempty"`
-	Stream      bool            `json:"stream,omitempty"`
+	Model     string          `json:"model"`
+	Messages  []openaiMessage `json:"messages"`
+	MaxTokens int             `json:"max_tokens,omitempty"`
+	Stream    bool            `json:"stream,omitempty"`
+	openaiSampling
+
+	StreamOptions  *openaiStreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *openaiResponseFormat `json:"response_format,omitempty"`
+	Tools          []openaiTool          `json:"tools,omitempty"`
+	ToolChoice     *openaiToolChoice     `json:"tool_choice,omitempty"`
+}
+
+// openaiTool declares a function the model can call
+type openaiTool struct {
+	Type     string         `json:"type"` // Always "function"
+	Function openaiFunction `json:"function"`
+}
+
+type openaiFunction struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Parameters  *jsonschema.Schema `json:"parameters,omitempty"`
+}
+
+// openaiToolChoice forces a call to the named function
+type openaiToolChoice struct {
+	Type     string         `json:"type"`
+	Function openaiFunction `json:"function"`
+}
+
+// openaiResponseFormat requests structured output following a JSON schema
+type openaiResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openaiJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openaiJSONSchema struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Schema      *jsonschema.Schema `json:"schema"`
+	Strict      bool               `json:"strict"`
+}
+
+// newOpenAIResponseFormat creates a strict json_schema response format, nil for free text
+func newOpenAIResponseFormat(format *jsonschema.Format) *openaiResponseFormat {
+	if format == nil {
+		return nil
+	}
+	return &openaiResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &openaiJSONSchema{
+			Name:        format.Name,
+			Description: format.Description,
+			Schema:      format.Schema,
+			Strict:      true,
+		},
+	}
+}
+
+// openaiSampling holds the sampling fields of OpenAI-compatible chat requests
+type openaiSampling struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	Seed             *int64   `json:"seed,omitempty"`
+	ReasoningEffort  string   `json:"reasoning_effort,omitempty"`
+	TopK             *int     `json:"top_k,omitempty"` // Not part of the OpenAI API, accepted by vLLM and similar servers
+}
+
+// newOpenAISampling maps sampling params to OpenAI field names. TopK and
+// ThinkingBudget have no OpenAI equivalent and are left out, as is
+// ReasoningEffort unless the model accepts it.
+func newOpenAISampling(p model.SamplingParams, reasoning bool) openaiSampling {
+	if !reasoning {
+		p.ReasoningEffort = ""
+	}
+	return openaiSampling{
+		Temperature:      p.Temperature,
+		TopP:             p.TopP,
+		Stop:             p.Stop,
+		PresencePenalty:  p.PresencePenalty,
+		FrequencyPenalty: p.FrequencyPenalty,
+		Seed:             p.Seed,
+		ReasoningEffort:  p.ReasoningEffort,
+	}
+}
+
+// openaiStreamOptions asks the server to report usage in the final stream chunk
+type openaiStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type openaiMessage struct {
@@ -80,8 +166,16 @@ type openaiResponse struct {
 	ID      string `json:"id"`
 	Choices []struct {
 		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role             string `json:"role"`
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content,omitempty"` // DeepSeek, vLLM
+			Reasoning        string `json:"reasoning,omitempty"`         // Ollama, OpenRouter
+			ToolCalls        []struct {
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls,omitempty"`
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
@@ -97,8 +191,10 @@ type openaiStreamResponse struct {
 	ID      string `json:"id"`
 	Choices []struct {
 		Delta struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role             string `json:"role"`
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content,omitempty"`
+			Reasoning        string `json:"reasoning,omitempty"`
 		} `json:"delta"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
@@ -123,12 +219,22 @@ type openaiEmbeddingResponse struct {
 }
 
 func (a *OpenAIAdapter) Chat(ctx context.Context, messages []model.Message) (*model.ChatResponse, error) {
+	return a.chat(ctx, messages, nil)
+}
+
+// ChatJSON requests structured output through response_format json_schema
+func (a *OpenAIAdapter) ChatJSON(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error) {
+	return a.chat(ctx, messages, format)
+}
+
+func (a *OpenAIAdapter) chat(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error) {
 	reqBody := openaiRequest{
-		Model:       a.model,
-		Messages:    convertToOpenAIMessages(messages),
-		MaxTokens:   a.maxTokens,
-		Temperature: a.temperature,
-		Stream:      false,
+		Model:          a.model,
+		Messages:       convertToOpenAIMessages(messages),
+		MaxTokens:      a.maxTokens,
+		openaiSampling: newOpenAISampling(a.sampling, a.reasoning),
+		Stream:         false,
+		ResponseFormat: newOpenAIResponseFormat(format),
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -152,39 +258,21 @@ func (a *OpenAIAdapter) Chat(ctx context.Context, messages []model.Message) (*mo
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var openaiResp openaiResponse
-	if err := json.NewDecoder(resp.Body).Decode(&openaiResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if len(openaiResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
+		return nil, newAPIError(model.ProviderOpenAI, resp, bodyBytes)
 	}
 
-	return &model.ChatResponse{
-		ID: openaiResp.ID,
-		Message: model.Message{
-			Role:    model.MessageRole(openaiResp.Choices[0].Message.Role),
-			Content: openaiResp.Choices[0].Message.Content,
-		},
-		Usage: &model.Usage{
-			PromptTokens:     openaiResp.Usage.PromptTokens,
-			CompletionTokens: openaiResp.Usage.CompletionTokens,
-			TotalTokens:      openaiResp.Usage.TotalTokens,
-		},
-	}, nil
+	return decodeOpenAIResponse(resp.Body)
 }
 
 func (a *OpenAIAdapter) ChatStream(ctx context.Context, messages []model.Message) (<-chan model.StreamChunk, error) {
 	reqBody := openaiRequest{
-		Model:       a.model,
-		Messages:    convertToOpenAIMessages(messages),
-		MaxTokens:   a.maxTokens,
-		Temperature: a.temperature,
-		Stream:      true,
+		Model:          a.model,
+		Messages:       convertToOpenAIMessages(messages),
+		MaxTokens:      a.maxTokens,
+		openaiSampling: newOpenAISampling(a.sampling, a.reasoning),
+		Stream:         true,
+
+		StreamOptions: &openaiStreamOptions{IncludeUsage: true},
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -209,72 +297,11 @@ func (a *OpenAIAdapter) ChatStream(ctx context.Context, messages []model.Message
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(model.ProviderOpenAI, resp, bodyBytes)
 	}
 
 	ch := make(chan model.StreamChunk, 100)
-
-	go func() {
-		defer close(ch)
-		defer resp.Body.Close()
-
-		reader := bufio.NewReader(resp.Body)
-		id := uuid.New().String()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
-
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err != io.EOF {
-					ch <- model.StreamChunk{ID: id, Done: true}
-				}
-				return
-			}
-
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
-
-			if !strings.HasPrefix(line, "data: ") {
-				continue
-			}
-
-			data := strings.TrimPrefix(line, "data: ")
-			if data == "[DONE]" {
-				ch <- model.StreamChunk{ID: id, Done: true}
-				return
-			}
-
-			var streamResp openaiStreamResponse
-			if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
-				continue
-			}
-
-			if len(streamResp.Choices) > 0 {
-				chunk := model.StreamChunk{
-					ID:    id,
-					Delta: streamResp.Choices[0].Delta.Content,
-					Done:  streamResp.Choices[0].FinishReason != "",
-				}
-
-				if streamResp.Usage != nil {
-					chunk.Usage = &model.Usage{
-						PromptTokens:     streamResp.Usage.PromptTokens,
-						CompletionTokens: streamResp.Usage.CompletionTokens,
-						TotalTokens:      streamResp.Usage.TotalTokens,
-					}
-				}
-
-				ch <- chunk
-			}
-		}
-	}()
+	go streamOpenAIResponse(ctx, resp.Body, ch)
 
 	return ch, nil
 }
@@ -306,7 +333,7 @@ func (a *OpenAIAdapter) GetEmbedding(ctx context.Context, text string) ([]float3
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(model.ProviderOpenAI, resp, bodyBytes)
 	}
 
 	var embResp openaiEmbeddingResponse
@@ -321,6 +348,26 @@ func (a *OpenAIAdapter) GetEmbedding(ctx context.Context, text string) ([]float3
 	return embResp.Data[0].Embedding, nil
 }
 
+// ListModels returns the models available from the /models endpoint
+func (a *OpenAIAdapter) ListModels(ctx context.Context) ([]model.ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+a.apiKey)
+
+	var list openaiModelList
+	if err := fetchModelList(a.client, req, model.ProviderOpenAI, &list); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		ids = append(ids, m.ID)
+	}
+	return describeModels(ids), nil
+}
+
 func (a *OpenAIAdapter) CountTokens(text string) int {
 	// Rough estimation: ~4 characters per token for English
 	// This is a simplified estimation; for production, use tiktoken
@@ -335,6 +382,124 @@ func (a *OpenAIAdapter) Provider() model.LLMProvider {
 	return model.ProviderOpenAI
 }
 
+// decodeOpenAIResponse decodes an OpenAI-compatible chat completion response
+func decodeOpenAIResponse(body io.Reader) (*model.ChatResponse, error) {
+	var openaiResp openaiResponse
+	if err := json.NewDecoder(body).Decode(&openaiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(openaiResp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
+	}
+
+	msg := openaiResp.Choices[0].Message
+	reasoning, content := splitReasoning(msg.ReasoningContent+msg.Reasoning, msg.Content)
+	if content == "" && len(msg.ToolCalls) > 0 {
+		// Structured output requested through a forced function call
+		content = msg.ToolCalls[0].Function.Arguments
+	}
+
+	return &model.ChatResponse{
+		ID: openaiResp.ID,
+		Message: model.Message{
+			Role:      model.MessageRole(msg.Role),
+			Content:   content,
+			Reasoning: reasoning,
+		},
+		Usage: &model.Usage{
+			PromptTokens:     openaiResp.Usage.PromptTokens,
+			CompletionTokens: openaiResp.Usage.CompletionTokens,
+			TotalTokens:      openaiResp.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+// streamOpenAIResponse reads an OpenAI-compatible SSE stream and forwards it to ch.
+// Reasoning deltas and inline <think> blocks are forwarded as reasoning chunks.
+// The final chunk is marked Done and carries the usage reported by the server, if any.
+// A stream cut off before [DONE] (or, for servers that omit it, before a finish_reason)
+// ends with an Error chunk so the partial answer is not taken as complete.
+func streamOpenAIResponse(ctx context.Context, body io.ReadCloser, ch chan<- model.StreamChunk) {
+	defer close(ch)
+	defer body.Close()
+
+	reader := bufio.NewReader(body)
+	id := uuid.New().String()
+	var usage *model.Usage
+	var splitter thinkTagSplitter
+	var finished bool
+
+	emit := func(reasoning, content string) {
+		if reasoning != "" || content != "" {
+			ch <- model.StreamChunk{ID: id, Delta: content, Reasoning: reasoning}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		line, readErr := reader.ReadString('\n')
+
+		line = strings.TrimSpace(line)
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			if data == "[DONE]" {
+				emit(splitter.flush())
+				ch <- model.StreamChunk{ID: id, Done: true, Usage: usage}
+				return
+			}
+
+			var streamResp openaiStreamResponse
+			if err := json.Unmarshal([]byte(data), &streamResp); err == nil {
+				// With include_usage, usage arrives in a trailing chunk without choices
+				if streamResp.Usage != nil {
+					usage = &model.Usage{
+						PromptTokens:     streamResp.Usage.PromptTokens,
+						CompletionTokens: streamResp.Usage.CompletionTokens,
+						TotalTokens:      streamResp.Usage.TotalTokens,
+					}
+				}
+
+				if len(streamResp.Choices) > 0 {
+					choice := streamResp.Choices[0]
+					emit(choice.Delta.ReasoningContent+choice.Delta.Reasoning, "")
+					if choice.Delta.Content != "" {
+						emit(splitter.push(choice.Delta.Content))
+					}
+					if choice.FinishReason != "" {
+						finished = true
+					}
+				}
+			}
+		}
+
+		if readErr == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		emit(splitter.flush())
+		if readErr == io.EOF && finished {
+			ch <- model.StreamChunk{ID: id, Done: true, Usage: usage}
+			return
+		}
+
+		message := "stream ended before the response was complete"
+		if readErr != io.EOF {
+			message = fmt.Sprintf("stream interrupted: %v", readErr)
+		}
+		log.Printf("[OpenAIAdapter:ChatStream] %s", message)
+		ch <- model.StreamChunk{ID: id, Done: true, Usage: usage, Error: message, ErrorKind: string(ErrorKindServer)}
+		return
+	}
+}
+
 func convertToOpenAIMessages(messages []model.Message) []openaiMessage {
 	result := make([]openaiMessage, len(messages))
 	for i, msg := range messages {
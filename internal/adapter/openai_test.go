@@ -0,0 +1,65 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+)
+
+// brokenBody returns its content, then fails like a reset connection
+type brokenBody struct {
+	io.Reader
+}
+
+func (b *brokenBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF {
+		err = errors.New("connection reset by peer")
+	}
+	return n, err
+}
+
+func (b *brokenBody) Close() error { return nil }
+
+func TestStreamOpenAIResponse(t *testing.T) {
+	const hello = `data: {"choices":[{"delta":{"content":"Hel"}}]}` + "\n\n" + `data: {"choices":[{"delta":{"content":"lo"}}]}` + "\n\n"
+	const finish = `data: {"choices":[{"delta":{},"finish_reason":"stop"}]}` + "\n\n"
+
+	tests := []struct {
+		name    string
+		body    io.ReadCloser
+		wantErr bool
+	}{
+		{"done", io.NopCloser(strings.NewReader(hello + finish + "data: [DONE]\n\n")), false},
+		{"done without newline", io.NopCloser(strings.NewReader(hello + finish + "data: [DONE]")), false},
+		{"eof after finish reason", io.NopCloser(strings.NewReader(hello + finish)), false},
+		{"eof before finish reason", io.NopCloser(strings.NewReader(hello)), true},
+		{"connection reset", &brokenBody{strings.NewReader(hello)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := make(chan model.StreamChunk, 10)
+			streamOpenAIResponse(context.Background(), tt.body, ch)
+
+			var content string
+			var last model.StreamChunk
+			for chunk := range ch {
+				content += chunk.Delta
+				last = chunk
+			}
+			if content != "Hello" {
+				t.Errorf("content = %q, want Hello", content)
+			}
+			if !last.Done {
+				t.Fatal("last chunk is not marked Done")
+			}
+			if (last.Error != "") != tt.wantErr {
+				t.Errorf("Error = %q, want error %v", last.Error, tt.wantErr)
+			}
+		})
+	}
+}
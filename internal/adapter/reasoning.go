@@ -0,0 +1,115 @@
+package adapter
+
+import "strings"
+
+// Reasoning models served through OpenAI-compatible endpoints (DeepSeek-R1,
+// QwQ on Ollama or vLLM, ...) either return their thinking in a dedicated
+// reasoning_content / reasoning field or inline it as a leading <think> block.
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// splitReasoning separates thinking from the answer. A dedicated reasoning
+// field wins; otherwise a leading <think> block is cut out of the content.
+func splitReasoning(reasoning, content string) (string, string) {
+	if reasoning != "" {
+		return reasoning, content
+	}
+
+	trimmed := strings.TrimLeft(content, " \t\r\n")
+	if !strings.HasPrefix(trimmed, thinkOpenTag) {
+		return "", content
+	}
+
+	rest := trimmed[len(thinkOpenTag):]
+	end := strings.Index(rest, thinkCloseTag)
+	if end < 0 {
+		// Truncated before the answer started
+		return strings.TrimSpace(rest), ""
+	}
+	return strings.TrimSpace(rest[:end]), strings.TrimLeft(rest[end+len(thinkCloseTag):], " \t\r\n")
+}
+
+type thinkState int
+
+const (
+	thinkDetect  thinkState = iota // waiting to see whether content opens with <think>
+	thinkInside                    // inside the <think> block
+	thinkAfter                     // block closed, skipping whitespace before the answer
+	thinkContent                   // plain answer content
+)
+
+// thinkTagSplitter incrementally splits streamed content into reasoning and
+// answer parts. Tags may be split across deltas, so partial tags are buffered.
+type thinkTagSplitter struct {
+	state thinkState
+	buf   string
+}
+
+// push consumes a content delta and returns the reasoning and content ready to emit
+func (s *thinkTagSplitter) push(delta string) (reasoning, content string) {
+	switch s.state {
+	case thinkDetect:
+		s.buf += delta
+		trimmed := strings.TrimLeft(s.buf, " \t\r\n")
+		if len(trimmed) < len(thinkOpenTag) && strings.HasPrefix(thinkOpenTag, trimmed) {
+			return "", ""
+		}
+		if !strings.HasPrefix(trimmed, thinkOpenTag) {
+			s.state = thinkContent
+			content, s.buf = s.buf, ""
+			return "", content
+		}
+		s.state = thinkInside
+		s.buf = strings.TrimLeft(trimmed[len(thinkOpenTag):], " \t\r\n")
+		return s.push("")
+
+	case thinkInside:
+		s.buf += delta
+		if end := strings.Index(s.buf, thinkCloseTag); end >= 0 {
+			reasoning = s.buf[:end]
+			rest := s.buf[end+len(thinkCloseTag):]
+			s.state, s.buf = thinkAfter, ""
+			_, content = s.push(rest)
+			return reasoning, content
+		}
+		// Hold back a tail that could be the start of the closing tag
+		keep := partialSuffix(s.buf, thinkCloseTag)
+		reasoning = s.buf[:len(s.buf)-keep]
+		s.buf = s.buf[len(s.buf)-keep:]
+		return reasoning, ""
+
+	case thinkAfter:
+		content = strings.TrimLeft(delta, " \t\r\n")
+		if content != "" {
+			s.state = thinkContent
+		}
+		return "", content
+	}
+
+	return "", delta
+}
+
+// flush returns whatever is still buffered when the stream ends
+func (s *thinkTagSplitter) flush() (reasoning, content string) {
+	buf := s.buf
+	s.buf = ""
+	switch s.state {
+	case thinkDetect:
+		return "", buf
+	case thinkInside:
+		return buf, ""
+	}
+	return "", ""
+}
+
+// partialSuffix returns the length of the longest suffix of s that is a proper prefix of tag
+func partialSuffix(s, tag string) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,87 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/constants"
+	"github.com/allwaysyou/llm-agent/internal/pkg/jsonschema"
+)
+
+// structuredOutputAttempts is the number of calls made before giving up on invalid output
+const structuredOutputAttempts = 2
+
+// ChatStructured asks the model for JSON following the format's schema and decodes it
+// into out. Output that fails validation is sent back once with the validation error
+// for repair. Providers or models that reject native structured output are retried
+// with the schema in the prompt instead.
+func ChatStructured(ctx context.Context, llm LLMAdapter, messages []model.Message, format *jsonschema.Format, out interface{}) error {
+	native := true
+	var lastErr error
+
+	for attempt := 1; attempt <= structuredOutputAttempts; attempt++ {
+		var resp *model.ChatResponse
+		var err error
+		if native {
+			resp, err = llm.ChatJSON(ctx, messages, format)
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.Kind == ErrorKindInvalidRequest {
+				log.Printf("[Adapter:ChatStructured] %s rejected structured output (%v), falling back to prompting", llm.Name(), err)
+				native = false
+			}
+		}
+		if !native {
+			resp, err = llm.Chat(ctx, withSchemaInstruction(messages, format))
+		}
+		if err != nil {
+			return err
+		}
+
+		content := extractJSON(resp.Message.Content)
+		if lastErr = format.Schema.Validate([]byte(content)); lastErr == nil {
+			return json.Unmarshal([]byte(content), out)
+		}
+		log.Printf("[Adapter:ChatStructured] Attempt %d: output does not match schema %s: %v", attempt, format.Name, lastErr)
+
+		messages = append(messages,
+			model.Message{Role: model.RoleAssistant, Content: resp.Message.Content},
+			model.Message{Role: model.RoleUser, Content: fmt.Sprintf(constants.StructuredOutputRepairPrompt, lastErr)},
+		)
+	}
+
+	return fmt.Errorf("structured output does not match schema %s: %w", format.Name, lastErr)
+}
+
+// withSchemaInstruction appends the schema to the last message for providers without
+// native structured output
+func withSchemaInstruction(messages []model.Message, format *jsonschema.Format) []model.Message {
+	schema, _ := json.Marshal(format.Schema)
+	instructed := make([]model.Message, len(messages))
+	copy(instructed, messages)
+
+	last := &instructed[len(instructed)-1]
+	last.Content += "\n\n" + fmt.Sprintf(constants.StructuredOutputInstruction, schema)
+	return instructed
+}
+
+// extractJSON extracts JSON from a string that might contain markdown code blocks
+func extractJSON(s string) string {
+	s = strings.TrimSpace(s)
+
+	// Remove markdown code blocks
+	if strings.HasPrefix(s, "```json") {
+		s = strings.TrimPrefix(s, "```json")
+	} else if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```")
+	}
+	if strings.HasSuffix(s, "```") {
+		s = strings.TrimSuffix(s, "```")
+	}
+
+	return strings.TrimSpace(s)
+}
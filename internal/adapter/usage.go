@@ -0,0 +1,22 @@
+package adapter
+
+import (
+	"context"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+)
+
+type usagePurposeKey struct{}
+
+// WithUsagePurpose returns a context that tags LLM calls made with it for usage accounting
+func WithUsagePurpose(ctx context.Context, purpose model.UsagePurpose) context.Context {
+	return context.WithValue(ctx, usagePurposeKey{}, purpose)
+}
+
+// UsagePurposeFromContext returns the usage purpose of the context (defaults to chat)
+func UsagePurposeFromContext(ctx context.Context) model.UsagePurpose {
+	if purpose, ok := ctx.Value(usagePurposeKey{}).(model.UsagePurpose); ok && purpose != "" {
+		return purpose
+	}
+	return model.UsagePurposeChat
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -16,13 +18,20 @@ type Config struct {
 	Embedding  EmbeddingConfig  `mapstructure:"embedding"`
 	Memory     MemoryConfig     `mapstructure:"memory"`
 	LLM        LLMDefaults      `mapstructure:"llm"`
+	HTTP       HTTPConfig       `mapstructure:"http"`
+	Prompt     PromptConfig     `mapstructure:"prompt"`
+	Auth       AuthConfig       `mapstructure:"auth"`
+	Redaction  RedactionConfig  `mapstructure:"redaction"`
 	Log        LogConfig        `mapstructure:"log"`
+
+	File string `mapstructure:"-"` // Config file the settings were read from
 }
 
 type ServerConfig struct {
-	Host string `mapstructure:"host"`
-	Port int    `mapstructure:"port"`
-	Mode string `mapstructure:"mode"`
+	Host        string   `mapstructure:"host"`
+	Port        int      `mapstructure:"port"`
+	Mode        string   `mapstructure:"mode"`
+	CORSOrigins []string `mapstructure:"cors_origins"` // Origins allowed to call the API from a browser, "*" for any
 }
 
 type DatabaseConfig struct {
@@ -34,8 +43,13 @@ type VectorConfig struct {
 	Collection string `mapstructure:"collection"`
 }
 
+// EncryptionConfig contains the keys encrypting stored provider API keys and, with
+// AtRest, conversations and knowledge. A key is 32 raw bytes, 32 base64-encoded bytes or
+// a passphrase (stretched with scrypt).
 type EncryptionConfig struct {
-	Key string `mapstructure:"key"`
+	Key          string   `mapstructure:"key"`           // Encrypts new data
+	PreviousKeys []string `mapstructure:"previous_keys"` // Only decrypt, kept until -rotate-keys re-encrypted everything
+	AtRest       bool     `mapstructure:"at_rest"`       // Also encrypt messages, knowledge, summaries and the vector store
 }
 
 type EmbeddingConfig struct {
@@ -44,6 +58,26 @@ type EmbeddingConfig struct {
 	BaseURL  string `mapstructure:"base_url"`
 }
 
+// PromptConfig contains prompt template settings
+type PromptConfig struct {
+	Language string `mapstructure:"language"` // Preferred language of built-in prompts, empty = each prompt's original language
+}
+
+// AuthConfig contains user account settings
+type AuthConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`         // Require login; when disabled all requests act as a single local user
+	AdminUsername string `mapstructure:"admin_username"`  // Admin created on first start with auth enabled (default: admin)
+	AdminPassword string `mapstructure:"admin_password"`  // Required to create the first admin
+	TokenTTLHours int    `mapstructure:"token_ttl_hours"` // Lifetime of a login session (default: 720)
+}
+
+// RedactionConfig contains the redaction of personal information from prompts sent to
+// cloud providers. Providers can override it with their redaction mode.
+type RedactionConfig struct {
+	Enabled bool     `mapstructure:"enabled"` // Redact for providers in auto mode, local ones excepted
+	Types   []string `mapstructure:"types"`   // email, id_card, bank_card, phone, address (default: all)
+}
+
 type LogConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"`
@@ -73,6 +107,13 @@ type MemoryConfig struct {
 
 	// Default values
 	DefaultImportance float32 `mapstructure:"default_importance"` // Default importance for extracted facts (default: 0.5)
+
+	// Chat history import and knowledge extraction from it
+	ImportExtractionRPM int `mapstructure:"import_extraction_rpm"` // Max imported turns processed per minute (default: 6)
+	ImportMaxUploadMB   int `mapstructure:"import_max_upload_mb"`  // Max size of an uploaded chat history export (default: 512)
+
+	// Knowledge file import, which may cost an embedding and an LLM call per record
+	ImportMaxRecords int `mapstructure:"import_max_records"` // Max records of an imported knowledge file (default: 1000)
 }
 
 // LLMDefaults contains default LLM configuration
@@ -81,6 +122,20 @@ type LLMDefaults struct {
 	Temperature      float32 `mapstructure:"temperature"`        // Default temperature (default: 0.7)
 	StreamBufferSize int     `mapstructure:"stream_buffer_size"` // Stream channel buffer size (default: 100)
 	TitleMaxLength   int     `mapstructure:"title_max_length"`   // Max length for session titles (default: 50)
+
+	// Background LLM calls (knowledge extraction, conflict detection) are paused once
+	// this fraction of any provider/model budget or RPM limit is used (default: 0.8)
+	BackgroundBudgetRatio float64 `mapstructure:"background_budget_ratio"`
+}
+
+// HTTPConfig contains settings for HTTP calls to LLM and embedding providers
+type HTTPConfig struct {
+	ConnectTimeoutSec  int    `mapstructure:"connect_timeout_sec"`  // Dial and TLS handshake timeout (default: 10)
+	ResponseTimeoutSec int    `mapstructure:"response_timeout_sec"` // Max wait for response headers (default: 120)
+	MaxRetries         int    `mapstructure:"max_retries"`          // Retries on 429/5xx/connection errors (default: 2, -1 disables)
+	RetryBaseDelayMs   int    `mapstructure:"retry_base_delay_ms"`  // Initial jittered backoff (default: 500)
+	RetryMaxDelayMs    int    `mapstructure:"retry_max_delay_ms"`   // Backoff cap and max Retry-After waited for (default: 10000)
+	ProxyURL           string `mapstructure:"proxy_url"`            // Default proxy, empty = HTTP(S)_PROXY env
 }
 
 func Load(configPath string) (*Config, error) {
@@ -110,6 +165,12 @@ func Load(configPath string) (*Config, error) {
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	cfg.File = v.ConfigFileUsed()
+
+	// Retries moved from the chat service to the HTTP client
+	if v.IsSet("llm.max_retries") {
+		log.Printf("Config: llm.max_retries is no longer used, set http.max_retries instead")
+	}
 
 	// Override encryption key from environment if set
 	if envKey := os.Getenv("LLM_AGENT_ENCRYPTION_KEY"); envKey != "" {
@@ -119,6 +180,8 @@ func Load(configPath string) (*Config, error) {
 	// Apply default values for Memory config
 	cfg.Memory.applyDefaults()
 	cfg.LLM.applyDefaults()
+	cfg.HTTP.applyDefaults()
+	cfg.Auth.applyDefaults()
 
 	// Validate
 	if err := cfg.Validate(); err != nil {
@@ -144,6 +207,12 @@ func (c *Config) Address() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 }
 
+// SaltPath returns the path of the salt stretching passphrase encryption keys, kept
+// next to the database
+func (c *Config) SaltPath() string {
+	return filepath.Join(filepath.Dir(c.Database.Path), "encryption.salt")
+}
+
 // applyDefaults sets default values for MemoryConfig if not specified
 func (m *MemoryConfig) applyDefaults() {
 	if m.ConflictDetectionThreshold <= 0 {
@@ -187,6 +256,15 @@ func (m *MemoryConfig) applyDefaults() {
 	if m.DefaultImportance <= 0 {
 		m.DefaultImportance = 0.5
 	}
+	if m.ImportExtractionRPM <= 0 {
+		m.ImportExtractionRPM = 6
+	}
+	if m.ImportMaxUploadMB <= 0 {
+		m.ImportMaxUploadMB = 512
+	}
+	if m.ImportMaxRecords <= 0 {
+		m.ImportMaxRecords = 1000
+	}
 }
 
 // applyDefaults sets default values for LLMDefaults if not specified
@@ -203,4 +281,38 @@ func (l *LLMDefaults) applyDefaults() {
 	if l.TitleMaxLength <= 0 {
 		l.TitleMaxLength = 50
 	}
+	if l.BackgroundBudgetRatio <= 0 || l.BackgroundBudgetRatio > 1 {
+		l.BackgroundBudgetRatio = 0.8
+	}
+}
+
+// applyDefaults sets default values for HTTPConfig if not specified
+func (h *HTTPConfig) applyDefaults() {
+	if h.ConnectTimeoutSec <= 0 {
+		h.ConnectTimeoutSec = 10
+	}
+	if h.ResponseTimeoutSec <= 0 {
+		h.ResponseTimeoutSec = 120
+	}
+	if h.MaxRetries == 0 {
+		h.MaxRetries = 2
+	} else if h.MaxRetries < 0 {
+		h.MaxRetries = 0
+	}
+	if h.RetryBaseDelayMs <= 0 {
+		h.RetryBaseDelayMs = 500
+	}
+	if h.RetryMaxDelayMs <= 0 {
+		h.RetryMaxDelayMs = 10000
+	}
+}
+
+// applyDefaults sets default values for AuthConfig if not specified
+func (a *AuthConfig) applyDefaults() {
+	if a.AdminUsername == "" {
+		a.AdminUsername = "admin"
+	}
+	if a.TokenTTLHours <= 0 {
+		a.TokenTTLHours = 720
+	}
 }
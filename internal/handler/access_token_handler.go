@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AccessTokenHandler handles personal access token HTTP requests
+type AccessTokenHandler struct {
+	service *service.AccessTokenService
+}
+
+// NewAccessTokenHandler creates a new access token handler
+func NewAccessTokenHandler(service *service.AccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{service: service}
+}
+
+// Create creates a personal access token. The token is only returned here.
+// POST /api/v1/tokens
+func (h *AccessTokenHandler) Create(c *gin.Context) {
+	var req model.CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.service.Create(currentUserID(c), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// GetAll retrieves the current user's access tokens
+// GET /api/v1/tokens
+func (h *AccessTokenHandler) GetAll(c *gin.Context) {
+	tokens, err := h.service.GetAll(currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Delete revokes an access token
+// DELETE /api/v1/tokens/:id
+func (h *AccessTokenHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(currentUserID(c), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
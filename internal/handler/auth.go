@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	userContextKey        = "user"
+	tokenContextKey       = "token"
+	accessTokenContextKey = "access_token"
+)
+
+// Authenticate resolves the user of a request from its bearer token, either a login
+// session or a personal access token. With authentication disabled every request acts
+// as the local user.
+func Authenticate(userService *service.UserService, accessTokenService *service.AccessTokenService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !userService.Enabled() {
+			c.Set(userContextKey, userService.LocalUser())
+			c.Next()
+			return
+		}
+
+		token := bearerToken(c)
+		if service.IsAccessToken(token) {
+			user, accessToken, err := accessTokenService.Authenticate(token)
+			if err != nil {
+				c.AbortWithStatusJSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+			c.Set(userContextKey, user)
+			c.Set(accessTokenContextKey, accessToken)
+			c.Next()
+			return
+		}
+
+		user, err := userService.Authenticate(token)
+		if err != nil {
+			c.AbortWithStatusJSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(userContextKey, user)
+		c.Set(tokenContextKey, token)
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests made with a personal access token lacking the scope.
+// Login sessions have every scope.
+func RequireScope(scope model.TokenScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasScope(c, scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access token lacks the " + string(scope) + " scope"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// hasScope reports whether the request may act within the scope; only requests
+// authenticated by an access token are limited to scopes
+func hasScope(c *gin.Context, scope model.TokenScope) bool {
+	if v, ok := c.Get(accessTokenContextKey); ok {
+		if token, ok := v.(*model.AccessToken); ok {
+			return token.HasScope(scope)
+		}
+	}
+	return true
+}
+
+// RequireAuthEnabled rejects requests to features that only work with authentication
+// enabled, such as personal access tokens, which are not checked while it is disabled
+func RequireAuthEnabled(userService *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !userService.Enabled() {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "authentication is disabled"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin rejects requests of users without the admin role
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !currentUser(c).IsAdmin() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": service.ErrForbidden.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// currentUser returns the user set by Authenticate
+func currentUser(c *gin.Context) *model.User {
+	if v, ok := c.Get(userContextKey); ok {
+		if user, ok := v.(*model.User); ok {
+			return user
+		}
+	}
+	// Not behind Authenticate, act as a member without an account
+	return &model.User{Role: model.UserRoleMember}
+}
+
+// currentUserID returns the ID of the user set by Authenticate
+func currentUserID(c *gin.Context) string {
+	return currentUser(c).ID
+}
+
+// bearerToken returns the token of the Authorization header, if any
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/allwaysyou/llm-agent/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// passphraseHeader carries the export passphrase of a backup, kept out of URLs and logs
+const passphraseHeader = "X-Backup-Passphrase"
+
+// BackupHandler handles backup and restore HTTP requests
+type BackupHandler struct {
+	service *service.BackupService
+}
+
+// NewBackupHandler creates a new backup handler
+func NewBackupHandler(service *service.BackupService) *BackupHandler {
+	return &BackupHandler{service: service}
+}
+
+// Backup downloads a zip archive of all data, encrypted with the passphrase of the
+// X-Backup-Passphrase header if set
+// GET /api/v1/admin/backup
+func (h *BackupHandler) Backup(c *gin.Context) {
+	file, err := os.CreateTemp("", "llm-agent-backup-*.zip")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer os.Remove(file.Name())
+
+	manifest, err := h.service.Backup(file, c.GetHeader(passphraseHeader))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	name := fmt.Sprintf("llm-agent-backup-%s.zip", manifest.CreatedAt.Format("20060102-150405"))
+	c.FileAttachment(file.Name(), name)
+}
+
+// Restore replaces all data with an uploaded backup archive (form field "file"). The
+// passphrase goes in the X-Backup-Passphrase header or the "passphrase" form field.
+// POST /api/v1/admin/restore
+func (h *BackupHandler) Restore(c *gin.Context) {
+	upload, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "backup file is required"})
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "llm-agent-upload-*")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "backup.zip")
+	if err := c.SaveUploadedFile(upload, path); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	passphrase := c.GetHeader(passphraseHeader)
+	if passphrase == "" {
+		passphrase = c.PostForm("passphrase")
+	}
+
+	result, err := h.service.Restore(path, passphrase)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
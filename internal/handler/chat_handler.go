@@ -2,7 +2,9 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/allwaysyou/llm-agent/internal/model"
@@ -34,14 +36,77 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 		return
 	}
 
+	if req.Sampling != nil {
+		if err := req.Sampling.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	h.respond(c, &req)
+}
+
+// Regenerate generates another reply to a message on a new branch
+// POST /api/v1/sessions/:id/messages/:messageId/regenerate
+func (h *ChatHandler) Regenerate(c *gin.Context) {
+	var body model.RegenerateRequest
+	// The body is optional
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if body.Sampling != nil {
+		if err := body.Sampling.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	req, err := h.chatService.RegenerateRequest(currentUserID(c), c.Param("id"), c.Param("messageId"), &body)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.respond(c, req)
+}
+
+// Edit sends new content for a user message on a new branch and replies to it
+// POST /api/v1/sessions/:id/messages/:messageId/edit
+func (h *ChatHandler) Edit(c *gin.Context) {
+	var body model.EditMessageRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if body.Sampling != nil {
+		if err := body.Sampling.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	req, err := h.chatService.EditRequest(currentUserID(c), c.Param("id"), c.Param("messageId"), &body)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.respond(c, req)
+}
+
+// respond runs a chat request, streaming the reply if requested
+func (h *ChatHandler) respond(c *gin.Context, req *model.ChatRequest) {
 	if req.Stream {
-		h.handleStream(c, &req)
+		h.handleStream(c, req)
 		return
 	}
 
-	resp, err := h.chatService.Chat(c.Request.Context(), &req)
+	resp, err := h.chatService.Chat(c.Request.Context(), currentUserID(c), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -50,20 +115,35 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 
 // handleStream handles streaming chat requests
 func (h *ChatHandler) handleStream(c *gin.Context, req *model.ChatRequest) {
-	stream, sessionID, err := h.chatService.ChatStream(c.Request.Context(), req)
+	stream, info, err := h.chatService.ChatStream(c.Request.Context(), currentUserID(c), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
-	c.Header("X-Session-ID", sessionID)
+	c.Header("X-Session-ID", info.SessionID)
+	c.Header("X-Parent-ID", info.ParentID)
+	c.Header("X-Config-ID", info.ConfigID)
+	c.Header("X-Model", info.Model)
+	c.Header("X-Provider", string(info.Provider))
 
 	c.Writer.Flush()
 
 	for chunk := range stream {
+		// Thinking goes out as its own event type so clients can render it apart from the answer
+		if chunk.Reasoning != "" {
+			data, _ := json.Marshal(model.StreamChunk{ID: chunk.ID, Delta: chunk.Reasoning})
+			_, _ = fmt.Fprintf(c.Writer, "event: reasoning\ndata: %s\n\n", string(data))
+			chunk.Reasoning = ""
+			if chunk.Delta == "" && !chunk.Done {
+				c.Writer.Flush()
+				continue
+			}
+		}
+
 		data, _ := json.Marshal(chunk)
 		_, _ = fmt.Fprintf(c.Writer, "event: message\ndata: %s\n\n", string(data))
 		c.Writer.Flush()
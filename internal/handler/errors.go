@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/allwaysyou/llm-agent/internal/adapter"
+	"github.com/allwaysyou/llm-agent/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// errorStatus maps a service or adapter error to an HTTP status code
+func errorStatus(err error) int {
+	if errors.Is(err, service.ErrBudgetExceeded) || errors.Is(err, service.ErrRateLimited) {
+		return http.StatusTooManyRequests
+	}
+	if errors.Is(err, service.ErrUnauthenticated) || errors.Is(err, service.ErrInvalidCredentials) {
+		return http.StatusUnauthorized
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, service.ErrMessageNotFound) || errors.Is(err, service.ErrSessionNotFound) ||
+		errors.Is(err, service.ErrPersonaNotFound) || errors.Is(err, service.ErrModelConfigNotFound) ||
+		errors.Is(err, service.ErrPromptNotFound) || errors.Is(err, service.ErrWorkspaceNotFound) ||
+		errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrKnowledgeNotFound) ||
+		errors.Is(err, service.ErrAccessTokenNotFound) || errors.Is(err, service.ErrExtractionJobNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, service.ErrInvalidParent) || errors.Is(err, service.ErrNotAFork) ||
+		errors.Is(err, service.ErrForkUnchanged) ||
+		errors.Is(err, service.ErrInvalidPrompt) || errors.Is(err, service.ErrBuiltinPrompt) ||
+		errors.Is(err, service.ErrInvalidUser) || errors.Is(err, service.ErrInvalidAccessToken) ||
+		errors.Is(err, service.ErrInvalidProvider) || errors.Is(err, service.ErrInvalidBackup) ||
+		errors.Is(err, service.ErrBackupPassphrase) || errors.Is(err, service.ErrBackupKeyMismatch) ||
+		errors.Is(err, service.ErrInvalidKnowledgeFile) || errors.Is(err, service.ErrInvalidHistory) ||
+		errors.Is(err, service.ErrExtractionUnavailable) || errors.Is(err, service.ErrMixedCurrency) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, service.ErrPromptExists) || errors.Is(err, service.ErrUserExists) ||
+		errors.Is(err, service.ErrForkMerged) {
+		return http.StatusConflict
+	}
+
+	var apiErr *adapter.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Kind {
+		case adapter.ErrorKindRateLimit:
+			return http.StatusTooManyRequests
+		case adapter.ErrorKindContextLength:
+			return http.StatusRequestEntityTooLarge
+		case adapter.ErrorKindContentFilter:
+			return http.StatusUnprocessableEntity
+		case adapter.ErrorKindInvalidRequest:
+			return http.StatusBadRequest
+		default:
+			// Auth, permission, quota, not found and server errors are upstream
+			// misconfigurations or failures, not problems with the client's request
+			return http.StatusBadGateway
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return http.StatusGatewayTimeout
+		}
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// errorBody builds the JSON error body, including the provider error kind and code if known
+func errorBody(c *gin.Context, err error) gin.H {
+	body := gin.H{"error": err.Error()}
+
+	var apiErr *adapter.APIError
+	if errors.As(err, &apiErr) {
+		body["kind"] = apiErr.Kind
+		if apiErr.Code != "" {
+			body["code"] = apiErr.Code
+		}
+		if apiErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(apiErr.RetryAfter.Seconds()))))
+		}
+	}
+	return body
+}
+
+// respondError writes an error response with a status matching the cause
+func respondError(c *gin.Context, err error) {
+	c.JSON(errorStatus(err), errorBody(c, err))
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// HistoryImportHandler handles chat history import HTTP requests
+type HistoryImportHandler struct {
+	service       *service.HistoryImportService
+	maxUploadSize int64 // Bytes
+}
+
+// NewHistoryImportHandler creates a new chat history import handler accepting exports of
+// up to maxUploadSize bytes
+func NewHistoryImportHandler(service *service.HistoryImportService, maxUploadSize int64) *HistoryImportHandler {
+	return &HistoryImportHandler{service: service, maxUploadSize: maxUploadSize}
+}
+
+// Import creates sessions from an uploaded ChatGPT or Claude export (form field "file"),
+// either conversations.json or the export zip. With extract=true knowledge is extracted
+// from the imported turns by a background job.
+// POST /api/v1/history/import?workspace_id=xxx&extract=true&config_id=xxx
+func (h *HistoryImportHandler) Import(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUploadSize)
+	upload, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("export file exceeds the upload limit of %d MB", h.maxUploadSize>>20)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "export file is required"})
+		return
+	}
+	extract := c.DefaultQuery("extract", "false") == "true"
+	if extract && !hasScope(c, model.ScopeKnowledgeWrite) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access token lacks the " + string(model.ScopeKnowledgeWrite) + " scope"})
+		return
+	}
+
+	file, err := upload.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	result, err := h.service.Import(c.Request.Context(), currentUserID(c), file, upload.Size, c.Query("workspace_id"), extract, c.Query("config_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetJobs lists the user's knowledge extraction jobs since the server started
+// GET /api/v1/history/import/jobs
+func (h *HistoryImportHandler) GetJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.GetJobs(currentUserID(c)))
+}
+
+// GetJob returns the progress of a knowledge extraction job
+// GET /api/v1/history/import/jobs/:id
+func (h *HistoryImportHandler) GetJob(c *gin.Context) {
+	job, err := h.service.GetJob(currentUserID(c), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJob stops a running knowledge extraction job
+// DELETE /api/v1/history/import/jobs/:id
+func (h *HistoryImportHandler) CancelJob(c *gin.Context) {
+	job, err := h.service.CancelJob(currentUserID(c), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/pkg/knowledgeio"
+	"github.com/allwaysyou/llm-agent/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// KnowledgeTransferHandler handles knowledge export and import HTTP requests
+type KnowledgeTransferHandler struct {
+	service *service.KnowledgeTransferService
+}
+
+// NewKnowledgeTransferHandler creates a new knowledge transfer handler
+func NewKnowledgeTransferHandler(service *service.KnowledgeTransferService) *KnowledgeTransferHandler {
+	return &KnowledgeTransferHandler{service: service}
+}
+
+// Export downloads the user's knowledge as JSON Lines, Markdown or CSV
+// GET /api/v1/knowledge/export?format=jsonl&workspace_id=xxx&active_only=false
+func (h *KnowledgeTransferHandler) Export(c *gin.Context) {
+	format, err := knowledgeio.ParseFormat(c.DefaultQuery("format", string(knowledgeio.FormatJSONL)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	activeOnly := c.DefaultQuery("active_only", "false") == "true"
+
+	var buf bytes.Buffer
+	if _, err := h.service.Export(c.Request.Context(), currentUserID(c), workspaceQuery(c), activeOnly, format, &buf); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	name := fmt.Sprintf("knowledge-%s.%s", time.Now().Format("20060102-150405"), format.Extension())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	c.Data(http.StatusOK, format.ContentType(), buf.Bytes())
+}
+
+// Import adds the knowledge of an uploaded file (form field "file") to the user's
+// knowledge, skipping duplicates. The format is taken from the file extension unless
+// given. Records go to the workspace given, or else to the one they name.
+// POST /api/v1/knowledge/import?format=jsonl&workspace_id=xxx&config_id=xxx
+func (h *KnowledgeTransferHandler) Import(c *gin.Context) {
+	upload, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "knowledge file is required"})
+		return
+	}
+
+	var format knowledgeio.Format
+	if name := c.Query("format"); name != "" {
+		format, err = knowledgeio.ParseFormat(name)
+	} else {
+		format, err = knowledgeio.FormatOf(upload.Filename)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := upload.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	result, err := h.service.Import(c.Request.Context(), currentUserID(c), file, format, workspaceQuery(c), c.Query("config_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -24,7 +24,7 @@ func NewMemoryHandler(memoryService *service.MemoryService, summarizeService *se
 }
 
 // Search searches for relevant memories
-// GET /api/v1/memories/search?query=xxx&session_id=xxx&limit=10
+// GET /api/v1/memories/search?query=xxx&session_id=xxx&workspace_id=xxx&limit=10
 func (h *MemoryHandler) Search(c *gin.Context) {
 	query := c.Query("query")
 	if query == "" {
@@ -35,9 +35,9 @@ func (h *MemoryHandler) Search(c *gin.Context) {
 	sessionID := c.Query("session_id")
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	results, err := h.memoryService.SearchMemories(c.Request.Context(), query, sessionID, limit)
+	results, err := h.memoryService.SearchMemories(c.Request.Context(), currentUserID(c), query, sessionID, workspaceQuery(c), limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -49,9 +49,9 @@ func (h *MemoryHandler) Search(c *gin.Context) {
 func (h *MemoryHandler) Summarize(c *gin.Context) {
 	sessionID := c.Param("id")
 
-	summary, err := h.summarizeService.SummarizeSession(c.Request.Context(), sessionID)
+	summary, err := h.summarizeService.SummarizeSession(c.Request.Context(), currentUserID(c), sessionID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -75,9 +75,9 @@ func (h *MemoryHandler) Create(c *gin.Context) {
 		return
 	}
 
-	memory, err := h.memoryService.SaveMemory(c.Request.Context(), req.SessionID, req.Role, req.Content)
+	memory, err := h.memoryService.SaveMemory(c.Request.Context(), currentUserID(c), req.SessionID, req.Role, req.Content)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -85,12 +85,12 @@ func (h *MemoryHandler) Create(c *gin.Context) {
 }
 
 // GetAllKnowledge returns all knowledge entries
-// GET /api/v1/knowledge?active_only=true&limit=100
+// GET /api/v1/knowledge?active_only=true&limit=100&workspace_id=xxx
 func (h *MemoryHandler) GetAllKnowledge(c *gin.Context) {
 	activeOnly := c.DefaultQuery("active_only", "true") == "true"
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
 
-	knowledge, err := h.memoryService.GetAllKnowledge(c.Request.Context(), activeOnly, limit)
+	knowledge, err := h.memoryService.GetAllKnowledge(c.Request.Context(), currentUserID(c), workspaceQuery(c), activeOnly, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -104,7 +104,7 @@ func (h *MemoryHandler) GetAllKnowledge(c *gin.Context) {
 func (h *MemoryHandler) GetKnowledge(c *gin.Context) {
 	id := c.Param("id")
 
-	knowledge, err := h.memoryService.GetKnowledge(c.Request.Context(), id)
+	knowledge, err := h.memoryService.GetKnowledge(c.Request.Context(), currentUserID(c), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -121,7 +121,8 @@ func (h *MemoryHandler) GetKnowledge(c *gin.Context) {
 // POST /api/v1/knowledge
 func (h *MemoryHandler) CreateKnowledge(c *gin.Context) {
 	var req struct {
-		Content string `json:"content" binding:"required"`
+		Content     string `json:"content" binding:"required"`
+		WorkspaceID string `json:"workspace_id"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -129,9 +130,9 @@ func (h *MemoryHandler) CreateKnowledge(c *gin.Context) {
 		return
 	}
 
-	knowledge, err := h.memoryService.CreateKnowledge(c.Request.Context(), req.Content)
+	knowledge, err := h.memoryService.CreateKnowledge(c.Request.Context(), currentUserID(c), req.Content, req.WorkspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -152,9 +153,9 @@ func (h *MemoryHandler) UpdateKnowledge(c *gin.Context) {
 		return
 	}
 
-	knowledge, err := h.memoryService.UpdateKnowledge(c.Request.Context(), id, req.Content)
+	knowledge, err := h.memoryService.UpdateKnowledge(c.Request.Context(), currentUserID(c), id, req.Content)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -166,8 +167,8 @@ func (h *MemoryHandler) UpdateKnowledge(c *gin.Context) {
 func (h *MemoryHandler) DeleteKnowledge(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := h.memoryService.DeleteKnowledge(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.memoryService.DeleteKnowledge(c.Request.Context(), currentUserID(c), id); err != nil {
+		respondError(c, err)
 		return
 	}
 
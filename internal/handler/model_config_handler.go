@@ -36,13 +36,13 @@ func (h *ModelConfigHandler) Create(c *gin.Context) {
 		return
 	}
 
-	config, err := h.service.Create(&req)
+	config, err := h.service.Create(currentUser(c), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, config.ToResponse())
+	c.JSON(http.StatusCreated, h.service.ToResponse(config))
 }
 
 // GetAll retrieves all model configs
@@ -55,9 +55,9 @@ func (h *ModelConfigHandler) GetAll(c *gin.Context) {
 	var err error
 
 	if providerID != "" {
-		configs, err = h.service.GetByProvider(providerID)
+		configs, err = h.service.GetByProvider(currentUserID(c), providerID)
 	} else {
-		configs, err = h.service.GetAll()
+		configs, err = h.service.GetAll(currentUserID(c))
 	}
 
 	if err != nil {
@@ -66,8 +66,8 @@ func (h *ModelConfigHandler) GetAll(c *gin.Context) {
 	}
 
 	responses := make([]model.ModelConfigResponse, len(configs))
-	for i, cfg := range configs {
-		responses[i] = cfg.ToResponse()
+	for i := range configs {
+		responses[i] = h.service.ToResponse(&configs[i])
 	}
 
 	c.JSON(http.StatusOK, responses)
@@ -78,7 +78,7 @@ func (h *ModelConfigHandler) GetAll(c *gin.Context) {
 func (h *ModelConfigHandler) GetByID(c *gin.Context) {
 	id := c.Param("id")
 
-	config, err := h.service.GetByID(id)
+	config, err := h.service.GetByID(currentUserID(c), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -88,7 +88,7 @@ func (h *ModelConfigHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, config.ToResponse())
+	c.JSON(http.StatusOK, h.service.ToResponse(config))
 }
 
 // Update updates a model config
@@ -102,13 +102,13 @@ func (h *ModelConfigHandler) Update(c *gin.Context) {
 		return
 	}
 
-	config, err := h.service.Update(id, &req)
+	config, err := h.service.Update(currentUser(c), id, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, config.ToResponse())
+	c.JSON(http.StatusOK, h.service.ToResponse(config))
 }
 
 // Delete deletes a model config
@@ -116,8 +116,8 @@ func (h *ModelConfigHandler) Update(c *gin.Context) {
 func (h *ModelConfigHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := h.service.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.Delete(currentUser(c), id); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -129,8 +129,8 @@ func (h *ModelConfigHandler) Delete(c *gin.Context) {
 func (h *ModelConfigHandler) SetDefault(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := h.service.SetDefault(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.SetDefault(currentUser(c), id); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -142,7 +142,7 @@ func (h *ModelConfigHandler) SetDefault(c *gin.Context) {
 func (h *ModelConfigHandler) Test(c *gin.Context) {
 	id := c.Param("id")
 
-	config, err := h.service.GetByID(id)
+	config, err := h.service.GetByID(currentUserID(c), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -153,7 +153,7 @@ func (h *ModelConfigHandler) Test(c *gin.Context) {
 	}
 
 	// Get provider
-	provider, err := h.providerService.GetByID(config.ProviderID)
+	provider, err := h.providerService.GetByID(currentUserID(c), config.ProviderID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -163,21 +163,17 @@ func (h *ModelConfigHandler) Test(c *gin.Context) {
 		return
 	}
 
-	// Decrypt API key
-	apiKey, err := h.providerService.DecryptAPIKey(provider.APIKey)
+	// Decrypt API key and set up the HTTP client
+	adapterCfg, err := h.providerService.AdapterConfig(provider)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decrypt API key"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Create adapter
-	adapterCfg := adapter.AdapterConfig{
-		APIKey:      apiKey,
-		BaseURL:     provider.BaseURL,
-		Model:       config.Model,
-		MaxTokens:   config.MaxTokens,
-		Temperature: config.Temperature,
-	}
+	adapterCfg.Model = config.Model
+	adapterCfg.MaxTokens = config.MaxTokens
+	adapterCfg.Sampling = config.EffectiveSampling()
 
 	llmAdapter, err := h.adapterFactory.Create(provider.Type, adapterCfg)
 	if err != nil {
@@ -194,10 +190,9 @@ func (h *ModelConfigHandler) Test(c *gin.Context) {
 		// Test embedding
 		_, err = llmAdapter.GetEmbedding(ctx, "test")
 		if err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"success": false,
-				"error":   err.Error(),
-			})
+			body := errorBody(c, err)
+			body["success"] = false
+			c.JSON(errorStatus(err), body)
 			return
 		}
 	} else {
@@ -207,10 +202,9 @@ func (h *ModelConfigHandler) Test(c *gin.Context) {
 		}
 		_, err = llmAdapter.Chat(ctx, messages)
 		if err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"success": false,
-				"error":   err.Error(),
-			})
+			body := errorBody(c, err)
+			body["success"] = false
+			c.JSON(errorStatus(err), body)
 			return
 		}
 	}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// PersonaHandler handles persona HTTP requests
+type PersonaHandler struct {
+	service *service.PersonaService
+}
+
+// NewPersonaHandler creates a new persona handler
+func NewPersonaHandler(service *service.PersonaService) *PersonaHandler {
+	return &PersonaHandler{service: service}
+}
+
+// Create creates a new persona
+// POST /api/v1/personas
+func (h *PersonaHandler) Create(c *gin.Context) {
+	var req model.CreatePersonaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Sampling.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	persona, err := h.service.Create(currentUser(c), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, persona)
+}
+
+// GetAll retrieves all personas
+// GET /api/v1/personas
+func (h *PersonaHandler) GetAll(c *gin.Context) {
+	personas, err := h.service.GetAll(currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, personas)
+}
+
+// GetByID retrieves a persona by ID
+// GET /api/v1/personas/:id
+func (h *PersonaHandler) GetByID(c *gin.Context) {
+	persona, err := h.service.GetByID(currentUserID(c), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if persona == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "persona not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, persona)
+}
+
+// Update updates a persona
+// PUT /api/v1/personas/:id
+func (h *PersonaHandler) Update(c *gin.Context) {
+	var req model.UpdatePersonaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Sampling != nil {
+		if err := req.Sampling.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	persona, err := h.service.Update(currentUser(c), c.Param("id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, persona)
+}
+
+// Delete deletes a persona
+// DELETE /api/v1/personas/:id
+func (h *PersonaHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(currentUser(c), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// PromptHandler handles prompt template HTTP requests
+type PromptHandler struct {
+	service *service.PromptService
+}
+
+// NewPromptHandler creates a new prompt handler
+func NewPromptHandler(service *service.PromptService) *PromptHandler {
+	return &PromptHandler{service: service}
+}
+
+// GetAll retrieves the active version of every prompt
+// GET /api/v1/prompts?kind=slash&language=zh
+func (h *PromptHandler) GetAll(c *gin.Context) {
+	prompts, err := h.service.GetAll(model.PromptKind(c.Query("kind")), c.Query("language"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prompts)
+}
+
+// GetByID retrieves a prompt version
+// GET /api/v1/prompts/:id
+func (h *PromptHandler) GetByID(c *gin.Context) {
+	prompt, err := h.service.GetByID(c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prompt)
+}
+
+// GetVersions retrieves all versions of a prompt, newest first
+// GET /api/v1/prompts/:id/versions
+func (h *PromptHandler) GetVersions(c *gin.Context) {
+	versions, err := h.service.GetVersions(c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// Create creates a slash prompt or a new language variant of a prompt
+// POST /api/v1/prompts
+func (h *PromptHandler) Create(c *gin.Context) {
+	var req model.CreatePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prompt, err := h.service.Create(&req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, prompt)
+}
+
+// Update saves a new version of a prompt
+// PUT /api/v1/prompts/:id
+func (h *PromptHandler) Update(c *gin.Context) {
+	var req model.UpdatePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prompt, err := h.service.Update(c.Param("id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prompt)
+}
+
+// Activate makes a prompt version the one in use
+// POST /api/v1/prompts/:id/activate
+func (h *PromptHandler) Activate(c *gin.Context) {
+	prompt, err := h.service.Activate(c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prompt)
+}
+
+// Render fills in a prompt's variables
+// POST /api/v1/prompts/:id/render
+func (h *PromptHandler) Render(c *gin.Context) {
+	var req model.RenderPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	content, err := h.service.Render(c.Param("id"), req.Variables)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.RenderPromptResponse{Content: content})
+}
+
+// Delete deletes all versions of a prompt
+// DELETE /api/v1/prompts/:id
+func (h *PromptHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
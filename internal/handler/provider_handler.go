@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
@@ -14,13 +15,15 @@ import (
 // ProviderHandler handles Provider HTTP requests
 type ProviderHandler struct {
 	service        *service.ProviderService
+	catalog        *service.ModelCatalogService
 	adapterFactory *adapter.AdapterFactory
 }
 
 // NewProviderHandler creates a new provider handler
-func NewProviderHandler(service *service.ProviderService, adapterFactory *adapter.AdapterFactory) *ProviderHandler {
+func NewProviderHandler(service *service.ProviderService, catalog *service.ModelCatalogService, adapterFactory *adapter.AdapterFactory) *ProviderHandler {
 	return &ProviderHandler{
 		service:        service,
+		catalog:        catalog,
 		adapterFactory: adapterFactory,
 	}
 }
@@ -34,9 +37,9 @@ func (h *ProviderHandler) Create(c *gin.Context) {
 		return
 	}
 
-	provider, err := h.service.Create(&req)
+	provider, err := h.service.Create(currentUser(c), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -46,7 +49,7 @@ func (h *ProviderHandler) Create(c *gin.Context) {
 // GetAll retrieves all providers
 // GET /api/v1/providers
 func (h *ProviderHandler) GetAll(c *gin.Context) {
-	providers, err := h.service.GetAll()
+	providers, err := h.service.GetAll(currentUserID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -65,7 +68,7 @@ func (h *ProviderHandler) GetAll(c *gin.Context) {
 func (h *ProviderHandler) GetByID(c *gin.Context) {
 	id := c.Param("id")
 
-	resp, err := h.service.GetByIDWithModels(id)
+	resp, err := h.service.GetByIDWithModels(currentUserID(c), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -89,11 +92,12 @@ func (h *ProviderHandler) Update(c *gin.Context) {
 		return
 	}
 
-	provider, err := h.service.Update(id, &req)
+	provider, err := h.service.Update(currentUser(c), id, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
+	h.catalog.Invalidate(id)
 
 	c.JSON(http.StatusOK, provider.ToResponse())
 }
@@ -103,20 +107,47 @@ func (h *ProviderHandler) Update(c *gin.Context) {
 func (h *ProviderHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := h.service.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.Delete(currentUser(c), id); err != nil {
+		respondError(c, err)
 		return
 	}
+	h.catalog.Invalidate(id)
 
 	c.JSON(http.StatusNoContent, nil)
 }
 
+// ListModels lists the models available from a provider
+// GET /api/v1/providers/:id/models?refresh=true
+func (h *ProviderHandler) ListModels(c *gin.Context) {
+	id := c.Param("id")
+	refresh := c.Query("refresh") == "true"
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	models, err := h.catalog.ListModels(ctx, currentUserID(c), id, refresh)
+	if errors.Is(err, service.ErrModelListingUnsupported) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if models == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "provider not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models)
+}
+
 // Test tests a provider's API key
 // POST /api/v1/providers/:id/test
 func (h *ProviderHandler) Test(c *gin.Context) {
 	id := c.Param("id")
 
-	provider, err := h.service.GetByID(id)
+	provider, err := h.service.GetByID(currentUserID(c), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -126,10 +157,10 @@ func (h *ProviderHandler) Test(c *gin.Context) {
 		return
 	}
 
-	// Decrypt API key
-	apiKey, err := h.service.DecryptAPIKey(provider.APIKey)
+	// Decrypt API key and set up the HTTP client
+	adapterCfg, err := h.service.AdapterConfig(provider)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decrypt API key"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -140,15 +171,27 @@ func (h *ProviderHandler) Test(c *gin.Context) {
 		testModel = "claude-3-haiku-20240307"
 	case model.ProviderTypeOllama:
 		testModel = "llama2"
-	}
-
-	adapterCfg := adapter.AdapterConfig{
-		APIKey:      apiKey,
-		BaseURL:     provider.BaseURL,
-		Model:       testModel,
-		MaxTokens:   100,
-		Temperature: 0.7,
-	}
+	case model.ProviderTypeGemini:
+		testModel = "gemini-2.0-flash"
+	case model.ProviderTypeCustom:
+		// Custom endpoints serve arbitrary models, test with one configured for this provider
+		testModel = ""
+		if resp, err := h.service.GetByIDWithModels(currentUserID(c), provider.ID); err == nil && resp != nil {
+			for _, m := range resp.Models {
+				if m.ConfigType != model.ConfigTypeEmbedding {
+					testModel = m.Model
+					break
+				}
+			}
+		}
+		if testModel == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "add a chat model to this provider before testing it"})
+			return
+		}
+	}
+
+	adapterCfg.Model = testModel
+	adapterCfg.MaxTokens = 100
 
 	llmAdapter, err := h.adapterFactory.Create(provider.Type, adapterCfg)
 	if err != nil {
@@ -166,10 +209,9 @@ func (h *ProviderHandler) Test(c *gin.Context) {
 	}
 	_, err = llmAdapter.Chat(ctx, messages)
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"success": false,
-			"error":   err.Error(),
-		})
+		body := errorBody(c, err)
+		body["success"] = false
+		c.JSON(errorStatus(err), body)
 		return
 	}
 
@@ -1,35 +1,99 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
 	"github.com/allwaysyou/llm-agent/internal/model"
 	"github.com/allwaysyou/llm-agent/internal/repository"
+	"github.com/allwaysyou/llm-agent/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
 // SessionHandler handles session HTTP requests
 type SessionHandler struct {
-	sessionRepo *repository.SessionRepository
-	memoryRepo  *repository.MemoryRepository
+	sessionRepo    *repository.SessionRepository
+	memoryRepo     *repository.MemoryRepository
+	sessionService *service.SessionService
 }
 
 // NewSessionHandler creates a new session handler
-func NewSessionHandler(sessionRepo *repository.SessionRepository, memoryRepo *repository.MemoryRepository) *SessionHandler {
+func NewSessionHandler(sessionRepo *repository.SessionRepository, memoryRepo *repository.MemoryRepository, sessionService *service.SessionService) *SessionHandler {
 	return &SessionHandler{
-		sessionRepo: sessionRepo,
-		memoryRepo:  memoryRepo,
+		sessionRepo:    sessionRepo,
+		memoryRepo:     memoryRepo,
+		sessionService: sessionService,
 	}
 }
 
-// GetAll retrieves all sessions
-// GET /api/v1/sessions
+// Create creates an empty session with a system prompt, persona, model and sampling overrides
+// POST /api/v1/sessions
+func (h *SessionHandler) Create(c *gin.Context) {
+	var req model.CreateSessionRequest
+	// The body is optional
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Sampling != nil {
+		if err := req.Sampling.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	session, err := h.sessionService.Create(currentUserID(c), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, session.ToResponse())
+}
+
+// Update updates a session's title, system prompt, persona, model and sampling overrides
+// PUT /api/v1/sessions/:id
+func (h *SessionHandler) Update(c *gin.Context) {
+	var req model.UpdateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Sampling != nil {
+		if err := req.Sampling.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	session, err := h.sessionService.Update(currentUserID(c), c.Param("id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session.ToResponse())
+}
+
+// GetAll retrieves all sessions, optionally of one workspace only
+// GET /api/v1/sessions?workspace_id=xxx
 func (h *SessionHandler) GetAll(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
-	sessions, err := h.sessionRepo.GetAll(limit, offset)
+	sessions, err := h.sessionRepo.GetAll(currentUserID(c), workspaceQuery(c), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]string, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.ID
+	}
+	forkIDs, err := h.sessionRepo.GetForkIDs(ids)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -38,17 +102,18 @@ func (h *SessionHandler) GetAll(c *gin.Context) {
 	responses := make([]model.SessionResponse, len(sessions))
 	for i, s := range sessions {
 		responses[i] = s.ToResponse()
+		responses[i].ForkIDs = forkIDs[s.ID]
 	}
 
 	c.JSON(http.StatusOK, responses)
 }
 
-// GetByID retrieves a session by ID with its messages
+// GetByID retrieves a session by ID with the messages of its active branch
 // GET /api/v1/sessions/:id
 func (h *SessionHandler) GetByID(c *gin.Context) {
 	id := c.Param("id")
 
-	session, err := h.sessionRepo.GetByID(id)
+	session, err := h.sessionRepo.GetByID(currentUserID(c), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -58,32 +123,169 @@ func (h *SessionHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	// Get messages for this session
-	memories, err := h.memoryRepo.GetBySessionID(id, 0)
+	h.respondWithBranch(c, session)
+}
+
+// SwitchBranch makes the branch containing a message active. The branch is followed
+// down to its most recent reply.
+// PUT /api/v1/sessions/:id/branch
+func (h *SessionHandler) SwitchBranch(c *gin.Context) {
+	id := c.Param("id")
+
+	var req model.SwitchBranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.sessionRepo.GetByID(currentUserID(c), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if session == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
 
-	messages := make([]model.MessageWithID, len(memories))
-	for i, m := range memories {
-		messages[i] = model.MessageWithID{
-			ID:      m.ID,
-			Role:    m.Role,
-			Content: m.Content,
-		}
+	memory, err := h.memoryRepo.GetByID(req.MessageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if memory == nil || memory.SessionID != id {
+		c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+		return
+	}
+
+	leafID, err := h.memoryRepo.GetLatestLeaf(id, memory.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.sessionRepo.UpdateActiveLeaf(id, leafID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	session.ActiveLeafID = leafID
+
+	h.respondWithBranch(c, session)
+}
+
+// respondWithBranch writes the session with the messages of its active branch
+func (h *SessionHandler) respondWithBranch(c *gin.Context, session *model.Session) {
+	memories, err := h.memoryRepo.GetBySessionID(session.ID, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	forkIDs, err := h.sessionRepo.GetForkIDs([]string{session.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := session.ToResponse()
+	response.ForkIDs = forkIDs[session.ID]
 
 	c.JSON(http.StatusOK, gin.H{
-		"session":  session.ToResponse(),
-		"messages": messages,
+		"session":  response,
+		"messages": branchMessages(memories, session.ActiveLeafID),
 	})
 }
 
+// Fork copies a session's conversation up to a message into a new session
+// POST /api/v1/sessions/:id/fork
+func (h *SessionHandler) Fork(c *gin.Context) {
+	var req model.ForkSessionRequest
+	// The body is optional
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fork, err := h.sessionService.Fork(currentUserID(c), c.Param("id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, fork.ToResponse())
+}
+
+// Merge summarizes a fork and appends the summary to the session it was forked from
+// POST /api/v1/sessions/:id/merge
+func (h *SessionHandler) Merge(c *gin.Context) {
+	origin, err := h.sessionService.Merge(c.Request.Context(), currentUserID(c), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, origin.ToResponse())
+}
+
+// branchMessages returns the messages on the path from the root to leafID, each with
+// the IDs of its alternative versions. memories must be ordered by creation time.
+func branchMessages(memories []model.Memory, leafID string) []model.MessageWithID {
+	byID := make(map[string]*model.Memory, len(memories))
+	siblings := make(map[string][]string)
+	for i := range memories {
+		m := &memories[i]
+		byID[m.ID] = m
+		siblings[m.ParentID] = append(siblings[m.ParentID], m.ID)
+	}
+
+	var branch []*model.Memory
+	for id := leafID; id != "" && len(branch) < len(memories); {
+		m, ok := byID[id]
+		if !ok {
+			break
+		}
+		branch = append(branch, m)
+		id = m.ParentID
+	}
+
+	messages := make([]model.MessageWithID, len(branch))
+	for i, m := range branch {
+		msg := model.MessageWithID{
+			ID:        m.ID,
+			ParentID:  m.ParentID,
+			Role:      m.Role,
+			Content:   m.Content,
+			Reasoning: m.Reasoning,
+		}
+		if ids := siblings[m.ParentID]; len(ids) > 1 {
+			msg.SiblingIDs = ids
+		}
+		if m.PromptTokens > 0 || m.CompletionTokens > 0 {
+			msg.Usage = &model.Usage{
+				PromptTokens:     m.PromptTokens,
+				CompletionTokens: m.CompletionTokens,
+				TotalTokens:      m.PromptTokens + m.CompletionTokens,
+			}
+		}
+		// The branch was walked from the leaf, fill from the end
+		messages[len(branch)-1-i] = msg
+	}
+	return messages
+}
+
 // Delete deletes a session and its messages
 // DELETE /api/v1/sessions/:id
 func (h *SessionHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
+	userID := currentUserID(c)
+
+	session, err := h.sessionRepo.GetByID(userID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if session == nil {
+		c.JSON(http.StatusNoContent, nil)
+		return
+	}
 
 	// Delete memories first
 	if err := h.memoryRepo.DeleteBySessionID(id); err != nil {
@@ -92,7 +294,7 @@ func (h *SessionHandler) Delete(c *gin.Context) {
 	}
 
 	// Delete session
-	if err := h.sessionRepo.Delete(id); err != nil {
+	if err := h.sessionRepo.Delete(userID, id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -106,6 +308,16 @@ func (h *SessionHandler) DeleteMessage(c *gin.Context) {
 	sessionID := c.Param("id")
 	messageID := c.Param("messageId")
 
+	session, err := h.sessionRepo.GetByID(currentUserID(c), sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if session == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
 	// Verify the message belongs to this session
 	memory, err := h.memoryRepo.GetByID(messageID)
 	if err != nil {
@@ -121,11 +333,25 @@ func (h *SessionHandler) DeleteMessage(c *gin.Context) {
 		return
 	}
 
-	// Delete the message
-	if err := h.memoryRepo.Delete(messageID); err != nil {
+	// Delete the message, keeping the replies below it attached to its parent
+	if err := h.memoryRepo.DeleteFromBranch(memory); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if session.ActiveLeafID == messageID {
+		// Continue on the latest remaining branch below the parent, which is another
+		// root branch if the deleted message was the first one
+		leafID, err := h.memoryRepo.GetLatestLeaf(sessionID, memory.ParentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := h.sessionRepo.UpdateActiveLeaf(sessionID, leafID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	c.JSON(http.StatusNoContent, nil)
 }
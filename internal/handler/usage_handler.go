@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// UsageHandler handles usage HTTP requests
+type UsageHandler struct {
+	usageService *service.UsageService
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(usageService *service.UsageService) *UsageHandler {
+	return &UsageHandler{usageService: usageService}
+}
+
+// Summary aggregates token usage and cost
+// GET /api/v1/usage?group_by=day|session|model|purpose&session_id=xxx&from=2006-01-02&to=2006-01-02
+func (h *UsageHandler) Summary(c *gin.Context) {
+	q := model.UsageQuery{
+		GroupBy:   model.UsageGroupBy(c.DefaultQuery("group_by", string(model.UsageGroupByDay))),
+		SessionID: c.Query("session_id"),
+	}
+
+	var err error
+	if q.From, err = parseUsageTime(c.Query("from"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+		return
+	}
+	if q.To, err = parseUsageTime(c.Query("to"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+		return
+	}
+
+	items, err := h.usageService.Summarize(q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if items == nil {
+		items = []model.UsageSummary{}
+	}
+
+	total := model.UsageSummary{Key: "total"}
+	for _, item := range items {
+		total.Requests += item.Requests
+		total.PromptTokens += item.PromptTokens
+		total.CompletionTokens += item.CompletionTokens
+		total.TotalTokens += item.TotalTokens
+		total.Cost += item.Cost
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"group_by": q.GroupBy,
+		"items":    items,
+		"total":    total,
+	})
+}
+
+// parseUsageTime accepts either a date (local time) or an RFC3339 timestamp.
+// A bare date used as an upper bound includes the whole day.
+func parseUsageTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
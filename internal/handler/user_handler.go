@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler handles login and user management HTTP requests
+type UserHandler struct {
+	service *service.UserService
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(service *service.UserService) *UserHandler {
+	return &UserHandler{service: service}
+}
+
+// Login checks the credentials and returns a bearer token
+// POST /api/v1/auth/login
+func (h *UserHandler) Login(c *gin.Context) {
+	var req model.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.service.Login(&req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Logout ends the login session of the request's token
+// POST /api/v1/auth/logout
+func (h *UserHandler) Logout(c *gin.Context) {
+	if token := c.GetString(tokenContextKey); token != "" {
+		if err := h.service.Logout(token); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// Me returns the current user and whether authentication is enabled
+// GET /api/v1/auth/me
+func (h *UserHandler) Me(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"user":         currentUser(c),
+		"auth_enabled": h.service.Enabled(),
+	})
+}
+
+// ChangePassword changes the current user's password
+// PUT /api/v1/auth/password
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req model.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.service.Enabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "authentication is disabled"})
+		return
+	}
+
+	if err := h.service.ChangePassword(currentUserID(c), c.GetString(tokenContextKey), &req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed"})
+}
+
+// Create creates a new user
+// POST /api/v1/users
+func (h *UserHandler) Create(c *gin.Context) {
+	var req model.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.service.Create(&req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// GetAll retrieves all users
+// GET /api/v1/users
+func (h *UserHandler) GetAll(c *gin.Context) {
+	users, err := h.service.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// Update updates a user's password, role or disabled state
+// PUT /api/v1/users/:id
+func (h *UserHandler) Update(c *gin.Context) {
+	var req model.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.service.Update(c.Param("id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// Delete deletes a user and all of their data
+// DELETE /api/v1/users/:id
+func (h *UserHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(currentUserID(c), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// WorkspaceHandler handles workspace HTTP requests
+type WorkspaceHandler struct {
+	service *service.WorkspaceService
+}
+
+// NewWorkspaceHandler creates a new workspace handler
+func NewWorkspaceHandler(service *service.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{service: service}
+}
+
+// workspaceQuery returns the workspace_id query parameter: nil when absent (no filter),
+// empty for the global pool
+func workspaceQuery(c *gin.Context) *string {
+	workspaceID, ok := c.GetQuery("workspace_id")
+	if !ok {
+		return nil
+	}
+	return &workspaceID
+}
+
+// Create creates a new workspace
+// POST /api/v1/workspaces
+func (h *WorkspaceHandler) Create(c *gin.Context) {
+	var req model.CreateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspace, err := h.service.Create(currentUserID(c), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, workspace)
+}
+
+// GetAll retrieves all workspaces
+// GET /api/v1/workspaces
+func (h *WorkspaceHandler) GetAll(c *gin.Context) {
+	workspaces, err := h.service.GetAll(currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+}
+
+// GetByID retrieves a workspace by ID
+// GET /api/v1/workspaces/:id
+func (h *WorkspaceHandler) GetByID(c *gin.Context) {
+	workspace, err := h.service.GetByID(currentUserID(c), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+// Update updates a workspace
+// PUT /api/v1/workspaces/:id
+func (h *WorkspaceHandler) Update(c *gin.Context) {
+	var req model.UpdateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspace, err := h.service.Update(currentUserID(c), c.Param("id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+// Delete deletes a workspace with its sessions and knowledge
+// DELETE /api/v1/workspaces/:id
+func (h *WorkspaceHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(currentUserID(c), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
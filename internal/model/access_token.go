@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// TokenScope limits what a personal access token may do
+type TokenScope string
+
+const (
+	ScopeChat           TokenScope = "chat"            // Chat, sessions and reading models, personas and prompts
+	ScopeKnowledgeRead  TokenScope = "knowledge:read"  // Read and search knowledge and workspaces
+	ScopeKnowledgeWrite TokenScope = "knowledge:write" // Create, update and delete knowledge and workspaces
+	ScopeAdmin          TokenScope = "admin"           // Everything the owner may do
+)
+
+// ValidTokenScopes lists the scopes a token can be given
+var ValidTokenScopes = []TokenScope{ScopeChat, ScopeKnowledgeRead, ScopeKnowledgeWrite, ScopeAdmin}
+
+// AccessTokenPrefix starts every personal access token, telling them apart from login sessions
+const AccessTokenPrefix = "pat_"
+
+// AccessToken is a personal access token for scripts and integrations. Only the SHA-256
+// hash of the token is stored.
+type AccessToken struct {
+	ID         string       `json:"id" gorm:"primaryKey"`
+	UserID     string       `json:"-" gorm:"index;not null"`
+	Name       string       `json:"name" gorm:"not null"`
+	TokenHash  string       `json:"-" gorm:"uniqueIndex;not null"`
+	Hint       string       `json:"hint"` // Start of the token, to recognize it
+	Scopes     []TokenScope `json:"scopes" gorm:"serializer:json"`
+	ExpiresAt  *time.Time   `json:"expires_at"` // nil = never
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// HasScope returns true if the token grants the scope. The admin scope grants all
+// scopes and knowledge:write grants knowledge:read.
+func (t *AccessToken) HasScope(scope TokenScope) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ScopeAdmin || (s == ScopeKnowledgeWrite && scope == ScopeKnowledgeRead) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAccessTokenRequest represents the request to create a personal access token
+type CreateAccessTokenRequest struct {
+	Name          string       `json:"name" binding:"required"`
+	Scopes        []TokenScope `json:"scopes" binding:"required"`
+	ExpiresInDays int          `json:"expires_in_days"` // 0 = never expires
+}
+
+// CreateAccessTokenResponse carries the new token, which is shown only once
+type CreateAccessTokenResponse struct {
+	AccessToken
+	Token string `json:"token"`
+}
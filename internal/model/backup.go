@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// BackupFormatVersion is the layout version of backup archives written by this version
+const BackupFormatVersion = 1
+
+// BackupManifest describes a backup archive
+type BackupManifest struct {
+	FormatVersion  int       `json:"format_version"`
+	CreatedAt      time.Time `json:"created_at"`
+	KeyID          string    `json:"key_id"`                    // Key encrypting the secrets in the archive
+	PassphraseSalt string    `json:"passphrase_salt,omitempty"` // Hex salt of the export passphrase, if one was used
+	AtRest         bool      `json:"at_rest"`                   // Content is encrypted, not only provider API keys
+	Documents      int       `json:"documents"`                 // Vector store documents
+}
+
+// RestoreResult reports a completed restore
+type RestoreResult struct {
+	Manifest  BackupManifest `json:"manifest"`
+	Documents int            `json:"documents"` // Vector store documents restored
+}
@@ -10,9 +10,20 @@ const (
 	ProviderTypeClaude ProviderType = "claude"
 	ProviderTypeAzure  ProviderType = "azure"
 	ProviderTypeOllama ProviderType = "ollama"
+	ProviderTypeGemini ProviderType = "gemini"
 	ProviderTypeCustom ProviderType = "custom"
 )
 
+// RedactionMode controls whether personal information is redacted from prompts sent to
+// a provider
+type RedactionMode string
+
+const (
+	RedactionAuto RedactionMode = ""    // Follow redaction.enabled, except for local providers
+	RedactionOn   RedactionMode = "on"  // Always redact
+	RedactionOff  RedactionMode = "off" // Never redact
+)
+
 // ConfigType represents the purpose of an LLM configuration
 type ConfigType string
 
@@ -24,14 +35,49 @@ const (
 
 // Provider represents an LLM service provider
 type Provider struct {
-	ID        string       `json:"id" gorm:"primaryKey"`
-	Name      string       `json:"name" gorm:"not null"`
-	Type      ProviderType `json:"type" gorm:"not null"`
-	APIKey    string       `json:"-" gorm:"column:api_key"` // Encrypted, hidden from JSON
-	BaseURL   string       `json:"base_url"`
-	Enabled   bool         `json:"enabled" gorm:"default:true"`
-	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
+	ID       string       `json:"id" gorm:"primaryKey"`
+	Name     string       `json:"name" gorm:"not null"`
+	Type     ProviderType `json:"type" gorm:"not null"`
+	APIKey   string       `json:"-" gorm:"column:api_key"` // Encrypted, hidden from JSON
+	BaseURL  string       `json:"base_url"`
+	ProxyURL string       `json:"proxy_url"` // Optional: overrides the global http.proxy_url
+	Enabled  bool         `json:"enabled" gorm:"default:true"`
+	UserID   string       `json:"user_id" gorm:"index"` // Owner, empty for a provider shared with all users
+	Limits   UsageLimits  `json:"limits" gorm:"embedded;embeddedPrefix:limit_"`
+
+	Redaction RedactionMode `json:"redaction"` // PII redaction of prompts: "" (auto), on or off
+
+	Options ProviderOptions `json:"options" gorm:"serializer:json"` // Used by custom providers
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Auth schemes for custom providers
+const (
+	AuthSchemeBearer = "bearer" // Authorization: Bearer <key> (default)
+	AuthSchemeHeader = "header" // <auth_header>: <key>
+	AuthSchemeNone   = "none"   // No credentials, e.g. a local server
+)
+
+// ProviderOptions configures a custom OpenAI-compatible provider (vLLM, LM Studio, DeepSeek, ...)
+type ProviderOptions struct {
+	Headers        map[string]string    `json:"headers,omitempty"`         // Extra headers sent with every request
+	AuthScheme     string               `json:"auth_scheme,omitempty"`     // bearer, header or none
+	AuthHeader     string               `json:"auth_header,omitempty"`     // Header name for the "header" scheme, e.g. "api-key"
+	ChatPath       string               `json:"chat_path,omitempty"`       // Default: /chat/completions
+	EmbeddingsPath string               `json:"embeddings_path,omitempty"` // Default: /embeddings
+	ModelsPath     string               `json:"models_path,omitempty"`     // Default: /models, next to a custom chat_path ending in /chat/completions
+	EmbeddingModel string               `json:"embedding_model,omitempty"` // Default: the model config's model
+	Capabilities   ProviderCapabilities `json:"capabilities"`
+}
+
+// ProviderCapabilities declares which optional OpenAI features an endpoint supports
+type ProviderCapabilities struct {
+	StreamUsage bool `json:"stream_usage"` // Accepts stream_options.include_usage
+	Embeddings  bool `json:"embeddings"`   // Serves the embeddings endpoint
+	Tools       bool `json:"tools"`        // Accepts tool/function definitions, used for structured output
+	Reasoning   bool `json:"reasoning"`    // Accepts reasoning_effort
 }
 
 // ModelConfig represents a model configuration associated with a provider
@@ -40,43 +86,145 @@ type ModelConfig struct {
 	ProviderID  string     `json:"provider_id" gorm:"not null;index"`
 	Model       string     `json:"model" gorm:"not null"`
 	MaxTokens   int        `json:"max_tokens" gorm:"default:4096"`
-	Temperature float64    `json:"temperature" gorm:"default:0.7"`
+	Temperature float64    `json:"temperature"` // 0 is honored for deterministic output
 	ConfigType  ConfigType `json:"config_type" gorm:"default:chat"`
 	IsDefault   bool       `json:"is_default" gorm:"default:false"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Pricing per 1M tokens, used for cost accounting (0 = free / unknown)
+	PromptPrice     float64 `json:"prompt_price"`
+	CompletionPrice float64 `json:"completion_price"`
+	Currency        string  `json:"currency" gorm:"default:USD"`
+
+	Limits UsageLimits `json:"limits" gorm:"embedded;embeddedPrefix:limit_"`
+
+	// Ordered model config IDs to fail over to when this config errors (network, 429, 5xx)
+	FallbackIDs []string `json:"fallback_ids" gorm:"serializer:json"`
+
+	// Sampling parameters besides temperature, overridable per chat request
+	Sampling SamplingParams `json:"sampling" gorm:"serializer:json"`
+
+	// User overrides for the capabilities known from the built-in model registry
+	Capabilities CapabilityOverrides `json:"capabilities" gorm:"serializer:json"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 
 	Provider *Provider `json:"provider,omitempty" gorm:"foreignKey:ProviderID"`
 }
 
+// Cost returns the cost of the given usage according to the config's price table
+func (m *ModelConfig) Cost(usage *Usage) float64 {
+	if usage == nil {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*m.PromptPrice + float64(usage.CompletionTokens)*m.CompletionPrice) / 1_000_000
+}
+
+// EffectiveSampling returns the config's sampling parameters with its temperature
+func (m *ModelConfig) EffectiveSampling() SamplingParams {
+	temperature := m.Temperature
+	return SamplingParams{Temperature: &temperature}.Merge(m.Sampling)
+}
+
+// ModelCapabilities describes what a model supports
+type ModelCapabilities struct {
+	ContextWindow      int  `json:"context_window"`      // Input + output tokens, 0 = unknown
+	StreamUsage        bool `json:"stream_usage"`        // Reports token usage when streaming
+	Vision             bool `json:"vision"`              // Accepts image input
+	Tools              bool `json:"tools"`               // Supports tool/function calling
+	JSONMode           bool `json:"json_mode"`           // Supports a JSON-only response format
+	Reasoning          bool `json:"reasoning"`           // Accepts reasoning_effort (OpenAI-compatible APIs)
+	EmbeddingDimension int  `json:"embedding_dimension"` // Embedding models only, 0 = unknown
+}
+
+// CapabilityOverrides overrides individual capabilities, nil fields keep the registry value
+type CapabilityOverrides struct {
+	ContextWindow      *int  `json:"context_window,omitempty"`
+	StreamUsage        *bool `json:"stream_usage,omitempty"`
+	Vision             *bool `json:"vision,omitempty"`
+	Tools              *bool `json:"tools,omitempty"`
+	JSONMode           *bool `json:"json_mode,omitempty"`
+	Reasoning          *bool `json:"reasoning,omitempty"`
+	EmbeddingDimension *int  `json:"embedding_dimension,omitempty"`
+}
+
+// Apply returns caps with the overrides applied
+func (o CapabilityOverrides) Apply(caps ModelCapabilities) ModelCapabilities {
+	if o.ContextWindow != nil {
+		caps.ContextWindow = *o.ContextWindow
+	}
+	if o.StreamUsage != nil {
+		caps.StreamUsage = *o.StreamUsage
+	}
+	if o.Vision != nil {
+		caps.Vision = *o.Vision
+	}
+	if o.Tools != nil {
+		caps.Tools = *o.Tools
+	}
+	if o.JSONMode != nil {
+		caps.JSONMode = *o.JSONMode
+	}
+	if o.Reasoning != nil {
+		caps.Reasoning = *o.Reasoning
+	}
+	if o.EmbeddingDimension != nil {
+		caps.EmbeddingDimension = *o.EmbeddingDimension
+	}
+	return caps
+}
+
+// ModelInfo describes a model offered by a provider
+type ModelInfo struct {
+	ID            string `json:"id"`
+	DisplayName   string `json:"display_name,omitempty"`
+	ContextWindow int    `json:"context_window,omitempty"` // 0 = unknown
+	Chat          bool   `json:"chat"`
+	Embedding     bool   `json:"embedding"`
+}
+
 // CreateProviderRequest represents the request to create a new provider
 type CreateProviderRequest struct {
-	Name    string       `json:"name" binding:"required"`
-	Type    ProviderType `json:"type" binding:"required"`
-	APIKey  string       `json:"api_key" binding:"required"`
-	BaseURL string       `json:"base_url"`
-	Enabled *bool        `json:"enabled"`
+	Name      string          `json:"name" binding:"required"`
+	Type      ProviderType    `json:"type" binding:"required"`
+	APIKey    string          `json:"api_key"` // Not needed for Ollama and custom providers with auth_scheme none
+	BaseURL   string          `json:"base_url"`
+	ProxyURL  string          `json:"proxy_url"`
+	Enabled   *bool           `json:"enabled"`
+	Limits    UsageLimits     `json:"limits"`
+	Options   ProviderOptions `json:"options"`
+	Redaction RedactionMode   `json:"redaction"`
+	Shared    bool            `json:"shared"` // Admins only: usable by all users instead of private
 }
 
 // UpdateProviderRequest represents the request to update a provider
 type UpdateProviderRequest struct {
-	Name    string       `json:"name"`
-	Type    ProviderType `json:"type"`
-	APIKey  string       `json:"api_key"`
-	BaseURL string       `json:"base_url"`
-	Enabled *bool        `json:"enabled"`
+	Name      string           `json:"name"`
+	Type      ProviderType     `json:"type"`
+	APIKey    string           `json:"api_key"`
+	BaseURL   string           `json:"base_url"`
+	ProxyURL  *string          `json:"proxy_url"` // "" clears the override
+	Enabled   *bool            `json:"enabled"`
+	Limits    *UsageLimits     `json:"limits"`
+	Options   *ProviderOptions `json:"options"`
+	Redaction *RedactionMode   `json:"redaction"`
 }
 
 // ProviderResponse represents the response for a provider (without sensitive data)
 type ProviderResponse struct {
-	ID        string       `json:"id"`
-	Name      string       `json:"name"`
-	Type      ProviderType `json:"type"`
-	BaseURL   string       `json:"base_url"`
-	Enabled   bool         `json:"enabled"`
-	HasAPIKey bool         `json:"has_api_key"`
-	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
+	ID        string                `json:"id"`
+	Name      string                `json:"name"`
+	Type      ProviderType          `json:"type"`
+	BaseURL   string                `json:"base_url"`
+	ProxyURL  string                `json:"proxy_url"`
+	Enabled   bool                  `json:"enabled"`
+	HasAPIKey bool                  `json:"has_api_key"`
+	Shared    bool                  `json:"shared"`
+	Limits    UsageLimits           `json:"limits"`
+	Options   ProviderOptions       `json:"options"`
+	Redaction RedactionMode         `json:"redaction"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
 	Models    []ModelConfigResponse `json:"models,omitempty"`
 }
 
@@ -87,8 +235,13 @@ func (p *Provider) ToResponse() ProviderResponse {
 		Name:      p.Name,
 		Type:      p.Type,
 		BaseURL:   p.BaseURL,
+		ProxyURL:  p.ProxyURL,
 		Enabled:   p.Enabled,
 		HasAPIKey: p.APIKey != "",
+		Shared:    p.UserID == "",
+		Limits:    p.Limits,
+		Options:   p.Options,
+		Redaction: p.Redaction,
 		CreatedAt: p.CreatedAt,
 		UpdatedAt: p.UpdatedAt,
 	}
@@ -96,49 +249,79 @@ func (p *Provider) ToResponse() ProviderResponse {
 
 // CreateModelConfigRequest represents the request to create a new model config
 type CreateModelConfigRequest struct {
-	ProviderID  string     `json:"provider_id" binding:"required"`
-	Model       string     `json:"model" binding:"required"`
-	MaxTokens   int        `json:"max_tokens"`
-	Temperature float64    `json:"temperature"`
-	ConfigType  ConfigType `json:"config_type"`
-	IsDefault   bool       `json:"is_default"`
+	ProviderID      string              `json:"provider_id" binding:"required"`
+	Model           string              `json:"model" binding:"required"`
+	MaxTokens       int                 `json:"max_tokens"`
+	Temperature     *float64            `json:"temperature"` // Default: 0.7
+	Sampling        SamplingParams      `json:"sampling"`
+	ConfigType      ConfigType          `json:"config_type"`
+	IsDefault       bool                `json:"is_default"`
+	PromptPrice     float64             `json:"prompt_price"`
+	CompletionPrice float64             `json:"completion_price"`
+	Currency        string              `json:"currency"`
+	Limits          UsageLimits         `json:"limits"`
+	FallbackIDs     []string            `json:"fallback_ids"`
+	Capabilities    CapabilityOverrides `json:"capabilities"`
 }
 
 // UpdateModelConfigRequest represents the request to update a model config
 type UpdateModelConfigRequest struct {
-	Model       string     `json:"model"`
-	MaxTokens   *int       `json:"max_tokens"`
-	Temperature *float64   `json:"temperature"`
-	ConfigType  ConfigType `json:"config_type"`
-	IsDefault   *bool      `json:"is_default"`
+	Model           string               `json:"model"`
+	MaxTokens       *int                 `json:"max_tokens"`
+	Temperature     *float64             `json:"temperature"`
+	Sampling        *SamplingParams      `json:"sampling"`
+	ConfigType      ConfigType           `json:"config_type"`
+	IsDefault       *bool                `json:"is_default"`
+	PromptPrice     *float64             `json:"prompt_price"`
+	CompletionPrice *float64             `json:"completion_price"`
+	Currency        string               `json:"currency"`
+	Limits          *UsageLimits         `json:"limits"`
+	FallbackIDs     []string             `json:"fallback_ids"` // nil = unchanged, [] = clear
+	Capabilities    *CapabilityOverrides `json:"capabilities"`
 }
 
 // ModelConfigResponse represents the response for a model config
 type ModelConfigResponse struct {
-	ID          string            `json:"id"`
-	ProviderID  string            `json:"provider_id"`
-	Model       string            `json:"model"`
-	MaxTokens   int               `json:"max_tokens"`
-	Temperature float64           `json:"temperature"`
-	ConfigType  ConfigType        `json:"config_type"`
-	IsDefault   bool              `json:"is_default"`
-	CreatedAt   time.Time         `json:"created_at"`
-	UpdatedAt   time.Time         `json:"updated_at"`
-	Provider    *ProviderResponse `json:"provider,omitempty"`
+	ID              string              `json:"id"`
+	ProviderID      string              `json:"provider_id"`
+	Model           string              `json:"model"`
+	MaxTokens       int                 `json:"max_tokens"`
+	Temperature     float64             `json:"temperature"`
+	Sampling        SamplingParams      `json:"sampling"`
+	ConfigType      ConfigType          `json:"config_type"`
+	IsDefault       bool                `json:"is_default"`
+	PromptPrice     float64             `json:"prompt_price"`
+	CompletionPrice float64             `json:"completion_price"`
+	Currency        string              `json:"currency"`
+	Limits          UsageLimits         `json:"limits"`
+	FallbackIDs     []string            `json:"fallback_ids"`
+	Capabilities    CapabilityOverrides `json:"capabilities"`
+	// Registry defaults with the overrides applied, filled in by the service layer
+	EffectiveCapabilities *ModelCapabilities `json:"effective_capabilities,omitempty"`
+	CreatedAt             time.Time          `json:"created_at"`
+	UpdatedAt             time.Time          `json:"updated_at"`
+	Provider              *ProviderResponse  `json:"provider,omitempty"`
 }
 
 // ToResponse converts ModelConfig to ModelConfigResponse
 func (m *ModelConfig) ToResponse() ModelConfigResponse {
 	resp := ModelConfigResponse{
-		ID:          m.ID,
-		ProviderID:  m.ProviderID,
-		Model:       m.Model,
-		MaxTokens:   m.MaxTokens,
-		Temperature: m.Temperature,
-		ConfigType:  m.ConfigType,
-		IsDefault:   m.IsDefault,
-		CreatedAt:   m.CreatedAt,
-		UpdatedAt:   m.UpdatedAt,
+		ID:              m.ID,
+		ProviderID:      m.ProviderID,
+		Model:           m.Model,
+		MaxTokens:       m.MaxTokens,
+		Temperature:     m.Temperature,
+		Sampling:        m.Sampling,
+		ConfigType:      m.ConfigType,
+		IsDefault:       m.IsDefault,
+		PromptPrice:     m.PromptPrice,
+		CompletionPrice: m.CompletionPrice,
+		Currency:        m.Currency,
+		Limits:          m.Limits,
+		FallbackIDs:     m.FallbackIDs,
+		Capabilities:    m.Capabilities,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
 	}
 	if m.Provider != nil {
 		providerResp := m.Provider.ToResponse()
@@ -152,9 +335,10 @@ type LLMProvider = ProviderType
 
 // Provider type aliases for convenience
 const (
-	ProviderOpenAI  = ProviderTypeOpenAI
-	ProviderClaude  = ProviderTypeClaude
-	ProviderAzure   = ProviderTypeAzure
-	ProviderOllama  = ProviderTypeOllama
-	ProviderCustom  = ProviderTypeCustom
+	ProviderOpenAI = ProviderTypeOpenAI
+	ProviderClaude = ProviderTypeClaude
+	ProviderAzure  = ProviderTypeAzure
+	ProviderOllama = ProviderTypeOllama
+	ProviderGemini = ProviderTypeGemini
+	ProviderCustom = ProviderTypeCustom
 )
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// HistoryImportResult reports the import of another assistant's chat history export
+type HistoryImportResult struct {
+	Source        string         `json:"source"`        // Assistant of the export: chatgpt or claude
+	Conversations int            `json:"conversations"` // Conversations in the export
+	Imported      int            `json:"imported"`      // Sessions created
+	Skipped       int            `json:"skipped"`       // Conversations imported before or without messages
+	Messages      int            `json:"messages"`      // Messages created
+	Errors        []string       `json:"errors,omitempty"`
+	Job           *ExtractionJob `json:"job,omitempty"` // Knowledge extraction started for the imported turns
+}
+
+// ExtractionJobStatus is the state of a knowledge extraction job
+type ExtractionJobStatus string
+
+const (
+	ExtractionRunning   ExtractionJobStatus = "running"
+	ExtractionCompleted ExtractionJobStatus = "completed"
+	ExtractionFailed    ExtractionJobStatus = "failed"   // Stopped early, see Error
+	ExtractionCanceled  ExtractionJobStatus = "canceled" // Canceled by the user
+)
+
+// ExtractionJob extracts knowledge from imported conversation turns in the background
+type ExtractionJob struct {
+	ID          string              `json:"id"`
+	UserID      string              `json:"-"`
+	WorkspaceID string              `json:"workspace_id"`
+	Status      ExtractionJobStatus `json:"status"`
+	Turns       int                 `json:"turns"`     // Turns to process
+	Processed   int                 `json:"processed"` // Turns processed so far
+	Failed      int                 `json:"failed"`    // Turns whose extraction failed
+	Error       string              `json:"error,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	FinishedAt  *time.Time          `json:"finished_at,omitempty"`
+}
@@ -29,17 +29,20 @@ const (
 )
 
 // Knowledge represents extracted user knowledge (long-term memory)
-// Unlike conversation messages, knowledge is global and not tied to a specific session
+// Unlike conversation messages, knowledge is not tied to a specific session. It belongs
+// to a workspace, or to the user's global pool when WorkspaceID is empty.
 type Knowledge struct {
-	ID           string         `json:"id" gorm:"primaryKey"`
-	Content      string         `json:"content" gorm:"not null"`
-	SupersededBy string         `json:"superseded_by" gorm:"index"` // 被哪条知识取代 (空=有效)
-	Tier         KnowledgeTier  `json:"tier" gorm:"default:long"`   // 记忆层级
-	HitCount     int            `json:"hit_count" gorm:"default:0"` // 命中次数（用于中期记忆提升）
-	LastHitAt    *time.Time     `json:"last_hit_at"`                // 最后命中时间
-	PromotedAt   *time.Time     `json:"promoted_at"`                // 从中期提升为长期的时间
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
+	ID           string        `json:"id" gorm:"primaryKey"`
+	Content      string        `json:"content" gorm:"not null;serializer:encrypted"` // Encrypted with encryption.at_rest
+	UserID       string        `json:"-" gorm:"index"`                               // Owner
+	WorkspaceID  string        `json:"workspace_id" gorm:"index"`
+	SupersededBy string        `json:"superseded_by" gorm:"index"` // 被哪条知识取代 (空=有效)
+	Tier         KnowledgeTier `json:"tier" gorm:"default:long"`   // 记忆层级
+	HitCount     int           `json:"hit_count" gorm:"default:0"` // 命中次数（用于中期记忆提升）
+	LastHitAt    *time.Time    `json:"last_hit_at"`                // 最后命中时间
+	PromotedAt   *time.Time    `json:"promoted_at"`                // 从中期提升为长期的时间
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
 }
 
 // IsActive returns true if the knowledge has not been superseded
@@ -56,3 +59,31 @@ func (k *Knowledge) IsMidTerm() bool {
 func (k *Knowledge) IsLongTerm() bool {
 	return k.Tier == TierLongTerm || k.Tier == "" // default is long-term
 }
+
+// KnowledgeRecord is a knowledge entry in portable form, as exported and imported. The
+// category, source and importance are kept in the vector store next to the embedding.
+type KnowledgeRecord struct {
+	ID           string            `json:"id"`
+	Content      string            `json:"content"`
+	WorkspaceID  string            `json:"workspace_id,omitempty"`
+	SupersededBy string            `json:"superseded_by,omitempty"` // ID of the replacing record
+	Tier         KnowledgeTier     `json:"tier,omitempty"`
+	HitCount     int               `json:"hit_count,omitempty"`
+	LastHitAt    *time.Time        `json:"last_hit_at,omitempty"`
+	PromotedAt   *time.Time        `json:"promoted_at,omitempty"`
+	Category     KnowledgeCategory `json:"category,omitempty"`
+	Source       KnowledgeSource   `json:"source,omitempty"`
+	Importance   float32           `json:"importance,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// KnowledgeImportResult reports the outcome of a knowledge import
+type KnowledgeImportResult struct {
+	Total      int      `json:"total"`      // Records in the file
+	Created    int      `json:"created"`    // New entries, including superseded history
+	Superseded int      `json:"superseded"` // Existing entries replaced by a conflicting record
+	Skipped    int      `json:"skipped"`    // Duplicates of existing knowledge
+	Errors     []string `json:"errors,omitempty"`
+	Stopped    bool     `json:"stopped,omitempty"` // Canceled or a usage or rate limit ended the import early
+}
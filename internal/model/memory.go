@@ -15,22 +15,47 @@ const (
 type Memory struct {
 	ID        string      `json:"id" gorm:"primaryKey"`
 	SessionID string      `json:"session_id" gorm:"index;not null"`
+	UserID    string      `json:"-" gorm:"index"`                   // Owner of the session
+	ParentID  string      `json:"parent_id,omitempty" gorm:"index"` // Previous message in the conversation tree, empty for the first
 	Role      MessageRole `json:"role" gorm:"not null"`
-	Content   string      `json:"content" gorm:"not null"`
+	Content   string      `json:"content" gorm:"not null;serializer:encrypted"` // Encrypted with encryption.at_rest
 	CreatedAt time.Time   `json:"created_at"`
+
+	// Thinking emitted by reasoning models (assistant only). Kept for display,
+	// never sent back to the model or used for knowledge extraction.
+	Reasoning string `json:"reasoning,omitempty" gorm:"serializer:encrypted"`
+
+	// Token usage of the LLM call that produced this message (assistant only)
+	ConfigID         string `json:"config_id,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
 }
 
+// RootMessageID can be passed as ChatRequest.ParentID to start a new branch at the
+// beginning of the conversation
+const RootMessageID = "root"
+
 // Message represents a chat message (used for API requests/responses)
 type Message struct {
 	Role    MessageRole `json:"role"`
 	Content string      `json:"content"`
+
+	// Reasoning holds the model's thinking, separate from the answer.
+	// Adapters ignore it when building provider requests.
+	Reasoning string `json:"reasoning,omitempty"`
 }
 
 // MessageWithID represents a chat message with its ID (used for API responses)
 type MessageWithID struct {
-	ID      string      `json:"id"`
-	Role    MessageRole `json:"role"`
-	Content string      `json:"content"`
+	ID        string      `json:"id"`
+	ParentID  string      `json:"parent_id,omitempty"`
+	Role      MessageRole `json:"role"`
+	Content   string      `json:"content"`
+	Reasoning string      `json:"reasoning,omitempty"`
+	Usage     *Usage      `json:"usage,omitempty"`
+
+	// Alternative versions of this message (same parent), oldest first, including itself
+	SiblingIDs []string `json:"sibling_ids,omitempty"`
 }
 
 // ChatRequest represents a chat completion request
@@ -39,14 +64,39 @@ type ChatRequest struct {
 	ConfigID  string    `json:"config_id"`  // Optional: use specific config
 	Messages  []Message `json:"messages"`   // Current conversation messages
 	Stream    bool      `json:"stream"`     // Enable streaming response
+
+	// Optional: workspace of a new session, ignored when continuing an existing session
+	WorkspaceID string `json:"workspace_id,omitempty"`
+
+	// Optional: message to continue from, defaults to the session's active branch.
+	// With no Messages, a new reply to this (user) message is generated instead.
+	ParentID string `json:"parent_id,omitempty"`
+
+	// Optional: overrides the model config's sampling parameters for this request
+	Sampling *SamplingParams `json:"sampling,omitempty"`
 }
 
 // ChatResponse represents a chat completion response
 type ChatResponse struct {
 	ID        string  `json:"id"`
 	SessionID string  `json:"session_id"`
+	MessageID string  `json:"message_id,omitempty"` // Stored assistant message
 	Message   Message `json:"message"`
 	Usage     *Usage  `json:"usage,omitempty"`
+
+	// Model config that actually served the response (may be a fallback)
+	ConfigID string       `json:"config_id,omitempty"`
+	Model    string       `json:"model,omitempty"`
+	Provider ProviderType `json:"provider,omitempty"`
+}
+
+// ChatStreamInfo describes the session and model config serving a streaming response
+type ChatStreamInfo struct {
+	SessionID string
+	ParentID  string // Message the streamed reply is attached to
+	ConfigID  string
+	Model     string
+	Provider  ProviderType
 }
 
 // Usage represents token usage information
@@ -62,6 +112,14 @@ type StreamChunk struct {
 	Delta string `json:"delta"`
 	Done  bool   `json:"done"`
 	Usage *Usage `json:"usage,omitempty"`
+
+	// Reasoning carries a thinking delta; it is sent to clients as a separate event
+	Reasoning string `json:"reasoning,omitempty"`
+
+	// Error is set on the final chunk when the provider stopped the answer, e.g. a
+	// safety block; the partial answer is not saved
+	Error     string `json:"error,omitempty"`
+	ErrorKind string `json:"error_kind,omitempty"` // adapter.ErrorKind, e.g. content_filter
 }
 
 // KnowledgeSearchRequest represents a request to search knowledge
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Persona is a reusable system prompt and sampling profile that sessions can use
+type Persona struct {
+	ID           string         `json:"id" gorm:"primaryKey"`
+	UserID       string         `json:"-" gorm:"index"` // Owner, empty for a persona shared with all users
+	Name         string         `json:"name" gorm:"not null"`
+	Description  string         `json:"description"`
+	SystemPrompt string         `json:"system_prompt"`
+	Sampling     SamplingParams `json:"sampling" gorm:"serializer:json"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+
+	Shared bool `json:"shared" gorm:"-"` // Set from UserID for responses
+}
+
+// CreatePersonaRequest represents the request to create a persona
+type CreatePersonaRequest struct {
+	Name         string         `json:"name" binding:"required"`
+	Description  string         `json:"description"`
+	SystemPrompt string         `json:"system_prompt"`
+	Sampling     SamplingParams `json:"sampling"`
+	Shared       bool           `json:"shared"` // Admins only: usable by all users instead of private
+}
+
+// UpdatePersonaRequest represents the request to update a persona
+type UpdatePersonaRequest struct {
+	Name         string          `json:"name"`
+	Description  *string         `json:"description"`
+	SystemPrompt *string         `json:"system_prompt"`
+	Sampling     *SamplingParams `json:"sampling"`
+}
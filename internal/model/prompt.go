@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// PromptKind distinguishes prompts used by the system from user-facing slash prompts
+type PromptKind string
+
+const (
+	PromptKindBuiltin PromptKind = "builtin" // Used internally, e.g. fact extraction and summaries
+	PromptKindSlash   PromptKind = "slash"   // Inserted by the user with /name in the chat input
+)
+
+// PromptTemplate is one version of a Go text/template prompt in one language.
+// Every edit creates a new version; the active version of a name and language is used.
+type PromptTemplate struct {
+	ID          string     `json:"id" gorm:"primaryKey"`
+	Name        string     `json:"name" gorm:"not null;uniqueIndex:idx_prompt_version"`
+	Language    string     `json:"language" gorm:"not null;uniqueIndex:idx_prompt_version"`
+	Version     int        `json:"version" gorm:"not null;uniqueIndex:idx_prompt_version"`
+	Kind        PromptKind `json:"kind" gorm:"not null;index"`
+	Description string     `json:"description"`
+	Content     string     `json:"content" gorm:"type:text;not null"`
+	Active      bool       `json:"active" gorm:"index"`
+	Seeded      bool       `json:"seeded"` // Stored from the compiled-in default of a built-in prompt
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreatePromptRequest represents the request to create a slash prompt, or a new
+// language variant of a prompt
+type CreatePromptRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Language    string `json:"language" binding:"required"`
+	Description string `json:"description"`
+	Content     string `json:"content" binding:"required"`
+}
+
+// UpdatePromptRequest represents the request to save a new version of a prompt
+type UpdatePromptRequest struct {
+	Description *string `json:"description"`
+	Content     string  `json:"content" binding:"required"`
+}
+
+// RenderPromptRequest represents the request to fill in a prompt's variables
+type RenderPromptRequest struct {
+	Variables map[string]any `json:"variables"`
+}
+
+// RenderPromptResponse represents a rendered prompt
+type RenderPromptResponse struct {
+	Content string `json:"content"`
+}
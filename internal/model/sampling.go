@@ -0,0 +1,102 @@
+package model
+
+import "fmt"
+
+// Reasoning effort levels
+const (
+	ReasoningEffortLow    = "low"
+	ReasoningEffortMedium = "medium"
+	ReasoningEffortHigh   = "high"
+)
+
+// SamplingParams controls how a model generates its output.
+// Nil fields are left to the provider's default.
+type SamplingParams struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	TopK             *int     `json:"top_k,omitempty"` // Claude, Gemini and custom providers only
+	Stop             []string `json:"stop,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	Seed             *int64   `json:"seed,omitempty"`
+	ReasoningEffort  string   `json:"reasoning_effort,omitempty"` // low, medium, high
+	ThinkingBudget   *int     `json:"thinking_budget,omitempty"`  // Tokens for extended thinking, 0 = disabled
+}
+
+// Merge returns p with the fields set in override taking precedence
+func (p SamplingParams) Merge(override SamplingParams) SamplingParams {
+	if override.Temperature != nil {
+		p.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		p.TopP = override.TopP
+	}
+	if override.TopK != nil {
+		p.TopK = override.TopK
+	}
+	if override.Stop != nil {
+		p.Stop = override.Stop
+	}
+	if override.PresencePenalty != nil {
+		p.PresencePenalty = override.PresencePenalty
+	}
+	if override.FrequencyPenalty != nil {
+		p.FrequencyPenalty = override.FrequencyPenalty
+	}
+	if override.Seed != nil {
+		p.Seed = override.Seed
+	}
+	if override.ReasoningEffort != "" {
+		p.ReasoningEffort = override.ReasoningEffort
+	}
+	if override.ThinkingBudget != nil {
+		p.ThinkingBudget = override.ThinkingBudget
+	}
+	return p
+}
+
+// ThinkingTokens returns the extended thinking budget for providers that take a token
+// count (Claude, Gemini): the explicit budget, or one derived from the reasoning effort.
+// Returns 0 when thinking is not requested.
+func (p SamplingParams) ThinkingTokens() int {
+	if p.ThinkingBudget != nil {
+		return *p.ThinkingBudget
+	}
+	switch p.ReasoningEffort {
+	case ReasoningEffortLow:
+		return 1024
+	case ReasoningEffortMedium:
+		return 8192
+	case ReasoningEffortHigh:
+		return 24576
+	}
+	return 0
+}
+
+// Validate checks the parameters are within the ranges accepted by providers
+func (p SamplingParams) Validate() error {
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if p.TopP != nil && (*p.TopP < 0 || *p.TopP > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1")
+	}
+	if p.TopK != nil && *p.TopK < 0 {
+		return fmt.Errorf("top_k must not be negative")
+	}
+	if p.PresencePenalty != nil && (*p.PresencePenalty < -2 || *p.PresencePenalty > 2) {
+		return fmt.Errorf("presence_penalty must be between -2 and 2")
+	}
+	if p.FrequencyPenalty != nil && (*p.FrequencyPenalty < -2 || *p.FrequencyPenalty > 2) {
+		return fmt.Errorf("frequency_penalty must be between -2 and 2")
+	}
+	if p.ThinkingBudget != nil && *p.ThinkingBudget < 0 {
+		return fmt.Errorf("thinking_budget must not be negative")
+	}
+	switch p.ReasoningEffort {
+	case "", ReasoningEffortLow, ReasoningEffortMedium, ReasoningEffortHigh:
+	default:
+		return fmt.Errorf("reasoning_effort must be one of low, medium, high")
+	}
+	return nil
+}
@@ -5,17 +5,53 @@ import "time"
 // Session represents a chat session
 type Session struct {
 	ID        string    `json:"id" gorm:"primaryKey"`
+	UserID    string    `json:"-" gorm:"index"` // Owner
 	Title     string    `json:"title"`
-	ConfigID  string    `json:"config_id" gorm:"index"` // LLM config used
-	Summary   string    `json:"summary"`                // Session summary for long-term memory
+	ConfigID  string    `json:"config_id" gorm:"index"`              // LLM config used
+	Summary   string    `json:"summary" gorm:"serializer:encrypted"` // Session summary for long-term memory, encrypted with encryption.at_rest
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Last message of the branch currently shown and continued
+	ActiveLeafID string `json:"active_leaf_id"`
+
+	// Workspace the session belongs to, empty for the global pool
+	WorkspaceID string `json:"workspace_id" gorm:"index"`
+
+	// Per-session settings applied on top of the model config: the persona's system prompt
+	// and sampling, then the session's own. OverrideConfigID pins the model for this session.
+	SystemPrompt     string         `json:"system_prompt"`
+	PersonaID        string         `json:"persona_id" gorm:"index"`
+	OverrideConfigID string         `json:"override_config_id"`
+	Sampling         SamplingParams `json:"sampling" gorm:"serializer:json"`
+
+	// Lineage of a forked session
+	ForkedFromID  string     `json:"forked_from_id,omitempty" gorm:"index"` // Session this one was forked from
+	ForkMessageID string     `json:"fork_message_id,omitempty"`             // Message of the origin session the fork starts after
+	MergedAt      *time.Time `json:"merged_at,omitempty"`                   // When the fork's summary was merged back
+
+	// Conversation of another assistant's export the session was imported from, e.g. "chatgpt:<id>"
+	ImportedFrom string `json:"imported_from,omitempty" gorm:"index"`
 }
 
 // CreateSessionRequest represents the request to create a new session
 type CreateSessionRequest struct {
-	Title    string `json:"title"`
-	ConfigID string `json:"config_id"`
+	Title            string          `json:"title"`
+	WorkspaceID      string          `json:"workspace_id"`
+	SystemPrompt     string          `json:"system_prompt"`
+	PersonaID        string          `json:"persona_id"`
+	OverrideConfigID string          `json:"override_config_id"`
+	Sampling         *SamplingParams `json:"sampling,omitempty"`
+}
+
+// UpdateSessionRequest represents the request to update a session's settings.
+// Empty strings clear the persona and model override.
+type UpdateSessionRequest struct {
+	Title            *string         `json:"title"`
+	SystemPrompt     *string         `json:"system_prompt"`
+	PersonaID        *string         `json:"persona_id"`
+	OverrideConfigID *string         `json:"override_config_id"`
+	Sampling         *SamplingParams `json:"sampling"`
 }
 
 // SessionResponse represents the response for a session
@@ -26,6 +62,47 @@ type SessionResponse struct {
 	Summary   string    `json:"summary,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	ActiveLeafID string `json:"active_leaf_id,omitempty"`
+	WorkspaceID  string `json:"workspace_id,omitempty"`
+
+	SystemPrompt     string         `json:"system_prompt,omitempty"`
+	PersonaID        string         `json:"persona_id,omitempty"`
+	OverrideConfigID string         `json:"override_config_id,omitempty"`
+	Sampling         SamplingParams `json:"sampling"`
+
+	ForkedFromID  string     `json:"forked_from_id,omitempty"`
+	ForkMessageID string     `json:"fork_message_id,omitempty"`
+	MergedAt      *time.Time `json:"merged_at,omitempty"`
+	ForkIDs       []string   `json:"fork_ids,omitempty"` // Sessions forked from this one
+
+	ImportedFrom string `json:"imported_from,omitempty"`
+}
+
+// ForkSessionRequest represents the request to fork a session
+type ForkSessionRequest struct {
+	MessageID string `json:"message_id"` // Last message to copy, defaults to the end of the active branch
+	Title     string `json:"title"`
+}
+
+// SwitchBranchRequest selects the branch containing a message
+type SwitchBranchRequest struct {
+	MessageID string `json:"message_id" binding:"required"`
+}
+
+// EditMessageRequest replaces a user message, starting a new branch
+type EditMessageRequest struct {
+	Content  string          `json:"content" binding:"required"`
+	ConfigID string          `json:"config_id"`
+	Stream   bool            `json:"stream"`
+	Sampling *SamplingParams `json:"sampling,omitempty"`
+}
+
+// RegenerateRequest generates another reply, starting a new branch
+type RegenerateRequest struct {
+	ConfigID string          `json:"config_id"`
+	Stream   bool            `json:"stream"`
+	Sampling *SamplingParams `json:"sampling,omitempty"`
 }
 
 // ToResponse converts Session to SessionResponse
@@ -37,5 +114,19 @@ func (s *Session) ToResponse() SessionResponse {
 		Summary:   s.Summary,
 		CreatedAt: s.CreatedAt,
 		UpdatedAt: s.UpdatedAt,
+
+		ActiveLeafID: s.ActiveLeafID,
+		WorkspaceID:  s.WorkspaceID,
+
+		SystemPrompt:     s.SystemPrompt,
+		PersonaID:        s.PersonaID,
+		OverrideConfigID: s.OverrideConfigID,
+		Sampling:         s.Sampling,
+
+		ForkedFromID:  s.ForkedFromID,
+		ForkMessageID: s.ForkMessageID,
+		MergedAt:      s.MergedAt,
+
+		ImportedFrom: s.ImportedFrom,
 	}
 }
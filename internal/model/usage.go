@@ -0,0 +1,82 @@
+package model
+
+import "time"
+
+// UsagePurpose represents why an LLM call was made
+type UsagePurpose string
+
+const (
+	UsagePurposeChat       UsagePurpose = "chat"       // Interactive chat reply
+	UsagePurposeExtraction UsagePurpose = "extraction" // Knowledge extraction (Processor.ExtractFacts)
+	UsagePurposeConflict   UsagePurpose = "conflict"   // Conflict detection (Processor.DetectConflict)
+	UsagePurposeSummarize  UsagePurpose = "summarize"  // Session summarization
+)
+
+// IsBackground reports whether the call runs in the background rather than on behalf of the user
+func (p UsagePurpose) IsBackground() bool {
+	return p == UsagePurposeExtraction || p == UsagePurposeConflict
+}
+
+// UsageLimits caps the usage of a provider or model config (0 = unlimited)
+type UsageLimits struct {
+	DailyTokens       int64   `json:"daily_tokens"`
+	MonthlyTokens     int64   `json:"monthly_tokens"`
+	DailyCost         float64 `json:"daily_cost"`   // In the currency of the model configs, which must all match for a provider
+	MonthlyCost       float64 `json:"monthly_cost"` // In the currency of the model configs, which must all match for a provider
+	RequestsPerMinute int     `json:"requests_per_minute"`
+}
+
+// HasBudget reports whether any token or cost budget is set
+func (l UsageLimits) HasBudget() bool {
+	return l.DailyTokens > 0 || l.MonthlyTokens > 0 || l.DailyCost > 0 || l.MonthlyCost > 0
+}
+
+// UsageTotals represents the accumulated usage within a budget period
+type UsageTotals struct {
+	Tokens int64
+	Cost   float64
+}
+
+// UsageRecord represents the token usage of a single LLM call
+type UsageRecord struct {
+	ID               string       `json:"id" gorm:"primaryKey"`
+	SessionID        string       `json:"session_id" gorm:"index"`
+	MemoryID         string       `json:"memory_id"` // Assistant message produced by this call (chat only)
+	ConfigID         string       `json:"config_id" gorm:"index"`
+	ProviderID       string       `json:"provider_id" gorm:"index"`
+	Model            string       `json:"model"`
+	Purpose          UsagePurpose `json:"purpose" gorm:"index"`
+	PromptTokens     int          `json:"prompt_tokens"`
+	CompletionTokens int          `json:"completion_tokens"`
+	Cost             float64      `json:"cost"`
+	Currency         string       `json:"currency"`
+	CreatedAt        time.Time    `json:"created_at" gorm:"index"`
+}
+
+// UsageGroupBy represents the dimension used to aggregate usage
+type UsageGroupBy string
+
+const (
+	UsageGroupByDay     UsageGroupBy = "day"
+	UsageGroupBySession UsageGroupBy = "session"
+	UsageGroupByModel   UsageGroupBy = "model"
+	UsageGroupByPurpose UsageGroupBy = "purpose"
+)
+
+// UsageQuery represents a usage aggregation query
+type UsageQuery struct {
+	GroupBy   UsageGroupBy
+	SessionID string     // Optional: restrict to a session
+	From      *time.Time // Optional: inclusive lower bound
+	To        *time.Time // Optional: exclusive upper bound
+}
+
+// UsageSummary represents aggregated usage for one group
+type UsageSummary struct {
+	Key              string  `json:"key"`
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
@@ -0,0 +1,73 @@
+package model
+
+import "time"
+
+// UserRole represents what a user may manage beyond their own data
+type UserRole string
+
+const (
+	UserRoleAdmin  UserRole = "admin"  // Manages users, shared providers, prompts and system settings
+	UserRoleMember UserRole = "member" // Manages only their own sessions, knowledge, workspaces and providers
+)
+
+// User represents an account of a shared deployment. Sessions, messages, knowledge,
+// workspaces and providers belong to the user who created them; rows with an empty
+// UserID predate accounts (or were created with authentication disabled).
+type User struct {
+	ID           string    `json:"id" gorm:"primaryKey"`
+	Username     string    `json:"username" gorm:"uniqueIndex;not null"`
+	PasswordHash string    `json:"-" gorm:"not null"` // bcrypt
+	Role         UserRole  `json:"role" gorm:"default:member"`
+	Disabled     bool      `json:"disabled"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// IsAdmin returns true if the user has the admin role
+func (u *User) IsAdmin() bool {
+	return u.Role == UserRoleAdmin
+}
+
+// AuthToken is a login session. Only the SHA-256 hash of the bearer token is stored.
+type AuthToken struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	UserID     string     `json:"user_id" gorm:"index;not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// LoginRequest represents the request to log in
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginResponse carries the bearer token of a new login session
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      User      `json:"user"`
+}
+
+// CreateUserRequest represents the request to create a user
+type CreateUserRequest struct {
+	Username string   `json:"username" binding:"required"`
+	Password string   `json:"password" binding:"required"`
+	Role     UserRole `json:"role"` // Defaults to member
+}
+
+// UpdateUserRequest represents the request to update a user. Changing the password or
+// disabling the user ends their login sessions.
+type UpdateUserRequest struct {
+	Password *string   `json:"password"`
+	Role     *UserRole `json:"role"`
+	Disabled *bool     `json:"disabled"`
+}
+
+// ChangePasswordRequest represents the request to change one's own password
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Workspace groups sessions and knowledge, e.g. to keep work and personal contexts apart.
+// Sessions and knowledge without a workspace form the global pool, which workspaces can
+// share by setting IncludeGlobal.
+type Workspace struct {
+	ID            string    `json:"id" gorm:"primaryKey"`
+	UserID        string    `json:"-" gorm:"index"` // Owner
+	Name          string    `json:"name" gorm:"not null"`
+	Description   string    `json:"description"`
+	IncludeGlobal bool      `json:"include_global"` // Also search the global knowledge pool
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CreateWorkspaceRequest represents the request to create a workspace
+type CreateWorkspaceRequest struct {
+	Name          string `json:"name" binding:"required"`
+	Description   string `json:"description"`
+	IncludeGlobal *bool  `json:"include_global"` // Defaults to true
+}
+
+// UpdateWorkspaceRequest represents the request to update a workspace
+type UpdateWorkspaceRequest struct {
+	Name          string  `json:"name"`
+	Description   *string `json:"description"`
+	IncludeGlobal *bool   `json:"include_global"`
+}
@@ -0,0 +1,131 @@
+package chatimport
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+)
+
+// chatGPTNode is a node of the message tree of a ChatGPT conversation
+type chatGPTNode struct {
+	ID       string          `json:"id"`
+	Message  *chatGPTMessage `json:"message"`
+	Parent   string          `json:"parent"`
+	Children []string        `json:"children"`
+}
+
+type chatGPTMessage struct {
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime *float64 `json:"create_time"`
+	Content    struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+	} `json:"content"`
+	Metadata struct {
+		Hidden bool `json:"is_visually_hidden_from_conversation"`
+	} `json:"metadata"`
+}
+
+// parseChatGPT converts a ChatGPT conversation. The message tree is kept, so edited
+// messages and regenerated replies become branches. System, tool and hidden messages
+// are left out and their children attached to the nearest message kept.
+func parseChatGPT(raw *rawConversation) Conversation {
+	conv := Conversation{
+		ID:        raw.ConversationID,
+		Title:     raw.Title,
+		CreatedAt: unixTime(raw.CreateTime),
+		UpdatedAt: unixTime(raw.UpdateTime),
+	}
+	if conv.ID == "" {
+		conv.ID = raw.ID
+	}
+
+	// Walk the tree from its roots, parents before children
+	var roots []string
+	for id, node := range raw.Mapping {
+		if _, ok := raw.Mapping[node.Parent]; !ok {
+			roots = append(roots, id)
+		}
+	}
+	sort.Strings(roots)
+
+	kept := make(map[string]string, len(raw.Mapping)) // Nearest kept message of each node
+	visited := make(map[string]bool, len(raw.Mapping))
+	last := conv.CreatedAt
+	var walk func(id, parent string)
+	walk = func(id, parent string) {
+		if visited[id] {
+			return
+		}
+		visited[id] = true
+		node, ok := raw.Mapping[id]
+		if !ok {
+			return
+		}
+
+		if msg, ok := chatGPTToMessage(id, node.Message); ok {
+			msg.ParentID = parent
+			if msg.CreatedAt.IsZero() {
+				msg.CreatedAt = last
+			}
+			last = msg.CreatedAt
+			conv.Messages = append(conv.Messages, msg)
+			parent = id
+		}
+		kept[id] = parent
+		for _, child := range node.Children {
+			walk(child, parent)
+		}
+	}
+	for _, root := range roots {
+		walk(root, "")
+	}
+
+	conv.CurrentID = kept[raw.CurrentNode]
+	return conv
+}
+
+// chatGPTToMessage converts the message of a node, reporting false for messages that
+// are not part of the visible conversation
+func chatGPTToMessage(id string, m *chatGPTMessage) (Message, bool) {
+	if m == nil || m.Metadata.Hidden {
+		return Message{}, false
+	}
+	var role model.MessageRole
+	switch m.Author.Role {
+	case "user":
+		role = model.RoleUser
+	case "assistant":
+		role = model.RoleAssistant
+	default:
+		return Message{}, false
+	}
+
+	// Other content types are tool calls and their output, or custom instructions
+	if m.Content.ContentType != "text" && m.Content.ContentType != "multimodal_text" {
+		return Message{}, false
+	}
+	// Parts are text or attachments such as images, which are left out
+	var texts []string
+	for _, part := range m.Content.Parts {
+		var text string
+		if json.Unmarshal(part, &text) == nil && text != "" {
+			texts = append(texts, text)
+		}
+	}
+	content := strings.TrimSpace(strings.Join(texts, "\n"))
+	if content == "" {
+		return Message{}, false
+	}
+
+	return Message{
+		ID:        id,
+		Role:      role,
+		Content:   content,
+		CreatedAt: unixTime(m.CreateTime),
+	}, true
+}
@@ -0,0 +1,158 @@
+// Package chatimport reads the conversations of chat history exports of other assistants:
+// conversations.json of ChatGPT and Claude data exports, or the export zip itself.
+package chatimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+)
+
+// Source is the assistant an export comes from
+type Source string
+
+const (
+	SourceChatGPT Source = "chatgpt"
+	SourceClaude  Source = "claude"
+)
+
+// conversationsFile is the name of the conversation list in a data export
+const conversationsFile = "conversations.json"
+
+// maxConversationsSize bounds the size of conversations.json, which may be much larger
+// than the zip it is compressed in
+const maxConversationsSize = 1 << 30
+
+// Conversation is an exported conversation
+type Conversation struct {
+	ID        string // ID in the source, used to recognize conversations imported before
+	Title     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Messages  []Message // Every message comes after its parent
+	CurrentID string    // Last message of the branch shown in the source, empty for the last message
+}
+
+// Message is a user or assistant message of a conversation
+type Message struct {
+	ID        string
+	ParentID  string // Previous message in the conversation tree, empty for the first
+	Role      model.MessageRole
+	Content   string
+	Reasoning string // Thinking of the assistant, if exported
+	CreatedAt time.Time
+}
+
+// rawConversation holds the fields of both export formats
+type rawConversation struct {
+	// ChatGPT
+	ID             string                 `json:"id"`
+	ConversationID string                 `json:"conversation_id"`
+	Title          string                 `json:"title"`
+	CreateTime     *float64               `json:"create_time"`
+	UpdateTime     *float64               `json:"update_time"`
+	Mapping        map[string]chatGPTNode `json:"mapping"`
+	CurrentNode    string                 `json:"current_node"`
+
+	// Claude
+	UUID         string          `json:"uuid"`
+	Name         string          `json:"name"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	ChatMessages []claudeMessage `json:"chat_messages"`
+}
+
+// Parse reads the conversations of an export, given as conversations.json or as the
+// export zip, and tells which assistant it comes from. The conversation list is decoded
+// one conversation at a time, so only the converted conversations are kept in memory.
+func Parse(r io.ReaderAt, size int64) (Source, []Conversation, error) {
+	content := &io.LimitedReader{R: io.NewSectionReader(r, 0, size), N: maxConversationsSize + 1}
+	magic := make([]byte, 4)
+	if n, _ := r.ReadAt(magic, 0); n == len(magic) && bytes.Equal(magic, []byte("PK\x03\x04")) {
+		rc, err := openFromZip(r, size)
+		if err != nil {
+			return "", nil, err
+		}
+		defer rc.Close()
+		content.R = rc
+	}
+
+	source, conversations, err := decode(json.NewDecoder(content))
+	if content.N <= 0 {
+		return "", nil, fmt.Errorf("%s is too large", conversationsFile)
+	}
+	return source, conversations, err
+}
+
+// decode reads a conversation list
+func decode(dec *json.Decoder) (Source, []Conversation, error) {
+	notList := func(err error) error {
+		return fmt.Errorf("%s is not a conversation list: %w", conversationsFile, err)
+	}
+	if tok, err := dec.Token(); err != nil {
+		return "", nil, notList(err)
+	} else if tok != json.Delim('[') {
+		return "", nil, notList(fmt.Errorf("unexpected %v", tok))
+	}
+
+	var source Source
+	var conversations []Conversation
+	for i := 0; dec.More(); i++ {
+		var raw rawConversation
+		if err := dec.Decode(&raw); err != nil {
+			return "", nil, notList(err)
+		}
+		var s Source
+		var conv Conversation
+		switch {
+		case raw.Mapping != nil:
+			s, conv = SourceChatGPT, parseChatGPT(&raw)
+		case raw.ChatMessages != nil || raw.UUID != "":
+			s, conv = SourceClaude, parseClaude(&raw)
+		default:
+			return "", nil, fmt.Errorf("conversation %d: unknown export format", i+1)
+		}
+		if source != "" && s != source {
+			return "", nil, fmt.Errorf("conversation %d: mixed export formats", i+1)
+		}
+		source = s
+		conversations = append(conversations, conv)
+	}
+	if _, err := dec.Token(); err != nil {
+		return "", nil, notList(err)
+	}
+	if source == "" {
+		return "", nil, errors.New("export contains no conversations")
+	}
+	return source, conversations, nil
+}
+
+// openFromZip opens conversations.json in an export zip
+func openFromZip(r io.ReaderAt, size int64) (io.ReadCloser, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip file: %w", err)
+	}
+	for _, f := range zr.File {
+		if path.Base(f.Name) == conversationsFile {
+			return f.Open()
+		}
+	}
+	return nil, fmt.Errorf("zip file has no %s", conversationsFile)
+}
+
+// unixTime converts the fractional Unix seconds of ChatGPT exports
+func unixTime(seconds *float64) time.Time {
+	if seconds == nil || *seconds <= 0 {
+		return time.Time{}
+	}
+	sec := int64(*seconds)
+	return time.Unix(sec, int64((*seconds-float64(sec))*1e9))
+}
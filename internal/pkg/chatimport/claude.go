@@ -0,0 +1,107 @@
+package chatimport
+
+import (
+	"strings"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+)
+
+type claudeMessage struct {
+	UUID      string    `json:"uuid"`
+	Text      string    `json:"text"`
+	Sender    string    `json:"sender"`
+	CreatedAt time.Time `json:"created_at"`
+	Content   []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		Thinking string `json:"thinking"`
+	} `json:"content"`
+	ParentUUID string `json:"parent_message_uuid"`
+}
+
+// parseClaude converts a Claude conversation. Messages follow each other in the order
+// exported unless they name their parent, as in newer exports with edited messages.
+func parseClaude(raw *rawConversation) Conversation {
+	conv := Conversation{
+		ID:        raw.UUID,
+		Title:     raw.Name,
+		CreatedAt: raw.CreatedAt,
+		UpdatedAt: raw.UpdatedAt,
+	}
+
+	nearest := make(map[string]string, len(raw.ChatMessages)) // Nearest kept message of each message
+	previous := ""
+	last := conv.CreatedAt
+	for _, m := range raw.ChatMessages {
+		parent := previous
+		if m.ParentUUID != "" {
+			parent = nearest[m.ParentUUID] // Empty for the root
+		}
+
+		msg, ok := claudeToMessage(&m)
+		if !ok {
+			if m.UUID != "" {
+				nearest[m.UUID] = parent
+			}
+			continue
+		}
+		msg.ParentID = parent
+		if msg.CreatedAt.IsZero() {
+			msg.CreatedAt = last
+		}
+		last = msg.CreatedAt
+
+		conv.Messages = append(conv.Messages, msg)
+		nearest[msg.ID] = msg.ID
+		previous = msg.ID
+	}
+	return conv
+}
+
+// claudeToMessage converts a message, reporting false for empty messages
+func claudeToMessage(m *claudeMessage) (Message, bool) {
+	var role model.MessageRole
+	switch m.Sender {
+	case "human":
+		role = model.RoleUser
+	case "assistant":
+		role = model.RoleAssistant
+	default:
+		return Message{}, false
+	}
+
+	// Content blocks are text, thinking and tool use; older exports only have the text
+	var texts, thinking []string
+	for _, block := range m.Content {
+		switch block.Type {
+		case "text":
+			if block.Text != "" {
+				texts = append(texts, block.Text)
+			}
+		case "thinking":
+			if block.Thinking != "" {
+				thinking = append(thinking, block.Thinking)
+			}
+		}
+	}
+	content := strings.Join(texts, "\n")
+	if content == "" {
+		content = m.Text
+	}
+	content = strings.TrimSpace(content)
+	if content == "" || m.UUID == "" {
+		return Message{}, false
+	}
+
+	msg := Message{
+		ID:        m.UUID,
+		Role:      role,
+		Content:   content,
+		CreatedAt: m.CreatedAt,
+	}
+	if role == model.RoleAssistant {
+		msg.Reasoning = strings.TrimSpace(strings.Join(thinking, "\n\n"))
+	}
+	return msg, true
+}
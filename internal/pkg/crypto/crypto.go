@@ -4,41 +4,83 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
 )
 
+// DefaultKey is the development key used when no key is configured. Data encrypted with
+// it is not protected; release mode refuses to start with it.
+const DefaultKey = "01234567890123456789012345678901"
+
+// minPassphraseLength is the minimum length of a passphrase key
+const minPassphraseLength = 12
+
+// versionPrefix starts ciphertexts carrying the ID of their key
+const versionPrefix = "v1:"
+
 var (
-	ErrInvalidKeyLength = errors.New("encryption key must be 32 bytes for AES-256")
+	ErrInvalidKeyLength  = errors.New("encryption key must be 32 bytes for AES-256")
 	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+	ErrWeakPassphrase    = fmt.Errorf("encryption passphrase must be at least %d characters", minPassphraseLength)
+	ErrUnknownKey        = errors.New("ciphertext was encrypted with an unknown key")
 )
 
-// Encryptor provides encryption and decryption using AES-256-GCM
+// Encryptor provides encryption and decryption using AES-256-GCM. It encrypts with the
+// current key and decrypts with the current or any previous key, so that keys can be
+// rotated.
 type Encryptor struct {
-	key []byte
+	currentID string
+	keys      map[string]cipher.AEAD
+	order     []string // Current key first, for ciphertexts without a key ID
 }
 
-// NewEncryptor creates a new Encryptor with the given key
-func NewEncryptor(key string) (*Encryptor, error) {
-	keyBytes := []byte(key)
-	if len(keyBytes) != 32 {
-		return nil, ErrInvalidKeyLength
+// NewEncryptor creates a new Encryptor from 32-byte keys. The first key encrypts, the
+// previous ones only decrypt.
+func NewEncryptor(current []byte, previous ...[]byte) (*Encryptor, error) {
+	e := &Encryptor{keys: make(map[string]cipher.AEAD)}
+	for i, key := range append([][]byte{current}, previous...) {
+		if len(key) != 32 {
+			return nil, ErrInvalidKeyLength
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		id := KeyID(key)
+		if i == 0 {
+			e.currentID = id
+		}
+		if _, ok := e.keys[id]; ok {
+			continue
+		}
+		e.keys[id] = gcm
+		e.order = append(e.order, id)
 	}
-	return &Encryptor{key: keyBytes}, nil
+	return e, nil
 }
 
-// Encrypt encrypts plaintext using AES-256-GCM and returns base64-encoded ciphertext
-func (e *Encryptor) Encrypt(plaintext string) (string, error) {
-	block, err := aes.NewCipher(e.key)
-	if err != nil {
-		return "", err
-	}
+// CurrentKeyID returns the ID of the key new ciphertexts are encrypted with
+func (e *Encryptor) CurrentKeyID() string {
+	return e.currentID
+}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
+// Encrypt encrypts plaintext with the current key and returns "v1:<key ID>:" followed
+// by the base64-encoded nonce and ciphertext
+func (e *Encryptor) Encrypt(plaintext string) (string, error) {
+	gcm := e.keys[e.currentID]
 
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
@@ -46,26 +88,63 @@ func (e *Encryptor) Encrypt(plaintext string) (string, error) {
 	}
 
 	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	return versionPrefix + e.currentID + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// Decrypt decrypts base64-encoded ciphertext using AES-256-GCM
+// Decrypt decrypts a ciphertext of Encrypt. Ciphertexts without a key ID, written before
+// key IDs were introduced, are tried with every key.
 func (e *Encryptor) Decrypt(encoded string) (string, error) {
-	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
-	if err != nil {
-		return "", err
+	if rest, ok := strings.CutPrefix(encoded, versionPrefix); ok {
+		id, data, ok := strings.Cut(rest, ":")
+		if !ok {
+			return "", ErrInvalidCiphertext
+		}
+		gcm, ok := e.keys[id]
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnknownKey, id)
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return "", err
+		}
+		return open(gcm, ciphertext)
 	}
 
-	block, err := aes.NewCipher(e.key)
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return "", err
 	}
+	for _, id := range e.order {
+		if plaintext, err := open(e.keys[id], ciphertext); err == nil {
+			return plaintext, nil
+		}
+	}
+	return "", ErrUnknownKey
+}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
+// HasKey returns true if the key with the ID can decrypt
+func (e *Encryptor) HasKey(id string) bool {
+	_, ok := e.keys[id]
+	return ok
+}
+
+// NeedsRotation returns true if the ciphertext is not encrypted with the current key
+func (e *Encryptor) NeedsRotation(encoded string) bool {
+	return !strings.HasPrefix(encoded, versionPrefix+e.currentID+":")
+}
+
+// IsCiphertext returns true if the value is a ciphertext of Encrypt rather than plaintext
+func IsCiphertext(value string) bool {
+	rest, ok := strings.CutPrefix(value, versionPrefix)
+	if !ok || len(rest) < 9 || rest[8] != ':' {
+		return false
 	}
+	_, err := hex.DecodeString(rest[:8])
+	return err == nil
+}
 
+// open splits the nonce off a ciphertext and decrypts it
+func open(gcm cipher.AEAD, ciphertext []byte) (string, error) {
 	nonceSize := gcm.NonceSize()
 	if len(ciphertext) < nonceSize {
 		return "", ErrInvalidCiphertext
@@ -80,7 +159,91 @@ func (e *Encryptor) Decrypt(encoded string) (string, error) {
 	return string(plaintext), nil
 }
 
-// GenerateKey generates a random 32-byte key suitable for AES-256
+// KeyID returns the ID of a key embedded in its ciphertexts: the first 8 hex digits of
+// its SHA-256 hash
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// IsPassphrase returns true if a configured secret is a passphrase rather than a key:
+// neither 32 raw bytes nor 32 base64-encoded bytes
+func IsPassphrase(secret string) bool {
+	if len(secret) == 32 {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(secret)
+	return err != nil || len(decoded) != 32
+}
+
+// ParseKey returns the AES-256 key of a configured secret. 32 raw bytes and 32
+// base64-encoded bytes (as returned by GenerateKey) are used as is; anything else is a
+// passphrase stretched with scrypt and the salt.
+func ParseKey(secret string, salt []byte) ([]byte, error) {
+	if len(secret) == 32 {
+		return []byte(secret), nil
+	}
+	if !IsPassphrase(secret) {
+		return base64.StdEncoding.DecodeString(secret)
+	}
+	return DeriveKey(secret, salt)
+}
+
+// DeriveKey stretches a passphrase into an AES-256 key with scrypt and the salt
+func DeriveKey(passphrase string, salt []byte) ([]byte, error) {
+	if len(passphrase) < minPassphraseLength {
+		return nil, ErrWeakPassphrase
+	}
+	if len(salt) == 0 {
+		return nil, errors.New("a salt is required to derive a key from a passphrase")
+	}
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+// NewSalt returns a random key derivation salt
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// LoadOrCreateSalt reads the key derivation salt from path, creating a random one on
+// first use. The salt is not secret but must be kept with the data it protects.
+func LoadOrCreateSalt(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		salt, err := ParseSalt(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid salt in %s", path)
+		}
+		return salt, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	salt, err := NewSalt()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(salt)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write salt: %w", err)
+	}
+	return salt, nil
+}
+
+// ParseSalt decodes the content of a salt file written by LoadOrCreateSalt
+func ParseSalt(data []byte) ([]byte, error) {
+	salt, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(salt) < 16 {
+		return nil, errors.New("invalid salt")
+	}
+	return salt, nil
+}
+
+// GenerateKey generates a random 32-byte key suitable for AES-256, base64-encoded
 func GenerateKey() (string, error) {
 	key := make([]byte, 32)
 	if _, err := io.ReadFull(rand.Reader, key); err != nil {
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/allwaysyou/llm-agent/internal/pkg/constants"
+	"github.com/allwaysyou/llm-agent/internal/pkg/httpclient"
 )
 
 // Provider defines the embedding provider interface
@@ -18,6 +19,13 @@ type Provider interface {
 	GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
 }
 
+func defaultClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return httpclient.Default()
+}
+
 // OpenAIProvider implements embedding using OpenAI API
 type OpenAIProvider struct {
 	apiKey  string
@@ -26,8 +34,9 @@ type OpenAIProvider struct {
 	client  *http.Client
 }
 
-// NewOpenAIProvider creates a new OpenAI embedding provider
-func NewOpenAIProvider(apiKey, baseURL, model string) *OpenAIProvider {
+// NewOpenAIProvider creates a new OpenAI embedding provider.
+// A nil client uses the shared default from httpclient.
+func NewOpenAIProvider(apiKey, baseURL, model string, client *http.Client) *OpenAIProvider {
 	if baseURL == "" {
 		baseURL = constants.DefaultOpenAIBaseURL
 	}
@@ -38,7 +47,7 @@ func NewOpenAIProvider(apiKey, baseURL, model string) *OpenAIProvider {
 		apiKey:  apiKey,
 		baseURL: strings.TrimSuffix(baseURL, "/"),
 		model:   model,
-		client:  &http.Client{},
+		client:  defaultClient(client),
 	}
 }
 
@@ -120,8 +129,9 @@ type OllamaProvider struct {
 	client  *http.Client
 }
 
-// NewOllamaProvider creates a new Ollama embedding provider
-func NewOllamaProvider(baseURL, model string) *OllamaProvider {
+// NewOllamaProvider creates a new Ollama embedding provider.
+// A nil client uses the shared default from httpclient.
+func NewOllamaProvider(baseURL, model string, client *http.Client) *OllamaProvider {
 	if baseURL == "" {
 		baseURL = constants.DefaultOllamaBaseURL
 	}
@@ -131,7 +141,7 @@ func NewOllamaProvider(baseURL, model string) *OllamaProvider {
 	return &OllamaProvider{
 		baseURL: strings.TrimSuffix(baseURL, "/"),
 		model:   model,
-		client:  &http.Client{},
+		client:  defaultClient(client),
 	}
 }
 
@@ -0,0 +1,207 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Config configures the HTTP clients used to call LLM and embedding providers
+type Config struct {
+	ConnectTimeout  time.Duration // Dial and TLS handshake timeout
+	ResponseTimeout time.Duration // Time to wait for response headers (streamed bodies are not cut off)
+	MaxRetries      int           // Retries on 429/5xx and connection failures
+	BaseDelay       time.Duration // Initial backoff, doubled on every retry
+	MaxDelay        time.Duration // Backoff cap, longer Retry-After values are not waited for
+	ProxyURL        string        // Empty = use HTTP_PROXY/HTTPS_PROXY from the environment
+}
+
+// DefaultConfig returns the config used when none is given
+func DefaultConfig() Config {
+	return Config{
+		ConnectTimeout:  10 * time.Second,
+		ResponseTimeout: 120 * time.Second,
+		MaxRetries:      2,
+		BaseDelay:       500 * time.Millisecond,
+		MaxDelay:        10 * time.Second,
+	}
+}
+
+var defaultClient = sync.OnceValue(func() *http.Client {
+	client, _ := New(DefaultConfig())
+	return client
+})
+
+// Default returns a shared client built from DefaultConfig
+func Default() *http.Client {
+	return defaultClient()
+}
+
+// New creates an HTTP client with timeouts, proxy settings and retries
+func New(cfg Config) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   cfg.ConnectTimeout,
+		ResponseHeaderTimeout: cfg.ResponseTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	return &http.Client{Transport: &retryTransport{base: transport, cfg: cfg}}, nil
+}
+
+// retryTransport retries requests that failed in a way that is safe to repeat
+type retryTransport struct {
+	base http.RoundTripper
+	cfg  Config
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.cfg.MaxRetries || !shouldRetry(req, resp, err) || !canRewind(req) {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if retryAfter := ParseRetryAfter(resp.Header); retryAfter > 0 {
+				// Too long to wait inline, let the caller decide (e.g. fail over)
+				if retryAfter > t.cfg.MaxDelay {
+					return resp, nil
+				}
+				wait = retryAfter
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		log.Printf("[HTTPClient:RoundTrip] %s %s failed (%s), retrying in %v (attempt %d/%d)",
+			req.Method, req.URL.Host, reason, wait, attempt+1, t.cfg.MaxRetries)
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// backoff returns the exponential backoff for the attempt with equal jitter
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	delay := t.cfg.BaseDelay << attempt
+	if delay <= 0 || delay > t.cfg.MaxDelay {
+		delay = t.cfg.MaxDelay
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// shouldRetry reports whether the attempt failed transiently. Rejected requests
+// (429/5xx) were not processed and can be resent; other transport failures are only
+// retried when the request is idempotent or never reached the server.
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return true
+		}
+		return isIdempotent(req.Method)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return !quotaExhausted(resp)
+	case http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout, 529: // 529 = Anthropic overloaded
+		return true
+	}
+	return false
+}
+
+// quotaExhausted reports whether a 429 response reports an exhausted quota (OpenAI
+// insufficient_quota), which fails again until topped up. The body stays readable.
+func quotaExhausted(resp *http.Response) bool {
+	peek, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peek), resp.Body), resp.Body}
+	return err == nil && bytes.Contains(peek, []byte("insufficient_quota"))
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// canRewind reports whether the request body can be sent again
+func canRewind(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// ParseRetryAfter reads retry-after-ms (OpenAI/Azure) or the standard Retry-After header
+func ParseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,175 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// JSON Schema types
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+)
+
+// Schema is the subset of JSON Schema supported by every provider's structured output
+// mode (OpenAI strict mode, Claude tool input schemas, Gemini response schemas)
+type Schema struct {
+	Type                 string             `json:"type"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+}
+
+// Format names a schema the model output must conform to
+type Format struct {
+	Name        string // Identifier, [a-zA-Z0-9_-]
+	Description string
+	Schema      *Schema // Root must be an object
+}
+
+// Object creates a strict object schema where all properties are required
+func Object(properties map[string]*Schema) *Schema {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+
+	closed := false
+	return &Schema{
+		Type:                 TypeObject,
+		Properties:           properties,
+		Required:             required,
+		AdditionalProperties: &closed,
+	}
+}
+
+// Array creates an array schema
+func Array(items *Schema) *Schema {
+	return &Schema{Type: TypeArray, Items: items}
+}
+
+// String creates a string schema, optionally restricted to the given values
+func String(description string, enum ...string) *Schema {
+	return &Schema{Type: TypeString, Description: description, Enum: enum}
+}
+
+// Number creates a number schema
+func Number(description string) *Schema {
+	return &Schema{Type: TypeNumber, Description: description}
+}
+
+// Integer creates an integer schema
+func Integer(description string) *Schema {
+	return &Schema{Type: TypeInteger, Description: description}
+}
+
+// Boolean creates a boolean schema
+func Boolean(description string) *Schema {
+	return &Schema{Type: TypeBoolean, Description: description}
+}
+
+// Validate parses data as JSON and checks it against the schema
+func (s *Schema) Validate(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return s.validate(value, "$")
+}
+
+func (s *Schema) validate(value interface{}, path string) error {
+	switch s.Type {
+	case TypeObject:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return typeError(path, s.Type, value)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		for name, v := range obj {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unexpected property %q", path, name)
+				}
+				continue
+			}
+			if err := prop.validate(v, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case TypeArray:
+		arr, ok := value.([]interface{})
+		if !ok {
+			return typeError(path, s.Type, value)
+		}
+		if s.Items != nil {
+			for i, v := range arr {
+				if err := s.Items.validate(v, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case TypeString:
+		str, ok := value.(string)
+		if !ok {
+			return typeError(path, s.Type, value)
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			return fmt.Errorf("%s: %q is not one of %s", path, str, strings.Join(s.Enum, ", "))
+		}
+	case TypeNumber:
+		if _, ok := value.(float64); !ok {
+			return typeError(path, s.Type, value)
+		}
+	case TypeInteger:
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return typeError(path, s.Type, value)
+		}
+	case TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return typeError(path, s.Type, value)
+		}
+	}
+	return nil
+}
+
+func typeError(path, want string, value interface{}) error {
+	got := "null"
+	switch value.(type) {
+	case map[string]interface{}:
+		got = TypeObject
+	case []interface{}:
+		got = TypeArray
+	case string:
+		got = TypeString
+	case float64:
+		got = TypeNumber
+	case bool:
+		got = TypeBoolean
+	}
+	return fmt.Errorf("%s: expected %s, got %s", path, want, got)
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
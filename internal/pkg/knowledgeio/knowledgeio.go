@@ -0,0 +1,381 @@
+// Package knowledgeio reads and writes knowledge records in portable formats: JSON
+// Lines, Markdown and CSV.
+package knowledgeio
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+)
+
+// Format is a portable knowledge file format
+type Format string
+
+const (
+	FormatJSONL    Format = "jsonl"
+	FormatMarkdown Format = "markdown"
+	FormatCSV      Format = "csv"
+)
+
+// ParseFormat returns the format of a name, e.g. from a query parameter
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "jsonl", "ndjson":
+		return FormatJSONL, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	case "csv":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("unknown knowledge format %q (want jsonl, markdown or csv)", name)
+}
+
+// FormatOf returns the format of a file by its extension
+func FormatOf(filename string) (Format, error) {
+	return ParseFormat(strings.TrimPrefix(filepath.Ext(filename), "."))
+}
+
+// Extension returns the file extension of the format, without the dot
+func (f Format) Extension() string {
+	if f == FormatMarkdown {
+		return "md"
+	}
+	return string(f)
+}
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// Write writes records in the format
+func Write(w io.Writer, format Format, records []model.KnowledgeRecord) error {
+	switch format {
+	case FormatJSONL:
+		return writeJSONL(w, records)
+	case FormatMarkdown:
+		return writeMarkdown(w, records)
+	case FormatCSV:
+		return writeCSV(w, records)
+	}
+	return fmt.Errorf("unknown knowledge format %q", format)
+}
+
+// Read reads records in the format. Fields missing from the file are left empty.
+func Read(r io.Reader, format Format) ([]model.KnowledgeRecord, error) {
+	switch format {
+	case FormatJSONL:
+		return readJSONL(r)
+	case FormatMarkdown:
+		return readMarkdown(r)
+	case FormatCSV:
+		return readCSV(r)
+	}
+	return nil, fmt.Errorf("unknown knowledge format %q", format)
+}
+
+// JSON Lines: one record object per line
+
+func writeJSONL(w io.Writer, records []model.KnowledgeRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readJSONL(r io.Reader) ([]model.KnowledgeRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var records []model.KnowledgeRecord
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var rec model.KnowledgeRecord
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Markdown: a "## <id>" section per record with a "- key: value" list of its fields,
+// followed by the content as a block quote. Hand-written sections without fields or
+// with plain paragraphs as content are accepted too.
+
+func writeMarkdown(w io.Writer, records []model.KnowledgeRecord) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("# Knowledge\n")
+	for _, rec := range records {
+		fmt.Fprintf(bw, "\n## %s\n\n", rec.ID)
+		for _, f := range recordFields[1:] { // The ID is the heading
+			if value := f.get(&rec); value != "" {
+				fmt.Fprintf(bw, "- %s: %s\n", f.name, value)
+			}
+		}
+		bw.WriteString("\n")
+		for _, line := range strings.Split(rec.Content, "\n") {
+			if line == "" {
+				bw.WriteString(">\n")
+			} else {
+				bw.WriteString("> " + line + "\n")
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func readMarkdown(r io.Reader) ([]model.KnowledgeRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var records []model.KnowledgeRecord
+	var current *model.KnowledgeRecord
+	var content []string
+	finish := func() {
+		if current == nil {
+			return
+		}
+		current.Content = strings.TrimSpace(strings.Join(content, "\n"))
+		records = append(records, *current)
+		current, content = nil, nil
+	}
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		switch {
+		case strings.HasPrefix(text, "## "):
+			finish()
+			current = &model.KnowledgeRecord{ID: strings.TrimSpace(text[3:])}
+		case current == nil:
+			// Title and text before the first record
+		case strings.HasPrefix(text, ">"):
+			content = append(content, strings.TrimPrefix(text[1:], " "))
+		case len(content) == 0 && strings.HasPrefix(text, "- "):
+			name, value, ok := strings.Cut(text[2:], ":")
+			if f := fieldByName(strings.TrimSpace(name)); ok && f != nil {
+				if err := f.set(current, strings.TrimSpace(value)); err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+			} else {
+				content = append(content, text)
+			}
+		case text == "" && len(content) == 0:
+			// Blank lines around the field list
+		default:
+			content = append(content, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	finish()
+	return records, nil
+}
+
+// CSV: a header row naming the columns, then a row per record. Columns are matched by
+// name, so they may come in any order and all but content may be left out.
+
+func writeCSV(w io.Writer, records []model.KnowledgeRecord) error {
+	cw := csv.NewWriter(w)
+	header := []string{"content"}
+	for _, f := range recordFields {
+		header = append(header, f.name)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, rec := range records {
+		row := []string{rec.Content}
+		for _, f := range recordFields {
+			row = append(row, f.get(&rec))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func readCSV(r io.Reader) ([]model.KnowledgeRecord, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	contentCol := -1
+	fields := make([]*recordField, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "content" {
+			contentCol = i
+		} else {
+			fields[i] = fieldByName(name)
+		}
+	}
+	if contentCol < 0 {
+		return nil, fmt.Errorf("missing content column")
+	}
+
+	var records []model.KnowledgeRecord
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if contentCol >= len(row) {
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		rec := model.KnowledgeRecord{Content: strings.TrimSpace(row[contentCol])}
+		for i, value := range row {
+			if i < len(fields) && fields[i] != nil {
+				if err := fields[i].set(&rec, strings.TrimSpace(value)); err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// recordField reads and writes a record field other than the content as text
+type recordField struct {
+	name string
+	get  func(*model.KnowledgeRecord) string
+	set  func(*model.KnowledgeRecord, string) error
+}
+
+// recordFields lists the fields written to Markdown and CSV, in order
+var recordFields = []recordField{
+	{"id", func(r *model.KnowledgeRecord) string { return r.ID }, func(r *model.KnowledgeRecord, v string) error { r.ID = v; return nil }},
+	{"workspace_id", func(r *model.KnowledgeRecord) string { return r.WorkspaceID }, func(r *model.KnowledgeRecord, v string) error { r.WorkspaceID = v; return nil }},
+	{"tier", func(r *model.KnowledgeRecord) string { return string(r.Tier) }, func(r *model.KnowledgeRecord, v string) error { r.Tier = model.KnowledgeTier(v); return nil }},
+	{"category", func(r *model.KnowledgeRecord) string { return string(r.Category) }, func(r *model.KnowledgeRecord, v string) error { r.Category = model.KnowledgeCategory(v); return nil }},
+	{"source", func(r *model.KnowledgeRecord) string { return string(r.Source) }, func(r *model.KnowledgeRecord, v string) error { r.Source = model.KnowledgeSource(v); return nil }},
+	{"importance", func(r *model.KnowledgeRecord) string { return formatFloat(r.Importance) }, func(r *model.KnowledgeRecord, v string) error { return parseFloat(v, &r.Importance) }},
+	{"hit_count", func(r *model.KnowledgeRecord) string { return formatInt(r.HitCount) }, func(r *model.KnowledgeRecord, v string) error { return parseInt(v, &r.HitCount) }},
+	{"last_hit_at", func(r *model.KnowledgeRecord) string { return formatTimePtr(r.LastHitAt) }, func(r *model.KnowledgeRecord, v string) error { return parseTimePtr(v, &r.LastHitAt) }},
+	{"promoted_at", func(r *model.KnowledgeRecord) string { return formatTimePtr(r.PromotedAt) }, func(r *model.KnowledgeRecord, v string) error { return parseTimePtr(v, &r.PromotedAt) }},
+	{"superseded_by", func(r *model.KnowledgeRecord) string { return r.SupersededBy }, func(r *model.KnowledgeRecord, v string) error { r.SupersededBy = v; return nil }},
+	{"created_at", func(r *model.KnowledgeRecord) string { return formatTime(r.CreatedAt) }, func(r *model.KnowledgeRecord, v string) error { return parseTime(v, &r.CreatedAt) }},
+	{"updated_at", func(r *model.KnowledgeRecord) string { return formatTime(r.UpdatedAt) }, func(r *model.KnowledgeRecord, v string) error { return parseTime(v, &r.UpdatedAt) }},
+}
+
+func fieldByName(name string) *recordField {
+	for i := range recordFields {
+		if recordFields[i].name == name {
+			return &recordFields[i]
+		}
+	}
+	return nil
+}
+
+func formatFloat(v float32) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
+
+func parseFloat(s string, v *float32) error {
+	if s == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*v = float32(f)
+	return nil
+}
+
+func formatInt(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+func parseInt(s string, v *int) error {
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v = n
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
+
+// parseTime accepts RFC 3339 timestamps and plain dates
+func parseTime(s string, t *time.Time) error {
+	if s == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			*t = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time %q", s)
+}
+
+func parseTimePtr(s string, t **time.Time) error {
+	var parsed time.Time
+	if err := parseTime(s, &parsed); err != nil || parsed.IsZero() {
+		return err
+	}
+	*t = &parsed
+	return nil
+}
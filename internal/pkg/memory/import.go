@@ -0,0 +1,249 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/adapter"
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/google/uuid"
+)
+
+// ImportKnowledge adds knowledge records of a user, each to the workspace it names.
+//
+// Active records are deduplicated against the active knowledge of their workspace: an
+// entry with the same content is always a duplicate, and if both an embedding provider
+// and llm are available, similar entries go through conflict detection like extracted
+// facts do, so that a record may also supersede an existing entry. Superseded records
+// are imported as history of the record replacing them, and dropped with it if it was a
+// duplicate. Tier, hit counts and timestamps of the records are kept. Canceling ctx or a
+// usage or rate limit during conflict detection stops the import, leaving the remaining
+// records out.
+func (m *DefaultManager) ImportKnowledge(ctx context.Context, userID string, records []model.KnowledgeRecord, llm adapter.LLMAdapter) *model.KnowledgeImportResult {
+	log.Printf("[Knowledge:Import] Starting - Records=%d, ConflictDetection=%v", len(records), m.embedProvider != nil && llm != nil)
+	result := &model.KnowledgeImportResult{Total: len(records)}
+
+	byID := make(map[string]int, len(records))
+	for i, rec := range records {
+		if rec.ID != "" {
+			byID[rec.ID] = i
+		}
+	}
+	imported := make(map[int]string, len(records)) // ID of the entry created for a record
+	done := make(map[int]bool, len(records))
+	existing := make(map[string]map[string]bool) // Active contents by workspace
+
+	// 1. Active records, checked for duplicates and conflicts
+	for i := range records {
+		rec := &records[i]
+		if rec.SupersededBy != "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("record %d: %v", i+1, err))
+			result.Stopped = true
+			break
+		}
+		done[i] = true
+		rec.Content = strings.TrimSpace(rec.Content)
+		if rec.Content == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("record %d: content is empty", i+1))
+			continue
+		}
+
+		contents, ok := existing[rec.WorkspaceID]
+		if !ok {
+			var err error
+			if contents, err = m.activeContents(userID, rec.WorkspaceID); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("record %d: %v", i+1, err))
+				continue
+			}
+			existing[rec.WorkspaceID] = contents
+		}
+		if contents[rec.Content] {
+			log.Printf("[Knowledge:Import] SKIP (same content) - Content='%s'", truncateStr(rec.Content, 50))
+			result.Skipped++
+			continue
+		}
+
+		conflict, err := m.detectImportConflict(ctx, userID, rec, llm)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("record %d: %v", i+1, err))
+			result.Stopped = true
+			break
+		}
+		if conflict.Action == ActionSkip {
+			log.Printf("[Knowledge:Import] SKIP (duplicate) - Content='%s'", truncateStr(rec.Content, 50))
+			result.Skipped++
+			continue
+		}
+
+		knowledge, err := m.importRecord(userID, rec, "")
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("record %d: %v", i+1, err))
+			continue
+		}
+		imported[i] = knowledge.ID
+		contents[rec.Content] = true
+		result.Created++
+
+		if conflict.Action == ActionUpdate {
+			if err := m.SupersedeKnowledge(ctx, conflict.ConflictingID, knowledge.ID); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("record %d: %v", i+1, err))
+				continue
+			}
+			delete(contents, conflict.OldContent)
+			result.Superseded++
+		}
+	}
+
+	// 2. Superseded records, after the record replacing them
+	var importHistory func(i int) (string, bool)
+	importHistory = func(i int) (string, bool) {
+		if id, ok := imported[i]; ok {
+			return id, true
+		}
+		if done[i] {
+			return "", false
+		}
+		done[i] = true // Also breaks cycles of supersession
+
+		rec := &records[i]
+		rec.Content = strings.TrimSpace(rec.Content)
+		if rec.Content == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("record %d: content is empty", i+1))
+			return "", false
+		}
+		next, ok := byID[rec.SupersededBy]
+		if !ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("record %d: superseded by unknown record %s", i+1, rec.SupersededBy))
+			return "", false
+		}
+		successorID, ok := importHistory(next)
+		if !ok {
+			// The replacing record was a duplicate, so is its history
+			result.Skipped++
+			return "", false
+		}
+
+		knowledge, err := m.importRecord(userID, rec, successorID)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("record %d: %v", i+1, err))
+			return "", false
+		}
+		imported[i] = knowledge.ID
+		result.Created++
+		return knowledge.ID, true
+	}
+	for i := range records {
+		if result.Stopped {
+			break // Successors may be missing, history would be dropped as duplicate
+		}
+		if records[i].SupersededBy == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("record %d: %v", i+1, err))
+			result.Stopped = true
+			break
+		}
+		importHistory(i)
+	}
+
+	log.Printf("[Knowledge:Import] Complete - Created=%d, Superseded=%d, Skipped=%d, Errors=%d, Stopped=%v",
+		result.Created, result.Superseded, result.Skipped, len(result.Errors), result.Stopped)
+	return result
+}
+
+// activeContents returns the contents of the active knowledge of a user's workspace
+func (m *DefaultManager) activeContents(userID, workspaceID string) (map[string]bool, error) {
+	knowledge, err := m.knowledgeRepo.GetAllActive(userID, &workspaceID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get knowledge: %w", err)
+	}
+	contents := make(map[string]bool, len(knowledge))
+	for _, k := range knowledge {
+		contents[strings.TrimSpace(k.Content)] = true
+	}
+	return contents, nil
+}
+
+// detectImportConflict checks a record against similar knowledge of its workspace. Without
+// an embedding provider or llm every record is new. Returns an error only if conflict
+// detection hit a usage or rate limit.
+func (m *DefaultManager) detectImportConflict(ctx context.Context, userID string, rec *model.KnowledgeRecord, llm adapter.LLMAdapter) (*ConflictResult, error) {
+	create := &ConflictResult{Action: ActionCreate}
+	if m.embedProvider == nil || llm == nil {
+		return create, nil
+	}
+
+	similar, err := m.SearchKnowledge(ctx, SearchOptions{
+		Query:      rec.Content,
+		UserID:     userID,
+		Scope:      &Scope{UserID: userID, WorkspaceID: rec.WorkspaceID},
+		ActiveOnly: true,
+		MinScore:   m.config.SimilarKnowledgeThreshold,
+		Limit:      m.config.ConflictCheckLimit,
+	})
+	if err != nil {
+		log.Printf("[Knowledge:Import] Error searching similar: %v", err)
+		return create, nil
+	}
+
+	fact := ExtractedFact{Content: rec.Content, Category: normalizeCategory(string(rec.Category)), Importance: rec.Importance}
+	conflict, err := m.processor.DetectConflict(ctx, fact, similar, llm)
+	if err != nil {
+		log.Printf("[Knowledge:Import] Error detecting conflict: %v", err)
+		return nil, err
+	}
+	return conflict, nil
+}
+
+// importRecord stores a record as a new knowledge entry with its embedding, superseded
+// by supersededBy if set
+func (m *DefaultManager) importRecord(userID string, rec *model.KnowledgeRecord, supersededBy string) (*model.Knowledge, error) {
+	now := time.Now()
+	knowledge := &model.Knowledge{
+		ID:           uuid.New().String(),
+		Content:      rec.Content,
+		UserID:       userID,
+		WorkspaceID:  rec.WorkspaceID,
+		SupersededBy: supersededBy,
+		Tier:         model.TierLongTerm,
+		HitCount:     max(rec.HitCount, 0),
+		LastHitAt:    rec.LastHitAt,
+		PromotedAt:   rec.PromotedAt,
+		CreatedAt:    rec.CreatedAt,
+		UpdatedAt:    rec.UpdatedAt,
+	}
+	if rec.Tier == model.TierMidTerm {
+		knowledge.Tier = model.TierMidTerm
+	}
+	if knowledge.CreatedAt.IsZero() {
+		knowledge.CreatedAt = now
+	}
+	if knowledge.UpdatedAt.IsZero() {
+		knowledge.UpdatedAt = knowledge.CreatedAt
+	}
+
+	if err := m.knowledgeRepo.Create(knowledge); err != nil {
+		return nil, fmt.Errorf("failed to save knowledge: %w", err)
+	}
+
+	// Embed right away, so that the following records are checked against this one
+	if m.embedProvider != nil {
+		source := rec.Source
+		if source != model.SourceExtracted && source != model.SourceManual {
+			source = model.SourceManual
+		}
+		importance := rec.Importance
+		if importance <= 0 || importance > 1 {
+			importance = m.config.DefaultImportance
+		}
+		m.saveKnowledgeEmbedding(knowledge, normalizeCategory(string(rec.Category)), source, importance)
+	}
+	return knowledge, nil
+}
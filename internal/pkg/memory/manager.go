@@ -11,6 +11,7 @@ import (
 	"github.com/allwaysyou/llm-agent/internal/model"
 	"github.com/allwaysyou/llm-agent/internal/pkg/constants"
 	"github.com/allwaysyou/llm-agent/internal/pkg/embedding"
+	"github.com/allwaysyou/llm-agent/internal/pkg/prompt"
 	"github.com/allwaysyou/llm-agent/internal/pkg/vector"
 	"github.com/allwaysyou/llm-agent/internal/repository"
 	"github.com/google/uuid"
@@ -23,6 +24,7 @@ type DefaultManager struct {
 	vectorStore   *vector.VectorStore
 	embedProvider embedding.Provider
 	processor     *Processor
+	prompts       *prompt.Library
 	config        config.MemoryConfig
 }
 
@@ -32,6 +34,7 @@ func NewManager(
 	knowledgeRepo *repository.KnowledgeRepository,
 	vectorStore *vector.VectorStore,
 	embedProvider embedding.Provider,
+	prompts *prompt.Library,
 	cfg config.MemoryConfig,
 ) *DefaultManager {
 	return &DefaultManager{
@@ -39,26 +42,35 @@ func NewManager(
 		knowledgeRepo: knowledgeRepo,
 		vectorStore:   vectorStore,
 		embedProvider: embedProvider,
-		processor:     NewProcessor(cfg),
+		processor:     NewProcessor(cfg, prompts),
+		prompts:       prompts,
 		config:        cfg,
 	}
 }
 
 // SaveConversationMemory saves a conversation message (short-term, session-scoped)
-func (m *DefaultManager) SaveConversationMemory(ctx context.Context, sessionID string, role model.MessageRole, content string) (*model.Memory, error) {
-	log.Printf("[Memory:SaveConversation] SessionID=%s, Role=%s, ContentLen=%d", sessionID, role, len(content))
+func (m *DefaultManager) SaveConversationMemory(ctx context.Context, opts SaveMemoryOptions) (*model.Memory, error) {
+	log.Printf("[Memory:SaveConversation] SessionID=%s, Role=%s, ContentLen=%d", opts.SessionID, opts.Role, len(opts.Content))
 
-	if content == "" {
+	if opts.Content == "" {
 		return nil, fmt.Errorf("content cannot be empty")
 	}
 
 	memory := &model.Memory{
 		ID:        uuid.New().String(),
-		SessionID: sessionID,
-		Role:      role,
-		Content:   content,
+		SessionID: opts.SessionID,
+		UserID:    opts.UserID,
+		ParentID:  opts.ParentID,
+		Role:      opts.Role,
+		Content:   opts.Content,
+		Reasoning: opts.Reasoning,
+		ConfigID:  opts.ConfigID,
 		CreatedAt: time.Now(),
 	}
+	if opts.Usage != nil {
+		memory.PromptTokens = opts.Usage.PromptTokens
+		memory.CompletionTokens = opts.Usage.CompletionTokens
+	}
 
 	if err := m.memoryRepo.Create(memory); err != nil {
 		log.Printf("[Memory:SaveConversation] Error saving to DB: %v", err)
@@ -69,7 +81,7 @@ func (m *DefaultManager) SaveConversationMemory(ctx context.Context, sessionID s
 	return memory, nil
 }
 
-// AddKnowledge adds extracted knowledge (long-term, global)
+// AddKnowledge adds extracted knowledge (long-term, not tied to a session)
 func (m *DefaultManager) AddKnowledge(ctx context.Context, opts AddKnowledgeOptions) (*model.Knowledge, error) {
 	log.Printf("[Knowledge:Add] Starting - Category=%s, Source=%s, Importance=%.2f, Tier=%s, ContentLen=%d",
 		opts.Category, opts.Source, opts.Importance, opts.Tier, len(opts.Content))
@@ -91,12 +103,14 @@ func (m *DefaultManager) AddKnowledge(ctx context.Context, opts AddKnowledgeOpti
 	}
 
 	knowledge := &model.Knowledge{
-		ID:        uuid.New().String(),
-		Content:   opts.Content,
-		Tier:      opts.Tier,
-		HitCount:  0,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:          uuid.New().String(),
+		Content:     opts.Content,
+		UserID:      opts.UserID,
+		WorkspaceID: opts.WorkspaceID,
+		Tier:        opts.Tier,
+		HitCount:    0,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 
 	// Save to database
@@ -131,12 +145,14 @@ func (m *DefaultManager) saveKnowledgeEmbedding(knowledge *model.Knowledge, cate
 		Content:   knowledge.Content,
 		Embedding: emb,
 		MetaData: &vector.DocumentMetadata{
-			Role:       constants.RoleKnowledge,
-			Category:   string(category),
-			Source:     string(source),
-			Importance: importance,
-			IsActive:   true,
-			CreatedAt:  knowledge.CreatedAt.Unix(),
+			UserID:      knowledge.UserID,
+			WorkspaceID: knowledge.WorkspaceID,
+			Role:        constants.RoleKnowledge,
+			Category:    string(category),
+			Source:      string(source),
+			Importance:  importance,
+			IsActive:    knowledge.IsActive(),
+			CreatedAt:   knowledge.CreatedAt.Unix(),
 		},
 	}
 
@@ -171,9 +187,13 @@ func (m *DefaultManager) SearchKnowledge(ctx context.Context, opts SearchOptions
 
 	// Build filter - only search knowledge (not conversation memories)
 	filter := &vector.SearchFilter{
+		UserID:     &opts.UserID,
 		ActiveOnly: opts.ActiveOnly,
 		MinScore:   opts.MinScore,
 	}
+	if opts.Scope != nil {
+		filter.WorkspaceIDs = opts.Scope.WorkspaceIDs()
+	}
 
 	// Convert categories
 	if len(opts.Categories) > 0 {
@@ -194,7 +214,7 @@ func (m *DefaultManager) SearchKnowledge(ctx context.Context, opts SearchOptions
 			continue
 		}
 
-		knowledge, err := m.knowledgeRepo.GetByID(r.Document.ID)
+		knowledge, err := m.knowledgeRepo.GetByID(opts.UserID, r.Document.ID)
 		if err != nil || knowledge == nil {
 			log.Printf("[Knowledge:Search] Skip result - ID=%s, Error=%v", r.Document.ID, err)
 			continue
@@ -213,26 +233,31 @@ func (m *DefaultManager) SearchKnowledge(ctx context.Context, opts SearchOptions
 	return searchResults, nil
 }
 
-// BuildContext builds context messages for LLM requests
-func (m *DefaultManager) BuildContext(ctx context.Context, sessionID, query string) ([]model.Message, error) {
-	log.Printf("[Memory:BuildContext] Starting - SessionID=%s, Query='%s'", sessionID, truncateStr(query, 50))
+// BuildContext builds context messages for LLM requests. History is taken from the
+// branch ending at leafID, so sibling branches of the conversation are left out, and
+// knowledge from the scope's workspaces.
+func (m *DefaultManager) BuildContext(ctx context.Context, sessionID, leafID, query string, scope Scope) ([]model.Message, error) {
+	log.Printf("[Memory:BuildContext] Starting - SessionID=%s, LeafID=%s, Workspace=%s, Query='%s'",
+		sessionID, leafID, scope.WorkspaceID, truncateStr(query, 50))
 
 	var messages []model.Message
 
-	// 1. Get recent conversation history from this session
-	recentMemories, err := m.memoryRepo.GetRecentBySessionID(sessionID, m.config.RecentMemoryLimit)
+	// 1. Get recent conversation history from the branch
+	recentMemories, err := m.memoryRepo.GetRecentOnBranch(sessionID, leafID, m.config.RecentMemoryLimit)
 	if err != nil {
 		log.Printf("[Memory:BuildContext] Error getting recent memories: %v", err)
 		return nil, fmt.Errorf("failed to get recent memories: %w", err)
 	}
 	log.Printf("[Memory:BuildContext] Got %d recent memories", len(recentMemories))
 
-	// 2. Search for relevant knowledge (global, extracted facts)
+	// 2. Search for relevant knowledge (extracted facts in scope)
 	var knowledgeResults []model.KnowledgeSearchResult
 	if m.embedProvider != nil && query != "" {
 		log.Printf("[Memory:BuildContext] Searching for relevant knowledge...")
 		knowledgeResults, _ = m.SearchKnowledge(ctx, SearchOptions{
 			Query:      query,
+			UserID:     scope.UserID,
+			Scope:      &scope,
 			Categories: []model.KnowledgeCategory{model.CategoryPersonalInfo, model.CategoryPreference, model.CategoryFact},
 			ActiveOnly: true,
 			MinScore:   m.config.ContextRelevanceThreshold,
@@ -267,28 +292,30 @@ func (m *DefaultManager) BuildContext(ctx context.Context, sessionID, query stri
 	if len(includedIDs) > 0 {
 		go func(ids []string) {
 			for _, id := range ids {
-				_ = m.RecordKnowledgeHit(context.Background(), id)
+				_ = m.RecordKnowledgeHit(context.Background(), scope.UserID, id)
 			}
 		}(includedIDs)
 	}
 
 	// 4. Build system message with knowledge
 	if len(knowledgeParts) > 0 {
-		contextContent := constants.KnowledgeContextPrefix
-		for _, part := range knowledgeParts {
-			contextContent += constants.KnowledgeContextItem + part + "\n"
+		contextContent, err := m.prompts.Render(prompt.KnowledgeContext, map[string]any{"Knowledge": knowledgeParts})
+		if err != nil {
+			log.Printf("[Memory:BuildContext] Failed to render knowledge context: %v", err)
+		} else {
+			messages = append(messages, model.Message{
+				Role:    model.RoleSystem,
+				Content: contextContent,
+			})
+			log.Printf("[Memory:BuildContext] Added system message with %d knowledge parts", len(knowledgeParts))
 		}
-		messages = append(messages, model.Message{
-			Role:    model.RoleSystem,
-			Content: contextContent,
-		})
-		log.Printf("[Memory:BuildContext] Added system message with %d knowledge parts", len(knowledgeParts))
 	}
 
 	// 5. Add recent conversation history
 	historyCount := 0
 	for _, mem := range recentMemories {
 		if mem.Role == model.RoleUser || mem.Role == model.RoleAssistant {
+			// Stored reasoning is display-only and is not replayed to the model
 			messages = append(messages, model.Message{
 				Role:    mem.Role,
 				Content: mem.Content,
@@ -302,10 +329,13 @@ func (m *DefaultManager) BuildContext(ctx context.Context, sessionID, query stri
 	return messages, nil
 }
 
-// ProcessConversation extracts and stores knowledge from a conversation
-func (m *DefaultManager) ProcessConversation(ctx context.Context, sessionID, userMsg, assistantResp string, llm adapter.LLMAdapter) error {
-	log.Printf("[Knowledge:Process] Starting - UserMsg='%s', AssistantResp='%s'",
-		truncateStr(userMsg, 50), truncateStr(assistantResp, 50))
+// ProcessConversation extracts and stores knowledge from a conversation. Facts are stored
+// in the scope's workspace and only checked against knowledge already there, so a
+// workspace never supersedes knowledge of the global pool.
+func (m *DefaultManager) ProcessConversation(ctx context.Context, sessionID string, scope Scope, userMsg, assistantResp string, llm adapter.LLMAdapter) error {
+	log.Printf("[Knowledge:Process] Starting - Workspace=%s, UserMsg='%s', AssistantResp='%s'",
+		scope.WorkspaceID, truncateStr(userMsg, 50), truncateStr(assistantResp, 50))
+	ownScope := Scope{UserID: scope.UserID, WorkspaceID: scope.WorkspaceID}
 
 	if m.embedProvider == nil {
 		log.Printf("[Knowledge:Process] Skipping - no embedding provider")
@@ -348,6 +378,8 @@ func (m *DefaultManager) ProcessConversation(ctx context.Context, sessionID, use
 		// Search for similar existing knowledge
 		similar, err := m.SearchKnowledge(ctx, SearchOptions{
 			Query:      fact.Content,
+			UserID:     scope.UserID,
+			Scope:      &ownScope,
 			ActiveOnly: true,
 			MinScore:   m.config.SimilarKnowledgeThreshold,
 			Limit:      m.config.ConflictCheckLimit,
@@ -362,8 +394,9 @@ func (m *DefaultManager) ProcessConversation(ctx context.Context, sessionID, use
 		log.Printf("[Knowledge:Process] Detecting conflicts via LLM...")
 		conflict, err := m.processor.DetectConflict(ctx, fact, similar, llm)
 		if err != nil {
-			log.Printf("[Knowledge:Process] Error detecting conflict: %v", err)
-			continue
+			// Only usage and rate limits fail detection, the remaining facts would hit them too
+			log.Printf("[Knowledge:Process] Error detecting conflict, stopping: %v", err)
+			return err
 		}
 		log.Printf("[Knowledge:Process] Conflict result - HasConflict=%v, Action=%s, ConflictingID=%s",
 			conflict.HasConflict, conflict.Action, conflict.ConflictingID)
@@ -377,11 +410,13 @@ func (m *DefaultManager) ProcessConversation(ctx context.Context, sessionID, use
 			log.Printf("[Knowledge:Process] UPDATE - Old='%s' -> New='%s'",
 				truncateStr(conflict.OldContent, 30), truncateStr(fact.Content, 30))
 			newKnowledge, err := m.AddKnowledge(ctx, AddKnowledgeOptions{
-				Content:    fact.Content,
-				Category:   fact.Category,
-				Source:     model.SourceExtracted,
-				Importance: fact.Importance,
-				Tier:       tier,
+				Content:     fact.Content,
+				UserID:      scope.UserID,
+				WorkspaceID: scope.WorkspaceID,
+				Category:    fact.Category,
+				Source:      model.SourceExtracted,
+				Importance:  fact.Importance,
+				Tier:        tier,
 			})
 			if err != nil {
 				log.Printf("[Knowledge:Process] Error creating new knowledge: %v", err)
@@ -396,11 +431,13 @@ func (m *DefaultManager) ProcessConversation(ctx context.Context, sessionID, use
 		case ActionCreate:
 			log.Printf("[Knowledge:Process] CREATE (Tier=%s) - Content='%s'", tier, truncateStr(fact.Content, 50))
 			newKnowledge, err := m.AddKnowledge(ctx, AddKnowledgeOptions{
-				Content:    fact.Content,
-				Category:   fact.Category,
-				Source:     model.SourceExtracted,
-				Importance: fact.Importance,
-				Tier:       tier,
+				Content:     fact.Content,
+				UserID:      scope.UserID,
+				WorkspaceID: scope.WorkspaceID,
+				Category:    fact.Category,
+				Source:      model.SourceExtracted,
+				Importance:  fact.Importance,
+				Tier:        tier,
 			})
 			if err != nil {
 				log.Printf("[Knowledge:Process] Error creating knowledge: %v", err)
@@ -458,8 +495,8 @@ func truncateStr(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
-// RecordKnowledgeHit records a hit for a knowledge entry and checks for promotion
-func (m *DefaultManager) RecordKnowledgeHit(ctx context.Context, knowledgeID string) error {
+// RecordKnowledgeHit records a hit for a knowledge entry of a user and checks for promotion
+func (m *DefaultManager) RecordKnowledgeHit(ctx context.Context, userID, knowledgeID string) error {
 	// Record the hit
 	if err := m.knowledgeRepo.RecordHit(knowledgeID); err != nil {
 		log.Printf("[Knowledge:Hit] Error recording hit for %s: %v", knowledgeID, err)
@@ -467,7 +504,7 @@ func (m *DefaultManager) RecordKnowledgeHit(ctx context.Context, knowledgeID str
 	}
 
 	// Check if this knowledge should be promoted
-	knowledge, err := m.knowledgeRepo.GetByID(knowledgeID)
+	knowledge, err := m.knowledgeRepo.GetByID(userID, knowledgeID)
 	if err != nil || knowledge == nil {
 		return err
 	}
@@ -2,7 +2,6 @@ package memory
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
@@ -10,17 +9,45 @@ import (
 	"github.com/allwaysyou/llm-agent/internal/adapter"
 	"github.com/allwaysyou/llm-agent/internal/config"
 	"github.com/allwaysyou/llm-agent/internal/model"
-	"github.com/allwaysyou/llm-agent/internal/pkg/constants"
+	"github.com/allwaysyou/llm-agent/internal/pkg/jsonschema"
+	"github.com/allwaysyou/llm-agent/internal/pkg/prompt"
 )
 
+// factsFormat is the structured output schema for fact extraction
+var factsFormat = &jsonschema.Format{
+	Name:        "extracted_facts",
+	Description: "Facts about the user worth remembering long-term",
+	Schema: jsonschema.Object(map[string]*jsonschema.Schema{
+		"facts": jsonschema.Array(jsonschema.Object(map[string]*jsonschema.Schema{
+			"content": jsonschema.String("Concise statement of the fact"),
+			"category": jsonschema.String("Fact category",
+				string(model.CategoryPersonalInfo), string(model.CategoryPreference),
+				string(model.CategoryFact), string(model.CategoryEvent)),
+			"importance": jsonschema.Number("Importance between 0 and 1"),
+		})),
+	}),
+}
+
+// conflictFormat is the structured output schema for conflict detection
+var conflictFormat = &jsonschema.Format{
+	Name:        "conflict_result",
+	Description: "Whether a new fact duplicates or conflicts with existing knowledge",
+	Schema: jsonschema.Object(map[string]*jsonschema.Schema{
+		"is_duplicate":   jsonschema.Boolean("The new fact repeats existing knowledge"),
+		"is_conflict":    jsonschema.Boolean("The new fact updates existing knowledge"),
+		"conflict_index": jsonschema.Integer("Index of the conflicting knowledge, -1 if none"),
+	}),
+}
+
 // Processor handles LLM-based memory processing
 type Processor struct {
-	config config.MemoryConfig
+	config  config.MemoryConfig
+	prompts *prompt.Library
 }
 
 // NewProcessor creates a new memory processor
-func NewProcessor(cfg config.MemoryConfig) *Processor {
-	return &Processor{config: cfg}
+func NewProcessor(cfg config.MemoryConfig, prompts *prompt.Library) *Processor {
+	return &Processor{config: cfg, prompts: prompts}
 }
 
 // ExtractFacts extracts key facts from a conversation using LLM
@@ -28,34 +55,26 @@ func (p *Processor) ExtractFacts(ctx context.Context, userMsg, assistantResp str
 	log.Printf("[Processor:ExtractFacts] Starting - UserMsg='%.50s...', AssistantResp='%.50s...'",
 		userMsg, assistantResp)
 
+	content, err := p.prompts.Render(prompt.FactExtraction, map[string]any{"User": userMsg, "Assistant": assistantResp})
+	if err != nil {
+		return nil, err
+	}
 	messages := []model.Message{
 		{
 			Role:    model.RoleUser,
-			Content: fmt.Sprintf(constants.FactExtractionPrompt, userMsg, assistantResp),
+			Content: content,
 		},
 	}
 
 	log.Printf("[Processor:ExtractFacts] Calling LLM...")
-	resp, err := llm.Chat(ctx, messages)
-	if err != nil {
-		log.Printf("[Processor:ExtractFacts] LLM call failed: %v", err)
-		return nil, fmt.Errorf("LLM extraction failed: %w", err)
+	var result struct {
+		Facts []ExtractedFact `json:"facts"`
 	}
-
-	// Parse JSON response
-	content := resp.Message.Content
-	log.Printf("[Processor:ExtractFacts] LLM response: %s", content)
-
-	// Extract JSON from response (handle markdown code blocks)
-	content = extractJSON(content)
-	log.Printf("[Processor:ExtractFacts] Extracted JSON: %s", content)
-
-	var facts []ExtractedFact
-	if err := json.Unmarshal([]byte(content), &facts); err != nil {
-		log.Printf("[Processor:ExtractFacts] JSON parse error: %v", err)
-		// If parsing fails, return empty
-		return nil, nil
+	if err := adapter.ChatStructured(adapter.WithUsagePurpose(ctx, model.UsagePurposeExtraction), llm, messages, factsFormat, &result); err != nil {
+		log.Printf("[Processor:ExtractFacts] LLM extraction failed: %v", err)
+		return nil, fmt.Errorf("LLM extraction failed: %w", err)
 	}
+	facts := result.Facts
 
 	// Convert category strings to proper type
 	for i := range facts {
@@ -100,30 +119,19 @@ func (p *Processor) DetectConflict(ctx context.Context, newFact ExtractedFact, e
 	log.Printf("[Processor:DetectConflict] Found %d high-similarity candidates", len(candidates))
 
 	// Use LLM to detect conflict
-	existingStr := ""
-	for i, c := range candidates {
-		existingStr += fmt.Sprintf("%d. %s\n", i, c)
+	content, err := p.prompts.Render(prompt.ConflictDetection, map[string]any{"Fact": newFact.Content, "Existing": candidates})
+	if err != nil {
+		log.Printf("[Processor:DetectConflict] Failed to render prompt: %v -> default to CREATE", err)
+		return &ConflictResult{HasConflict: false, Action: ActionCreate}, nil
 	}
-
 	messages := []model.Message{
 		{
 			Role:    model.RoleUser,
-			Content: fmt.Sprintf(constants.ConflictDetectionPrompt, newFact.Content, existingStr),
+			Content: content,
 		},
 	}
 
 	log.Printf("[Processor:DetectConflict] Calling LLM to detect conflict...")
-	resp, err := llm.Chat(ctx, messages)
-	if err != nil {
-		log.Printf("[Processor:DetectConflict] LLM call failed: %v -> default to CREATE", err)
-		// On error, default to create
-		return &ConflictResult{HasConflict: false, Action: ActionCreate}, nil
-	}
-
-	log.Printf("[Processor:DetectConflict] LLM response: %s", resp.Message.Content)
-	content := extractJSON(resp.Message.Content)
-	log.Printf("[Processor:DetectConflict] Extracted JSON: %s", content)
-
 	var result struct {
 		IsDuplicate   bool `json:"is_duplicate"`
 		IsConflict    bool `json:"is_conflict"`
@@ -131,8 +139,14 @@ func (p *Processor) DetectConflict(ctx context.Context, newFact ExtractedFact, e
 	}
 	result.ConflictIndex = -1
 
-	if err := json.Unmarshal([]byte(content), &result); err != nil {
-		log.Printf("[Processor:DetectConflict] JSON parse error: %v -> default to CREATE", err)
+	if err := adapter.ChatStructured(adapter.WithUsagePurpose(ctx, model.UsagePurposeConflict), llm, messages, conflictFormat, &result); err != nil {
+		// A limit or cancellation means no call could be made, creating would skip deduplication
+		if adapter.IsLimitError(err) || ctx.Err() != nil {
+			log.Printf("[Processor:DetectConflict] LLM call limited: %v", err)
+			return nil, fmt.Errorf("conflict detection failed: %w", err)
+		}
+		log.Printf("[Processor:DetectConflict] LLM call failed: %v -> default to CREATE", err)
+		// On other errors, default to create
 		return &ConflictResult{HasConflict: false, Action: ActionCreate}, nil
 	}
 
@@ -159,23 +173,6 @@ func (p *Processor) DetectConflict(ctx context.Context, newFact ExtractedFact, e
 	return &ConflictResult{HasConflict: false, Action: ActionCreate}, nil
 }
 
-// extractJSON extracts JSON from a string that might contain markdown code blocks
-func extractJSON(s string) string {
-	s = strings.TrimSpace(s)
-
-	// Remove markdown code blocks
-	if strings.HasPrefix(s, "```json") {
-		s = strings.TrimPrefix(s, "```json")
-	} else if strings.HasPrefix(s, "```") {
-		s = strings.TrimPrefix(s, "```")
-	}
-	if strings.HasSuffix(s, "```") {
-		s = strings.TrimSuffix(s, "```")
-	}
-
-	return strings.TrimSpace(s)
-}
-
 // normalizeCategory normalizes category string to KnowledgeCategory
 func normalizeCategory(s string) model.KnowledgeCategory {
 	s = strings.ToLower(strings.TrimSpace(s))
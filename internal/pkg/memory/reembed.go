@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/allwaysyou/llm-agent/internal/pkg/constants"
+	"github.com/allwaysyou/llm-agent/internal/pkg/vector"
+)
+
+// reembedBatchSize is the number of entries embedded per request while re-embedding
+const reembedBatchSize = 32
+
+// Reembed rebuilds the vector store from the knowledge of all users with the current
+// embedding provider, e.g. after switching to a model of another dimension. Metadata of
+// the existing documents is kept. The store is only replaced once every entry has been
+// embedded, so a failure leaves it unchanged.
+func (m *DefaultManager) Reembed(ctx context.Context) (int, error) {
+	if m.embedProvider == nil {
+		return 0, fmt.Errorf("embedding provider not configured")
+	}
+
+	knowledge, err := m.knowledgeRepo.ListAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get knowledge: %w", err)
+	}
+	log.Printf("[Knowledge:Reembed] Starting - Entries=%d", len(knowledge))
+
+	docs := make([]vector.Document, 0, len(knowledge))
+	for start := 0; start < len(knowledge); start += reembedBatchSize {
+		batch := knowledge[start:min(start+reembedBatchSize, len(knowledge))]
+		texts := make([]string, len(batch))
+		for i, k := range batch {
+			texts[i] = k.Content
+		}
+		embeddings, err := m.embedProvider.GetEmbeddings(ctx, texts)
+		if err != nil {
+			return 0, fmt.Errorf("failed to embed entries %d-%d: %w", start+1, start+len(batch), err)
+		}
+		if len(embeddings) != len(batch) {
+			return 0, fmt.Errorf("embedding provider returned %d embeddings for %d entries", len(embeddings), len(batch))
+		}
+
+		for i, k := range batch {
+			metadata := &vector.DocumentMetadata{
+				Role:      constants.RoleKnowledge,
+				CreatedAt: k.CreatedAt.Unix(),
+			}
+			if existing, ok := m.vectorStore.Get(k.ID); ok && existing.MetaData != nil {
+				metadata = existing.MetaData
+			}
+			metadata.UserID = k.UserID
+			metadata.WorkspaceID = k.WorkspaceID
+			metadata.IsActive = k.IsActive()
+
+			docs = append(docs, vector.Document{
+				ID:        k.ID,
+				Content:   k.Content,
+				Embedding: embeddings[i],
+				MetaData:  metadata,
+			})
+		}
+		log.Printf("[Knowledge:Reembed] Embedded %d/%d", len(docs), len(knowledge))
+	}
+
+	if err := m.vectorStore.ReplaceAll(docs); err != nil {
+		return 0, fmt.Errorf("failed to replace vector store: %w", err)
+	}
+	log.Printf("[Knowledge:Reembed] Completed - Entries=%d", len(docs))
+	return len(docs), nil
+}
@@ -10,33 +10,70 @@ import (
 // Manager defines the interface for memory management
 type Manager interface {
 	// SaveConversationMemory saves a conversation message
-	SaveConversationMemory(ctx context.Context, sessionID string, role model.MessageRole, content string) (*model.Memory, error)
+	SaveConversationMemory(ctx context.Context, opts SaveMemoryOptions) (*model.Memory, error)
 
 	// SearchKnowledge searches for relevant knowledge
 	SearchKnowledge(ctx context.Context, opts SearchOptions) ([]model.KnowledgeSearchResult, error)
 
-	// BuildContext builds context messages for LLM requests
-	BuildContext(ctx context.Context, sessionID, query string) ([]model.Message, error)
+	// BuildContext builds context messages for LLM requests from the branch ending at leafID,
+	// with the relevant knowledge of the scope
+	BuildContext(ctx context.Context, sessionID, leafID, query string, scope Scope) ([]model.Message, error)
 
-	// ProcessConversation extracts and stores knowledge from a conversation
-	ProcessConversation(ctx context.Context, sessionID, userMsg, assistantResp string, llm adapter.LLMAdapter) error
+	// ProcessConversation extracts and stores knowledge from a conversation in the scope's workspace
+	ProcessConversation(ctx context.Context, sessionID string, scope Scope, userMsg, assistantResp string, llm adapter.LLMAdapter) error
 
 	// SupersedeKnowledge marks old knowledge as superseded by new one
 	SupersedeKnowledge(ctx context.Context, oldID, newID string) error
 }
 
+// Scope selects the knowledge a chat sees: the user's knowledge of its workspace and, if
+// IncludeGlobal is set, the user's global pool. The zero Scope is the global pool of the
+// empty user, who owns everything while authentication is disabled.
+type Scope struct {
+	UserID        string
+	WorkspaceID   string
+	IncludeGlobal bool
+}
+
+// WorkspaceIDs returns the workspaces whose knowledge is in scope ("" = global pool)
+func (s Scope) WorkspaceIDs() []string {
+	if s.WorkspaceID == "" {
+		return []string{""}
+	}
+	if s.IncludeGlobal {
+		return []string{s.WorkspaceID, ""}
+	}
+	return []string{s.WorkspaceID}
+}
+
+// SaveMemoryOptions represents options for saving a conversation message
+type SaveMemoryOptions struct {
+	SessionID string
+	UserID    string // Owner of the session
+	ParentID  string // Previous message on the branch, empty for the first message
+	Role      model.MessageRole
+	Content   string
+	Reasoning string       // Model thinking, stored for display only (assistant only)
+	ConfigID  string       // Model config that generated the message (assistant only)
+	Usage     *model.Usage // Token usage of the generating call (assistant only)
+}
+
 // AddKnowledgeOptions represents options for adding knowledge
 type AddKnowledgeOptions struct {
-	Content    string
-	Category   model.KnowledgeCategory
-	Source     model.KnowledgeSource
-	Importance float32
-	Tier       model.KnowledgeTier // Memory tier (mid-term or long-term)
+	Content     string
+	UserID      string
+	WorkspaceID string // Empty for the global pool
+	Category    model.KnowledgeCategory
+	Source      model.KnowledgeSource
+	Importance  float32
+	Tier        model.KnowledgeTier // Memory tier (mid-term or long-term)
 }
 
 // SearchOptions represents options for searching knowledge
 type SearchOptions struct {
 	Query      string
+	UserID     string                    // Owner of the knowledge searched
+	Scope      *Scope                    // Optional: only knowledge in scope, nil for all of the user's workspaces
 	Categories []model.KnowledgeCategory // Optional: filter by categories
 	ActiveOnly bool                      // Only return active (not superseded) knowledge
 	MinScore   float32                   // Minimum similarity score
@@ -0,0 +1,151 @@
+package modelinfo
+
+import (
+	"strings"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+)
+
+// Entry describes a well-known model family
+type Entry struct {
+	Prefix             string // Matched against the lower-cased model ID, longest prefix wins
+	ContextWindow      int
+	Vision             bool
+	Tools              bool
+	JSONMode           bool
+	Reasoning          bool // Accepts reasoning_effort
+	Embedding          bool
+	EmbeddingDimension int
+}
+
+// registry holds models whose properties are not (or not always) reported by the provider
+var registry = []Entry{
+	// OpenAI
+	{Prefix: "gpt-4.1", ContextWindow: 1047576, Vision: true, Tools: true, JSONMode: true},
+	{Prefix: "gpt-4o", ContextWindow: 128000, Vision: true, Tools: true, JSONMode: true},
+	{Prefix: "gpt-4-turbo", ContextWindow: 128000, Vision: true, Tools: true, JSONMode: true},
+	{Prefix: "gpt-4", ContextWindow: 8192, Tools: true},
+	{Prefix: "gpt-3.5-turbo", ContextWindow: 16385, Tools: true, JSONMode: true},
+	{Prefix: "gpt-35-turbo", ContextWindow: 16385, Tools: true, JSONMode: true}, // Azure naming
+	{Prefix: "o1", ContextWindow: 200000, Vision: true, Tools: true, JSONMode: true, Reasoning: true},
+	{Prefix: "o1-mini", ContextWindow: 128000},
+	{Prefix: "o3", ContextWindow: 200000, Vision: true, Tools: true, JSONMode: true, Reasoning: true},
+	{Prefix: "o4-mini", ContextWindow: 200000, Vision: true, Tools: true, JSONMode: true, Reasoning: true},
+	{Prefix: "text-embedding-3-small", ContextWindow: 8191, Embedding: true, EmbeddingDimension: 1536},
+	{Prefix: "text-embedding-3-large", ContextWindow: 8191, Embedding: true, EmbeddingDimension: 3072},
+	{Prefix: "text-embedding-ada-002", ContextWindow: 8191, Embedding: true, EmbeddingDimension: 1536},
+
+	// Anthropic
+	{Prefix: "claude-", ContextWindow: 200000, Vision: true, Tools: true},
+	{Prefix: "claude-2", ContextWindow: 100000},
+	{Prefix: "claude-2.1", ContextWindow: 200000},
+	{Prefix: "claude-instant", ContextWindow: 100000},
+
+	// Google
+	{Prefix: "gemini-1.5-pro", ContextWindow: 2097152, Vision: true, Tools: true, JSONMode: true},
+	{Prefix: "gemini-1.5-flash", ContextWindow: 1048576, Vision: true, Tools: true, JSONMode: true},
+	{Prefix: "gemini-2", ContextWindow: 1048576, Vision: true, Tools: true, JSONMode: true},
+	{Prefix: "text-embedding-004", ContextWindow: 2048, Embedding: true, EmbeddingDimension: 768},
+
+	// Ollama / open models
+	{Prefix: "llama3.1", ContextWindow: 131072, Tools: true, JSONMode: true},
+	{Prefix: "llama3.2", ContextWindow: 131072, Tools: true, JSONMode: true},
+	{Prefix: "llama3.2-vision", ContextWindow: 131072, Vision: true, JSONMode: true},
+	{Prefix: "llama3.3", ContextWindow: 131072, Tools: true, JSONMode: true},
+	{Prefix: "llama3", ContextWindow: 8192, JSONMode: true},
+	{Prefix: "llama2", ContextWindow: 4096, JSONMode: true},
+	{Prefix: "llava", ContextWindow: 4096, Vision: true, JSONMode: true},
+	{Prefix: "qwen2.5", ContextWindow: 32768, Tools: true, JSONMode: true},
+	{Prefix: "qwen3", ContextWindow: 40960, Tools: true, JSONMode: true},
+	{Prefix: "mistral", ContextWindow: 32768, Tools: true, JSONMode: true},
+	{Prefix: "deepseek-chat", ContextWindow: 65536, Tools: true, JSONMode: true},
+	{Prefix: "deepseek-reasoner", ContextWindow: 65536},
+	{Prefix: "deepseek-r1", ContextWindow: 131072, JSONMode: true},
+	{Prefix: "moonshot-v1-8k", ContextWindow: 8192, Tools: true, JSONMode: true},
+	{Prefix: "moonshot-v1-32k", ContextWindow: 32768, Tools: true, JSONMode: true},
+	{Prefix: "moonshot-v1-128k", ContextWindow: 131072, Tools: true, JSONMode: true},
+	{Prefix: "nomic-embed-text", ContextWindow: 8192, Embedding: true, EmbeddingDimension: 768},
+	{Prefix: "mxbai-embed-large", ContextWindow: 512, Embedding: true, EmbeddingDimension: 1024},
+	{Prefix: "bge-m3", ContextWindow: 8192, Embedding: true, EmbeddingDimension: 1024},
+}
+
+// nonChatPrefixes are models listed by providers that serve neither chat nor embeddings
+var nonChatPrefixes = []string{"whisper", "tts", "dall-e", "davinci", "babbage", "omni-moderation", "text-moderation", "gpt-image", "sora"}
+
+// Lookup finds the registry entry for a model ID. Provider prefixes such as
+// "models/" or "library/" and Ollama tags (":latest") are ignored.
+func Lookup(id string) (Entry, bool) {
+	name := normalize(id)
+
+	var best Entry
+	found := false
+	for _, entry := range registry {
+		if strings.HasPrefix(name, entry.Prefix) && len(entry.Prefix) > len(best.Prefix) {
+			best = entry
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Describe returns what is known about a model from the registry and its name
+func Describe(id string) model.ModelInfo {
+	info := model.ModelInfo{ID: id}
+	name := normalize(id)
+
+	if entry, ok := Lookup(id); ok {
+		info.ContextWindow = entry.ContextWindow
+		info.Embedding = entry.Embedding
+	} else {
+		info.Embedding = strings.Contains(name, "embed")
+	}
+
+	info.Chat = !info.Embedding
+	for _, prefix := range nonChatPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			info.Chat = false
+			break
+		}
+	}
+	return info
+}
+
+// Capabilities returns the effective capabilities of a model config: registry defaults
+// for its model, provider-level defaults, then the config's own overrides
+func Capabilities(mc *model.ModelConfig) model.ModelCapabilities {
+	var caps model.ModelCapabilities
+	if entry, ok := Lookup(mc.Model); ok {
+		caps = model.ModelCapabilities{
+			ContextWindow:      entry.ContextWindow,
+			Vision:             entry.Vision,
+			Tools:              entry.Tools,
+			JSONMode:           entry.JSONMode,
+			Reasoning:          entry.Reasoning,
+			EmbeddingDimension: entry.EmbeddingDimension,
+		}
+	}
+
+	if mc.Provider != nil {
+		switch mc.Provider.Type {
+		case model.ProviderTypeOpenAI, model.ProviderTypeClaude, model.ProviderTypeGemini, model.ProviderTypeOllama:
+			caps.StreamUsage = true
+		case model.ProviderTypeCustom:
+			caps.StreamUsage = mc.Provider.Options.Capabilities.StreamUsage
+			caps.Tools = mc.Provider.Options.Capabilities.Tools
+			caps.Reasoning = mc.Provider.Options.Capabilities.Reasoning
+		}
+	}
+
+	return mc.Capabilities.Apply(caps)
+}
+
+func normalize(id string) string {
+	name := strings.ToLower(id)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
@@ -0,0 +1,204 @@
+package prompt
+
+// Built-in prompt names
+const (
+	FactExtraction    = "fact_extraction"    // Variables: .User, .Assistant
+	ConflictDetection = "conflict_detection" // Variables: .Fact, .Existing (list of strings)
+	KnowledgeContext  = "knowledge_context"  // Variables: .Knowledge (list of strings)
+	SummarySystem     = "summary_system"     // No variables
+	SummaryUser       = "summary_user"       // Variables: .Conversation
+)
+
+// Languages of the compiled-in defaults
+const (
+	LanguageChinese = "zh"
+	LanguageEnglish = "en"
+)
+
+// Builtin is a prompt the system uses, with its compiled-in defaults per language
+type Builtin struct {
+	Name        string
+	Description string
+	Language    string            // Original language, used when the configured one has no variant
+	Variants    map[string]string // Language -> template
+}
+
+// IsBuiltin reports whether name is a built-in prompt
+func IsBuiltin(name string) bool {
+	return builtinByName(name) != nil
+}
+
+func builtinByName(name string) *Builtin {
+	for i := range builtins {
+		if builtins[i].Name == name {
+			return &builtins[i]
+		}
+	}
+	return nil
+}
+
+var builtins = []Builtin{
+	{
+		Name:        FactExtraction,
+		Description: "Extracts long-term facts about the user from a conversation turn",
+		Language:    LanguageChinese,
+		Variants: map[string]string{
+			LanguageChinese: `分析以下对话，提取用户透露的**值得长期记忆**的关键信息。
+
+对话:
+用户: {{.User}}
+助手: {{.Assistant}}
+
+请以JSON对象格式返回，facts 字段为提取的事实数组，每个事实包含:
+- content: 事实内容（简洁的陈述句）
+- category: 类别（personal_info=个人信息, preference=偏好, fact=事实, event=事件）
+- importance: 重要性(0-1)
+
+**应该保存的信息（长期记忆）：**
+- 用户的个人信息（姓名、职业、住址等）
+- 用户的长期偏好（喜好、习惯等）
+- 用户的重要背景信息
+
+**不应该保存的信息：**
+- 当前操作的临时细节（如"开启了某模式"、"指定了某参数"）
+- 一次性问题排查的场景描述
+- 临时的技术配置或设置
+- 只在当前对话有意义的上下文
+
+示例输出:
+{
+  "facts": [
+    {"content": "用户名字是张三", "category": "personal_info", "importance": 0.9},
+    {"content": "用户偏好使用Python编程", "category": "preference", "importance": 0.7}
+  ]
+}
+
+如果没有值得**长期记忆**的信息，返回空数组: {"facts": []}
+
+注意：只提取用户明确说出的、具有长期价值的信息，不要推断，不要保存临时操作细节。`,
+			LanguageEnglish: `Analyze the following conversation and extract the key information the user revealed that is **worth remembering long-term**.
+
+Conversation:
+User: {{.User}}
+Assistant: {{.Assistant}}
+
+Return a JSON object whose facts field is an array of extracted facts, each with:
+- content: the fact (a concise statement)
+- category: personal_info, preference, fact or event
+- importance: importance (0-1)
+
+**Save (long-term memory):**
+- The user's personal information (name, occupation, location, ...)
+- The user's lasting preferences (likes, habits, ...)
+- Important background about the user
+
+**Do not save:**
+- Temporary details of the current task (e.g. "turned on some mode", "set some parameter")
+- Descriptions of one-off troubleshooting
+- Temporary technical configuration or settings
+- Context that only matters in this conversation
+
+Example output:
+{
+  "facts": [
+    {"content": "The user's name is John", "category": "personal_info", "importance": 0.9},
+    {"content": "The user prefers programming in Python", "category": "preference", "importance": 0.7}
+  ]
+}
+
+If nothing is worth remembering **long-term**, return an empty array: {"facts": []}
+
+Note: only extract information the user stated explicitly and that has lasting value. Do not infer, and do not save temporary details.`,
+		},
+	},
+	{
+		Name:        ConflictDetection,
+		Description: "Decides whether a new fact duplicates or updates existing knowledge",
+		Language:    LanguageChinese,
+		Variants: map[string]string{
+			LanguageChinese: `判断新信息是否与已有信息冲突或重复。
+
+新信息: {{.Fact}}
+
+已有信息:
+{{range $i, $k := .Existing}}{{$i}}. {{$k}}
+{{end}}
+请回答(JSON格式):
+{
+  "is_duplicate": true/false,  // 是否完全重复
+  "is_conflict": true/false,   // 是否存在冲突(新信息更新了旧信息)
+  "conflict_index": -1         // 冲突的已有信息索引(0开始)，无冲突则为-1
+}
+
+示例:
+- 新"住在上海" vs 旧"住在北京" -> conflict=true
+- 新"喜欢咖啡" vs 旧"喜欢喝咖啡" -> duplicate=true
+- 新"养了一只猫" vs 旧"喜欢运动" -> 都是false`,
+			LanguageEnglish: `Decide whether the new information conflicts with or duplicates existing information.
+
+New information: {{.Fact}}
+
+Existing information:
+{{range $i, $k := .Existing}}{{$i}}. {{$k}}
+{{end}}
+Answer in JSON:
+{
+  "is_duplicate": true/false,  // the new information repeats existing information
+  "is_conflict": true/false,   // the new information updates existing information
+  "conflict_index": -1         // index (from 0) of the conflicting information, -1 if none
+}
+
+Examples:
+- new "lives in Shanghai" vs old "lives in Beijing" -> conflict=true
+- new "likes coffee" vs old "enjoys drinking coffee" -> duplicate=true
+- new "has a cat" vs old "likes sports" -> both false`,
+		},
+	},
+	{
+		Name:        KnowledgeContext,
+		Description: "System message listing the knowledge about the user relevant to the chat",
+		Language:    LanguageChinese,
+		Variants: map[string]string{
+			LanguageChinese: "已知用户信息:\n{{range .Knowledge}}- {{.}}\n{{end}}",
+			LanguageEnglish: "Known information about the user:\n{{range .Knowledge}}- {{.}}\n{{end}}",
+		},
+	},
+	{
+		Name:        SummarySystem,
+		Description: "System instructions for summarizing a conversation",
+		Language:    LanguageEnglish,
+		Variants: map[string]string{
+			LanguageEnglish: `You are a helpful assistant that summarizes conversations.
+Create a concise summary that captures:
+1. Main topics discussed
+2. Key decisions or conclusions
+3. Important information shared
+4. Any action items or follow-ups
+
+Keep the summary brief but comprehensive. Write in a neutral, factual tone.`,
+			LanguageChinese: `你是一个总结对话的助手。
+请写一份简洁的总结，涵盖:
+1. 讨论的主要话题
+2. 关键决定或结论
+3. 分享的重要信息
+4. 待办事项或后续跟进
+
+总结要简短但全面，语气中立、客观。`,
+		},
+	},
+	{
+		Name:        SummaryUser,
+		Description: "Asks for the summary of a conversation",
+		Language:    LanguageEnglish,
+		Variants: map[string]string{
+			LanguageEnglish: "Please summarize the following conversation:\n\n{{.Conversation}}",
+			LanguageChinese: "请总结以下对话:\n\n{{.Conversation}}",
+		},
+	},
+}
+
+// HasDefault reports whether a built-in prompt has a compiled-in default in language
+func HasDefault(name, language string) bool {
+	b := builtinByName(name)
+	return b != nil && b.Variants[language] != ""
+}
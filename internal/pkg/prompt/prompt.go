@@ -0,0 +1,115 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"text/template"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Parse checks that content is a valid Go text/template
+func Parse(content string) (*template.Template, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Render fills in a template's variables
+func Render(content string, data map[string]any) (string, error) {
+	tmpl, err := Parse(content)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// Library renders the built-in prompts from the template store. The active version in
+// the configured language is preferred, then the one in the prompt's original language;
+// the compiled-in default is used if the store has neither or it fails to render.
+type Library struct {
+	repo     *repository.PromptRepository
+	language string
+}
+
+// NewLibrary creates a prompt library. An empty language uses each prompt's original language.
+func NewLibrary(repo *repository.PromptRepository, language string) *Library {
+	return &Library{repo: repo, language: language}
+}
+
+// SeedBuiltins stores the compiled-in defaults as the first version of every built-in
+// prompt and language that is not in the store yet. When a compiled-in default changed,
+// it is stored as a new version of prompts still using an earlier default, i.e. whose
+// latest version is a seeded one and active; edited or rolled back prompts are kept.
+func (l *Library) SeedBuiltins() error {
+	for _, b := range builtins {
+		for language, content := range b.Variants {
+			versions, err := l.repo.GetVersions(b.Name, language)
+			if err != nil {
+				return err
+			}
+			if len(versions) > 0 {
+				latest := versions[0]
+				if !latest.Seeded || !latest.Active || latest.Content == content {
+					continue
+				}
+				log.Printf("[Prompt:Seed] Updating %s (%s) v%d to the new default", b.Name, language, latest.Version)
+			}
+			if err := l.repo.CreateVersion(&model.PromptTemplate{
+				ID:          uuid.New().String(),
+				Name:        b.Name,
+				Language:    language,
+				Kind:        model.PromptKindBuiltin,
+				Description: b.Description,
+				Content:     content,
+				Seeded:      true,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Render renders a built-in prompt with the given variables
+func (l *Library) Render(name string, data map[string]any) (string, error) {
+	b := builtinByName(name)
+	if b == nil {
+		return "", fmt.Errorf("unknown built-in prompt %q", name)
+	}
+
+	for _, language := range []string{l.language, b.Language} {
+		if language == "" || l.repo == nil {
+			continue
+		}
+		stored, err := l.repo.GetActive(name, language)
+		if err != nil {
+			log.Printf("[Prompt:Render] Failed to get %s (%s): %v", name, language, err)
+			break
+		}
+		if stored == nil {
+			continue
+		}
+		rendered, err := Render(stored.Content, data)
+		if err == nil {
+			return rendered, nil
+		}
+		log.Printf("[Prompt:Render] Stored %s (%s) v%d failed, using the default: %v", name, language, stored.Version, err)
+		break
+	}
+
+	content := b.Variants[b.Language]
+	if l.language != "" && b.Variants[l.language] != "" {
+		content = b.Variants[l.language]
+	}
+	return Render(content, data)
+}
@@ -0,0 +1,302 @@
+// Package redact replaces personal information in text with placeholders before it is
+// sent to a cloud LLM provider, and restores the original values in the response.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Type is a kind of personal information
+type Type string
+
+const (
+	TypeEmail    Type = "email"
+	TypeIDCard   Type = "id_card"   // Chinese resident ID number
+	TypeBankCard Type = "bank_card" // Payment card number (Luhn checked)
+	TypePhone    Type = "phone"     // Chinese mobile and landline, international numbers
+	TypeAddress  Type = "address"   // Street addresses
+)
+
+// AllTypes lists the supported types in the order they are detected. Longer digit
+// sequences come first so that an ID number is not taken for a phone number.
+var AllTypes = []Type{TypeEmail, TypeIDCard, TypeBankCard, TypePhone, TypeAddress}
+
+// detector finds the values of one type
+type detector struct {
+	typ     Type
+	pattern *regexp.Regexp
+	digits  bool              // Matches must not be part of a longer digit sequence
+	valid   func(string) bool // Optional check of a match
+	trim    func(string) int  // Optional number of leading bytes that are not part of the value
+}
+
+var detectors = map[Type]detector{
+	TypeEmail: {
+		typ:     TypeEmail,
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	TypeIDCard: {
+		typ:     TypeIDCard,
+		pattern: regexp.MustCompile(`[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]`),
+		digits:  true,
+		valid:   validIDCard,
+	},
+	TypeBankCard: {
+		typ:     TypeBankCard,
+		pattern: regexp.MustCompile(`[1-9]\d{3}(?:[ \-]?\d{4}){3}(?:[ \-]?\d{1,3})?`), // 16-19 digits, also grouped 4-4-4-4-3
+		digits:  true,
+		valid:   validLuhn,
+	},
+	TypePhone: {
+		typ: TypePhone,
+		pattern: regexp.MustCompile(`(?:\+?86[ \-]?)?1[3-9]\d(?:[ \-]?\d{4}){2}` + // Chinese mobile
+			`|0\d{2,3}-\d{7,8}` + // Chinese landline
+			`|\+[1-9]\d{0,2}[ \-]?\(?\d{1,4}\)?(?:[ \-]?\d{2,4}){2,4}`), // International
+		digits: true,
+	},
+	TypeAddress: {
+		typ: TypeAddress,
+		pattern: regexp.MustCompile(`[\p{Han}\d]{2,24}(?:路|街|大道|巷|弄|胡同)[\d\-]+号(?:[\p{Han}\d\-]{0,12}?(?:栋|幢|座|楼|单元|层|室))*` +
+			`|\d{1,6}\s+(?:[A-Z][a-z]+\s+){1,4}(?:Street|St|Avenue|Ave|Road|Rd|Boulevard|Blvd|Lane|Ln|Drive|Dr|Court|Ct|Way)\b\.?`),
+		trim: trimAddressPrefix,
+	},
+}
+
+// placeholderPattern matches the placeholders of Redact, e.g. [PHONE_1]
+var placeholderPattern = regexp.MustCompile(`\[(?:EMAIL|ID_CARD|BANK_CARD|PHONE|ADDRESS)_\d+\]`)
+
+// maxPlaceholderLen bounds the length of a placeholder, used to hold back a partial
+// placeholder at the end of a streamed delta
+const maxPlaceholderLen = 24
+
+// Redactor detects personal information of the configured types
+type Redactor struct {
+	detectors []detector
+}
+
+// New creates a redactor for the given types, or all types if none are given
+func New(types []string) (*Redactor, error) {
+	r := &Redactor{}
+	if len(types) == 0 {
+		for _, t := range AllTypes {
+			r.detectors = append(r.detectors, detectors[t])
+		}
+		return r, nil
+	}
+
+	enabled := make(map[Type]bool)
+	for _, t := range types {
+		if _, ok := detectors[Type(t)]; !ok {
+			return nil, fmt.Errorf("unknown redaction type %q", t)
+		}
+		enabled[Type(t)] = true
+	}
+	for _, t := range AllTypes {
+		if enabled[t] {
+			r.detectors = append(r.detectors, detectors[t])
+		}
+	}
+	return r, nil
+}
+
+// NewSession starts a session mapping placeholders to values. A session covers one
+// request and its response, so that the response can refer to any redacted value.
+func (r *Redactor) NewSession() *Session {
+	return &Session{
+		redactor: r,
+		byValue:  make(map[string]string),
+		values:   make(map[string]string),
+		counts:   make(map[Type]int),
+	}
+}
+
+// Session redacts the texts of one request and restores them in its response
+type Session struct {
+	redactor *Redactor
+	byValue  map[string]string // Placeholder of each redacted value
+	values   map[string]string // Value of each placeholder
+	counts   map[Type]int
+}
+
+// Redact replaces the personal information in text with placeholders. The same value
+// always gets the same placeholder within a session.
+func (s *Session) Redact(text string) string {
+	for _, d := range s.redactor.detectors {
+		text = s.redactType(text, d)
+	}
+	return text
+}
+
+func (s *Session) redactType(text string, d detector) string {
+	matches := d.pattern.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if d.digits && (isDigitBefore(text, start) || isDigitAfter(text, end)) {
+			continue
+		}
+		if d.trim != nil {
+			start += d.trim(text[start:end])
+		}
+		value := text[start:end]
+		if d.valid != nil && !d.valid(value) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(s.placeholder(d.typ, value))
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// placeholder returns the placeholder of a value, creating it on first use
+func (s *Session) placeholder(typ Type, value string) string {
+	if p, ok := s.byValue[value]; ok {
+		return p
+	}
+	s.counts[typ]++
+	p := "[" + strings.ToUpper(string(typ)) + "_" + strconv.Itoa(s.counts[typ]) + "]"
+	s.byValue[value] = p
+	s.values[p] = value
+	return p
+}
+
+// Len returns the number of distinct values redacted so far
+func (s *Session) Len() int {
+	return len(s.values)
+}
+
+// Restore replaces the placeholders of this session in text with their values.
+// Unknown placeholders are left as is.
+func (s *Session) Restore(text string) string {
+	if len(s.values) == 0 {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(p string) string {
+		if value, ok := s.values[p]; ok {
+			return value
+		}
+		return p
+	})
+}
+
+// StreamRestorer restores placeholders in a streamed text whose deltas may split a
+// placeholder
+type StreamRestorer struct {
+	session *Session
+	pending string
+}
+
+// NewStreamRestorer creates a restorer for one streamed text of the session's response
+func (s *Session) NewStreamRestorer() *StreamRestorer {
+	return &StreamRestorer{session: s}
+}
+
+// Write adds a delta and returns the restored text that is complete so far. A trailing
+// partial placeholder is held back until the next delta.
+func (r *StreamRestorer) Write(delta string) string {
+	text := r.pending + delta
+	r.pending = ""
+	if len(r.session.values) == 0 {
+		return text
+	}
+
+	if i := strings.LastIndexByte(text, '['); i >= 0 && !strings.Contains(text[i:], "]") && len(text)-i < maxPlaceholderLen {
+		text, r.pending = text[:i], text[i:]
+	}
+	return r.session.Restore(text)
+}
+
+// Flush returns the text held back at the end of the stream
+func (r *StreamRestorer) Flush() string {
+	text := r.pending
+	r.pending = ""
+	return r.session.Restore(text)
+}
+
+func isDigitBefore(text string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	c, _ := utf8.DecodeLastRuneInString(text[:i])
+	return c >= '0' && c <= '9'
+}
+
+func isDigitAfter(text string, i int) bool {
+	if i >= len(text) {
+		return false
+	}
+	c := text[i]
+	return c >= '0' && c <= '9'
+}
+
+// idCardWeights are the checksum weights of the first 17 digits of a resident ID number
+var idCardWeights = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+
+// validIDCard checks the GB 11643 checksum of a resident ID number
+func validIDCard(id string) bool {
+	sum := 0
+	for i, w := range idCardWeights {
+		sum += int(id[i]-'0') * w
+	}
+	check := "10X98765432"[sum%11]
+	last := id[17]
+	if last == 'x' {
+		last = 'X'
+	}
+	return last == check
+}
+
+// validLuhn checks the Luhn checksum of a card number, ignoring separators
+func validLuhn(number string) bool {
+	sum, n := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// addressPrefixes introduce an address in a sentence, e.g. 我住在北京市...
+var addressPrefixes = []string{"住在", "住址是", "地址是", "地址为", "地址：", "地址:", "位于", "寄到", "送到", "在", "于", "是"}
+
+// trimAddressPrefix returns the length of the words before the address itself, which
+// the address pattern cannot tell apart from its first characters
+func trimAddressPrefix(match string) int {
+	// Only look before the first street or administrative unit
+	end := len(match)
+	for _, unit := range []string{"省", "市", "区", "县", "路", "街", "大道"} {
+		if i := strings.Index(match, unit); i >= 0 && i < end {
+			end = i
+		}
+	}
+
+	cut := 0
+	for _, prefix := range addressPrefixes {
+		if i := strings.LastIndex(match[:end], prefix); i >= 0 && i+len(prefix) > cut {
+			cut = i + len(prefix)
+		}
+	}
+	return cut
+}
@@ -0,0 +1,116 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		// Resident ID numbers
+		{"id card", "身份证号11010519491231002X。", "身份证号[ID_CARD_1]。"},
+		{"id card lowercase x", "ID 11010519491231002x", "ID [ID_CARD_1]"},
+		{"id card bad checksum", "编号110105194912310021", "编号110105194912310021"},
+		{"id card in longer number", "1101051949123100211", "1101051949123100211"},
+
+		// Phone numbers
+		{"mobile", "手机13812345678", "手机[PHONE_1]"},
+		{"mobile with country code", "call +86 138-1234-5678 now", "call [PHONE_1] now"},
+		{"landline", "电话010-12345678", "电话[PHONE_1]"},
+		{"international", "office +1 415 555 2671", "office [PHONE_1]"},
+		{"mobile in longer number", "订单号213812345678", "订单号213812345678"},
+
+		// Bank cards
+		{"bank card", "卡号6222020200112345679", "卡号[BANK_CARD_1]"},
+		{"bank card grouped", "card 6222 0202 0011 2345 679", "card [BANK_CARD_1]"},
+		{"bank card bad checksum", "卡号6222020200112345678", "卡号6222020200112345678"},
+
+		// Email
+		{"email", "mail zhang.san@example.com", "mail [EMAIL_1]"},
+
+		// Addresses
+		{"cn address", "我住在北京市朝阳区建国路88号3号楼501室，欢迎来玩", "我住在[ADDRESS_1]，欢迎来玩"},
+		{"cn address prefix", "地址是上海市徐汇区漕溪北路100号", "地址是[ADDRESS_1]"},
+		{"cn road without number", "沿着建国路走", "沿着建国路走"},
+		{"en address", "I live at 221 Baker Street, London", "I live at [ADDRESS_1], London"},
+
+		// Repeated and mixed values
+		{"same value same placeholder", "13812345678 or 13812345678", "[PHONE_1] or [PHONE_1]"},
+		{"numbered per type", "13812345678, 13987654321, a@b.cn", "[PHONE_1], [PHONE_2], [EMAIL_1]"},
+	}
+
+	r, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := r.NewSession()
+			got := session.Redact(tt.in)
+			if got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if restored := session.Restore(got); restored != tt.in {
+				t.Errorf("Restore(%q) = %q, want %q", got, restored, tt.in)
+			}
+		})
+	}
+}
+
+func TestNewTypes(t *testing.T) {
+	r, err := New([]string{"email"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := "a@b.cn 13812345678"
+	if got := r.NewSession().Redact(in); got != "[EMAIL_1] 13812345678" {
+		t.Errorf("Redact(%q) = %q, want only the email replaced", in, got)
+	}
+
+	if _, err := New([]string{"passport"}); err == nil {
+		t.Error("New accepted an unknown type")
+	}
+}
+
+func TestStreamRestorer(t *testing.T) {
+	tests := []struct {
+		name   string
+		deltas []string
+	}{
+		{"whole placeholder", []string{"Call ", "[PHONE_1]", " today"}},
+		{"split placeholder", []string{"Call [PH", "ONE_", "1] today"}},
+		{"split at bracket", []string{"Call [", "PHONE_1] today"}},
+		{"split placeholder at end", []string{"Call [PHONE", "_1]"}},
+		{"unknown placeholder", []string{"Call [PHONE_", "9] today"}},
+		{"plain bracket", []string{"Call [", "me] today"}},
+		{"unclosed bracket", []string{"Call [me today"}},
+	}
+
+	r, _ := New(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := r.NewSession()
+			session.Redact("13812345678")
+			restorer := session.NewStreamRestorer()
+
+			var got strings.Builder
+			for _, delta := range tt.deltas {
+				out := restorer.Write(delta)
+				if strings.Contains(out, "[PH") && !strings.Contains(out, "[PHONE_9]") {
+					t.Errorf("Write(%q) = %q, leaked a partial placeholder", delta, out)
+				}
+				got.WriteString(out)
+			}
+			got.WriteString(restorer.Flush())
+
+			want := session.Restore(strings.Join(tt.deltas, ""))
+			if got.String() != want {
+				t.Errorf("restored %q, want %q", got.String(), want)
+			}
+		})
+	}
+}
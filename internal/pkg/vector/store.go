@@ -6,19 +6,23 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 )
 
 // DocumentMetadata represents structured metadata for a document
 type DocumentMetadata struct {
-	SessionID  string  `json:"session_id"`
-	Role       string  `json:"role"`
-	Category   string  `json:"category"`
-	Source     string  `json:"source"`
-	Importance float32 `json:"importance"`
-	IsActive   bool    `json:"is_active"`
-	CreatedAt  int64   `json:"created_at"`
+	SessionID   string  `json:"session_id"`
+	UserID      string  `json:"user_id,omitempty"`      // Owner
+	WorkspaceID string  `json:"workspace_id,omitempty"` // Empty for the global pool
+	Role        string  `json:"role"`
+	Category    string  `json:"category"`
+	Source      string  `json:"source"`
+	Importance  float32 `json:"importance"`
+	IsActive    bool    `json:"is_active"`
+	CreatedAt   int64   `json:"created_at"`
 }
 
 // Document represents a document with its embedding
@@ -37,24 +41,46 @@ type SearchResult struct {
 
 // SearchFilter represents advanced search filter options
 type SearchFilter struct {
-	SessionID  string
-	Categories []string
-	ActiveOnly bool
-	MinScore   float32
+	SessionID    string
+	UserID       *string  // Only documents of this user, nil for all
+	WorkspaceIDs []string // Only documents in one of these workspaces ("" = global pool), nil for all
+	Categories   []string
+	ActiveOnly   bool
+	MinScore     float32
 }
 
+// Cipher encrypts the persisted store
+type Cipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
+
+// encryptedPrefix starts a store file written by a Cipher, a JSON file never does
+const encryptedPrefix = "v1:"
+
 // VectorStore is an in-memory vector store with persistence
 type VectorStore struct {
 	documents map[string]Document
 	mutex     sync.RWMutex
 	path      string
+	cipher    Cipher
+	encrypt   bool
 }
 
 // NewVectorStore creates a new vector store
 func NewVectorStore(path string) (*VectorStore, error) {
+	return NewVectorStoreWithCipher(path, nil, false)
+}
+
+// NewVectorStoreWithCipher creates a new vector store whose file is encrypted with the
+// cipher if encrypt is set. An encrypted file is read regardless of encrypt, and a
+// plaintext file is encrypted right away when encrypt is set.
+func NewVectorStoreWithCipher(path string, cipher Cipher, encrypt bool) (*VectorStore, error) {
 	store := &VectorStore{
 		documents: make(map[string]Document),
 		path:      path,
+		cipher:    cipher,
+		encrypt:   encrypt && cipher != nil,
 	}
 
 	// Ensure directory exists
@@ -65,19 +91,128 @@ func NewVectorStore(path string) (*VectorStore, error) {
 		}
 
 		// Load existing data if available
-		if err := store.load(); err != nil && !os.IsNotExist(err) {
+		encrypted, err := store.load()
+		if err != nil && !os.IsNotExist(err) {
 			return nil, fmt.Errorf("failed to load data: %w", err)
 		}
+		if err == nil && store.encrypt && !encrypted {
+			if err := store.save(); err != nil {
+				return nil, fmt.Errorf("failed to encrypt data: %w", err)
+			}
+		}
 	}
 
 	return store, nil
 }
 
+// Rewrite persists the store again, e.g. to re-encrypt it with a new key
+func (s *VectorStore) Rewrite() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.save()
+}
+
+// Snapshot returns the documents as unencrypted JSON, the format of a plaintext store file
+func (s *VectorStore) Snapshot() ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return json.Marshal(s.documents)
+}
+
+// Restore replaces all documents with those of a Snapshot and persists them
+func (s *VectorStore) Restore(data []byte) error {
+	staged, err := s.StageRestore(data)
+	if err != nil {
+		return err
+	}
+	if err := staged.Commit(); err != nil {
+		staged.Discard()
+		return err
+	}
+	return nil
+}
+
+// ReplaceAll replaces all documents, e.g. with embeddings of a new model, and persists
+// them. The documents must share one dimension.
+func (s *VectorStore) ReplaceAll(docs []Document) error {
+	documents := make(map[string]Document, len(docs))
+	for _, doc := range docs {
+		if len(doc.Embedding) != len(docs[0].Embedding) {
+			return fmt.Errorf("embedding dimension mismatch for %s: got %d, expected %d", doc.ID, len(doc.Embedding), len(docs[0].Embedding))
+		}
+		documents[doc.ID] = doc
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	previous := s.documents
+	s.documents = documents
+	if err := s.save(); err != nil {
+		s.documents = previous
+		return err
+	}
+	return nil
+}
+
+// StagedRestore is a Snapshot written next to the store file, ready to replace the store
+type StagedRestore struct {
+	store     *VectorStore
+	documents map[string]Document
+	path      string // Staged file, empty for a store without a file
+}
+
+// StageRestore parses a Snapshot and writes it next to the store file without changing
+// the store, so that replacing the store with Commit cannot fail halfway
+func (s *VectorStore) StageRestore(data []byte) (*StagedRestore, error) {
+	documents := make(map[string]Document)
+	if err := json.Unmarshal(data, &documents); err != nil {
+		return nil, fmt.Errorf("failed to parse documents: %w", err)
+	}
+
+	staged := &StagedRestore{store: s, documents: documents}
+	if s.path == "" {
+		return staged, nil
+	}
+	encoded, err := s.encode(documents)
+	if err != nil {
+		return nil, err
+	}
+	staged.path = s.path + ".restore"
+	if err := os.WriteFile(staged.path, encoded, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+	return staged, nil
+}
+
+// Commit replaces the documents and the file of the store with the staged ones
+func (r *StagedRestore) Commit() error {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+
+	if r.path != "" {
+		if err := os.Rename(r.path, r.store.path); err != nil {
+			return fmt.Errorf("failed to replace file: %w", err)
+		}
+	}
+	r.store.documents = r.documents
+	return nil
+}
+
+// Discard removes the staged file, leaving the store unchanged
+func (r *StagedRestore) Discard() {
+	if r.path != "" {
+		os.Remove(r.path)
+	}
+}
+
 // Add adds a document to the store
 func (s *VectorStore) Add(doc Document) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	if err := s.checkDimension(doc); err != nil {
+		return err
+	}
 	s.documents[doc.ID] = doc
 
 	// Persist to disk
@@ -89,6 +224,11 @@ func (s *VectorStore) AddBatch(docs []Document) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	for _, doc := range docs {
+		if err := s.checkDimension(doc); err != nil {
+			return err
+		}
+	}
 	for _, doc := range docs {
 		s.documents[doc.ID] = doc
 	}
@@ -132,6 +272,26 @@ func (s *VectorStore) Search(queryEmbedding []float32, limit int, filter *Search
 				continue
 			}
 
+			if filter.UserID != nil {
+				userID := ""
+				if doc.MetaData != nil {
+					userID = doc.MetaData.UserID
+				}
+				if userID != *filter.UserID {
+					continue
+				}
+			}
+
+			if filter.WorkspaceIDs != nil {
+				workspaceID := ""
+				if doc.MetaData != nil {
+					workspaceID = doc.MetaData.WorkspaceID
+				}
+				if !slices.Contains(filter.WorkspaceIDs, workspaceID) {
+					continue
+				}
+			}
+
 			if len(filter.Categories) > 0 {
 				if doc.MetaData == nil {
 					continue
@@ -189,6 +349,33 @@ func (s *VectorStore) UpdateMetadata(id string, metadata *DocumentMetadata) erro
 	return s.save()
 }
 
+// Dimension returns the embedding dimension of the stored documents, 0 if empty
+func (s *VectorStore) Dimension() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.dimensionLocked()
+}
+
+func (s *VectorStore) dimensionLocked() int {
+	for _, doc := range s.documents {
+		return len(doc.Embedding)
+	}
+	return 0
+}
+
+// checkDimension rejects embeddings whose dimension differs from the stored ones,
+// which would otherwise never match in cosine similarity
+func (s *VectorStore) checkDimension(doc Document) error {
+	dim := s.dimensionLocked()
+	if dim == 0 || len(doc.Embedding) == dim {
+		return nil
+	}
+	if _, exists := s.documents[doc.ID]; exists && len(s.documents) == 1 {
+		return nil // Replacing the only document
+	}
+	return fmt.Errorf("embedding dimension mismatch for %s: got %d, store has %d", doc.ID, len(doc.Embedding), dim)
+}
+
 // Count returns the number of documents
 func (s *VectorStore) Count() int {
 	s.mutex.RLock()
@@ -202,9 +389,9 @@ func (s *VectorStore) save() error {
 		return nil
 	}
 
-	data, err := json.Marshal(s.documents)
+	data, err := s.encode(s.documents)
 	if err != nil {
-		return fmt.Errorf("failed to marshal data: %w", err)
+		return err
 	}
 
 	if err := os.WriteFile(s.path, data, 0644); err != nil {
@@ -214,22 +401,51 @@ func (s *VectorStore) save() error {
 	return nil
 }
 
-// load loads the store from disk
-func (s *VectorStore) load() error {
+// encode returns the content of a store file holding the documents
+func (s *VectorStore) encode(documents map[string]Document) ([]byte, error) {
+	data, err := json.Marshal(documents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data: %w", err)
+	}
+
+	if s.encrypt {
+		encrypted, err := s.cipher.Encrypt(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt data: %w", err)
+		}
+		data = []byte(encrypted)
+	}
+	return data, nil
+}
+
+// load loads the store from disk and reports whether the file was encrypted
+func (s *VectorStore) load() (bool, error) {
 	if s.path == "" {
-		return nil
+		return false, nil
 	}
 
 	data, err := os.ReadFile(s.path)
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	encrypted := strings.HasPrefix(string(data), encryptedPrefix)
+	if encrypted {
+		if s.cipher == nil {
+			return true, fmt.Errorf("vector store is encrypted but no encryption key is configured")
+		}
+		plaintext, err := s.cipher.Decrypt(string(data))
+		if err != nil {
+			return true, fmt.Errorf("failed to decrypt data: %w", err)
+		}
+		data = []byte(plaintext)
 	}
 
 	if err := json.Unmarshal(data, &s.documents); err != nil {
-		return fmt.Errorf("failed to unmarshal data: %w", err)
+		return encrypted, fmt.Errorf("failed to unmarshal data: %w", err)
 	}
 
-	return nil
+	return encrypted, nil
 }
 
 // cosineSimilarity calculates the cosine similarity between two vectors
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"gorm.io/gorm"
+)
+
+// AccessTokenRepository handles personal access token persistence
+type AccessTokenRepository struct {
+	db *DB
+}
+
+// NewAccessTokenRepository creates a new access token repository
+func NewAccessTokenRepository(db *DB) *AccessTokenRepository {
+	return &AccessTokenRepository{db: db}
+}
+
+// Create creates a new access token
+func (r *AccessTokenRepository) Create(token *model.AccessToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetAll retrieves all access tokens of a user, newest first
+func (r *AccessTokenRepository) GetAll(userID string) ([]model.AccessToken, error) {
+	var tokens []model.AccessToken
+	if err := r.db.Scopes(ownedBy(userID)).Order("created_at desc").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// GetByHash retrieves an unexpired access token by token hash
+func (r *AccessTokenRepository) GetByHash(tokenHash string) (*model.AccessToken, error) {
+	var token model.AccessToken
+	err := r.db.Where("token_hash = ?", tokenHash).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Touch records the last use of an access token
+func (r *AccessTokenRepository) Touch(id string, usedAt time.Time) error {
+	return r.db.Model(&model.AccessToken{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+}
+
+// Delete deletes an access token of a user, returning false if there was none
+func (r *AccessTokenRepository) Delete(userID, id string) (bool, error) {
+	result := r.db.Scopes(ownedBy(userID)).Delete(&model.AccessToken{}, "id = ?", id)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Snapshot writes a consistent copy of the database to path while it is in use
+func (db *DB) Snapshot(path string) error {
+	if err := db.Exec("VACUUM INTO ?", path).Error; err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	return nil
+}
+
+// RestoreFrom replaces the contents of every table with those of the database file at
+// path in a single transaction. The file must have the current schema, e.g. after
+// NewDB migrated it. Columns missing from the file keep their defaults. beforeCommit,
+// if given, runs last in the transaction; an error from it rolls the restore back.
+func (db *DB) RestoreFrom(path string, beforeCommit func() error) error {
+	// ATTACH applies to one connection, so keep the whole restore on it
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("ATTACH DATABASE ? AS backup", path).Error; err != nil {
+			return fmt.Errorf("failed to attach backup: %w", err)
+		}
+		defer conn.Exec("DETACH DATABASE backup")
+
+		var tables []string
+		if err := conn.Raw("SELECT name FROM main.sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").
+			Scan(&tables).Error; err != nil {
+			return fmt.Errorf("failed to list tables: %w", err)
+		}
+
+		return conn.Transaction(func(tx *gorm.DB) error {
+			for _, table := range tables {
+				columns, err := commonColumns(tx, table)
+				if err != nil {
+					return err
+				}
+				if err := tx.Exec(`DELETE FROM main."` + table + `"`).Error; err != nil {
+					return fmt.Errorf("failed to clear %s: %w", table, err)
+				}
+				if len(columns) == 0 {
+					continue
+				}
+				list := `"` + strings.Join(columns, `", "`) + `"`
+				err = tx.Exec(`INSERT INTO main."` + table + `" (` + list + `) SELECT ` + list + ` FROM backup."` + table + `"`).Error
+				if err != nil {
+					return fmt.Errorf("failed to restore %s: %w", table, err)
+				}
+			}
+			if beforeCommit != nil {
+				return beforeCommit()
+			}
+			return nil
+		})
+	})
+}
+
+// commonColumns returns the columns of a table present in both the main and the
+// attached backup database, none if the backup lacks the table
+func commonColumns(tx *gorm.DB, table string) ([]string, error) {
+	var backupColumns []string
+	if err := tx.Raw("SELECT name FROM pragma_table_info(?, 'backup')", table).Scan(&backupColumns).Error; err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	inBackup := make(map[string]bool, len(backupColumns))
+	for _, c := range backupColumns {
+		inBackup[c] = true
+	}
+
+	var mainColumns []string
+	if err := tx.Raw("SELECT name FROM pragma_table_info(?, 'main')", table).Scan(&mainColumns).Error; err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	var columns []string
+	for _, c := range mainColumns {
+		if inBackup[c] {
+			columns = append(columns, c)
+		}
+	}
+	return columns, nil
+}
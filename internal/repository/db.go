@@ -39,13 +39,101 @@ func NewDB(dbPath string) (*DB, error) {
 		&model.Memory{},
 		&model.Knowledge{},
 		&model.SystemConfig{},
+		&model.UsageRecord{},
+		&model.Persona{},
+		&model.PromptTemplate{},
+		&model.Workspace{},
+		&model.User{},
+		&model.AuthToken{},
+		&model.AccessToken{},
 	); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	if err := backfillScopeColumns(db); err != nil {
+		return nil, fmt.Errorf("failed to backfill scope columns: %w", err)
+	}
+
+	if err := backfillConversationTree(db); err != nil {
+		return nil, fmt.Errorf("failed to backfill conversation tree: %w", err)
+	}
+
+	if err := backfillSeededPrompts(db); err != nil {
+		return nil, fmt.Errorf("failed to backfill seeded prompts: %w", err)
+	}
+
 	return &DB{DB: db}, nil
 }
 
+// backfillConversationTree links the messages of sessions created before conversation
+// branching into a single chain by creation time and marks the last one as active.
+// Those messages have no parent_id at all, so each session is only linked once and
+// the branches of later sessions are left alone.
+func backfillConversationTree(db *gorm.DB) error {
+	var sessionIDs []string
+	if err := db.Model(&model.Memory{}).
+		Distinct("session_id").
+		Where("parent_id IS NULL").
+		Pluck("session_id", &sessionIDs).Error; err != nil {
+		return err
+	}
+
+	for _, sessionID := range sessionIDs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var memories []model.Memory
+			if err := tx.Where("session_id = ?", sessionID).Order("created_at asc").Find(&memories).Error; err != nil {
+				return err
+			}
+
+			parentID := ""
+			for _, m := range memories {
+				if err := tx.Model(&model.Memory{}).Where("id = ?", m.ID).UpdateColumn("parent_id", parentID).Error; err != nil {
+					return err
+				}
+				parentID = m.ID
+			}
+			return tx.Model(&model.Session{}).Where("id = ?", sessionID).UpdateColumn("active_leaf_id", parentID).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillSeededPrompts marks the prompt versions stored before seeded defaults were
+// tracked. Only seeding creates the first version of a built-in prompt's language, and
+// rows added since have the column set, so this runs once.
+func backfillSeededPrompts(db *gorm.DB) error {
+	return db.Model(&model.PromptTemplate{}).
+		Where("seeded IS NULL").
+		UpdateColumn("seeded", gorm.Expr("kind = ? AND version = 1", model.PromptKindBuiltin)).Error
+}
+
+// backfillScopeColumns sets the owner and workspace columns added by migrations to empty
+// on existing rows, so that they match the queries for the global pool and the empty
+// user instead of being NULL
+func backfillScopeColumns(db *gorm.DB) error {
+	columns := []struct {
+		model  any
+		column string
+	}{
+		{&model.Session{}, "user_id"},
+		{&model.Session{}, "workspace_id"},
+		{&model.Memory{}, "user_id"},
+		{&model.Knowledge{}, "user_id"},
+		{&model.Knowledge{}, "workspace_id"},
+		{&model.Provider{}, "user_id"},
+		{&model.Workspace{}, "user_id"},
+	}
+	for _, c := range columns {
+		if err := db.Model(c.model).Where(c.column+" IS NULL").UpdateColumn(c.column, "").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close closes the database connection
 func (db *DB) Close() error {
 	sqlDB, err := db.DB.DB()
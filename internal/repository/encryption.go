@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/allwaysyou/llm-agent/internal/pkg/crypto"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// contentEncryption encrypts the columns tagged with serializer:encrypted
+var contentEncryption struct {
+	encryptor *crypto.Encryptor
+	enabled   bool
+}
+
+// encryptedColumns lists the tables and columns holding encrypted content
+var encryptedColumns = []struct {
+	table  string
+	column string
+}{
+	{"memories", "content"},
+	{"memories", "reasoning"},
+	{"knowledges", "content"},
+	{"sessions", "summary"},
+}
+
+func init() {
+	schema.RegisterSerializer("encrypted", encryptedSerializer{})
+}
+
+// SetContentEncryption sets the encryptor of message, knowledge and summary content.
+// With enabled false new content is stored as plaintext, but encrypted content is still
+// decrypted. It must be called before NewDB.
+func SetContentEncryption(encryptor *crypto.Encryptor, enabled bool) {
+	contentEncryption.encryptor = encryptor
+	contentEncryption.enabled = enabled
+}
+
+// encryptedSerializer transparently encrypts a string column at rest. Plaintext values,
+// stored before encryption was enabled, are read as is. Plaintext that looks like a
+// ciphertext is always stored encrypted, so a stored ciphertext-like value never is
+// plaintext.
+type encryptedSerializer struct{}
+
+// Scan decrypts a column value into the field
+func (encryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported type %T for encrypted field %s", dbValue, field.Name)
+	}
+
+	if crypto.IsCiphertext(value) {
+		if contentEncryption.encryptor == nil {
+			return errors.New("encrypted content found but no encryption key configured")
+		}
+		plaintext, err := contentEncryption.encryptor.Decrypt(value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", field.Name, err)
+		}
+		value = plaintext
+	}
+
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+// Value encrypts a field value when at-rest encryption is enabled or the value would
+// otherwise be read back as a ciphertext
+func (encryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue any) (any, error) {
+	value, _ := fieldValue.(string)
+	if value == "" || (!contentEncryption.enabled && !crypto.IsCiphertext(value)) {
+		return value, nil
+	}
+	if contentEncryption.encryptor == nil {
+		if contentEncryption.enabled {
+			return value, nil
+		}
+		return nil, fmt.Errorf("cannot store %s: content looks encrypted but no encryption key is configured", field.Name)
+	}
+	return contentEncryption.encryptor.Encrypt(value)
+}
+
+// EncryptContent brings the encrypted columns in line with the settings: with encryption
+// enabled plaintext values are encrypted, and with rotate values encrypted with a
+// previous key are re-encrypted with the current one. It returns the number of values
+// rewritten.
+func (d *DB) EncryptContent(rotate bool) (int, error) {
+	encryptor := contentEncryption.encryptor
+	if encryptor == nil {
+		return 0, nil
+	}
+
+	rewritten := 0
+	for _, c := range encryptedColumns {
+		n, err := rewriteColumn(d.DB, c.table, c.column, func(value string) (string, bool, error) {
+			plaintext := value
+			if crypto.IsCiphertext(value) {
+				if !rotate || !encryptor.NeedsRotation(value) {
+					return "", false, nil
+				}
+				var err error
+				if plaintext, err = encryptor.Decrypt(value); err != nil {
+					return "", false, err
+				}
+			} else if !contentEncryption.enabled {
+				return "", false, nil
+			}
+
+			encrypted, err := encryptor.Encrypt(plaintext)
+			return encrypted, err == nil, err
+		})
+		rewritten += n
+		if err != nil {
+			return rewritten, err
+		}
+	}
+	return rewritten, nil
+}
+
+// RecryptSnapshot re-encrypts the secrets of a database file that is not in use, e.g.
+// a backup: provider API keys are decrypted with from and encrypted with to, and
+// encrypted content is moved to the to key if atRest is set, or decrypted otherwise.
+// Columns missing from an older database are skipped.
+func RecryptSnapshot(path string, from, to *crypto.Encryptor, atRest bool) error {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	columns := append([]struct {
+		table  string
+		column string
+	}{{"providers", "api_key"}}, encryptedColumns...)
+	for _, c := range columns {
+		if !db.Migrator().HasColumn(c.table, c.column) {
+			continue
+		}
+		secret := c.table == "providers"
+		_, err := rewriteColumn(db, c.table, c.column, func(value string) (string, bool, error) {
+			plaintext := value
+			if secret || crypto.IsCiphertext(value) {
+				var err error
+				if plaintext, err = from.Decrypt(value); err != nil {
+					return "", false, err
+				}
+			}
+			// Plaintext that looks like a ciphertext stays encrypted, as Value would store it
+			if !secret && !atRest && !crypto.IsCiphertext(plaintext) {
+				return plaintext, plaintext != value, nil
+			}
+			encrypted, err := to.Encrypt(plaintext)
+			return encrypted, err == nil, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteColumn pages through the non-empty values of a column and replaces those for
+// which fn returns a new value. It returns the number of values rewritten.
+func rewriteColumn(db *gorm.DB, table, column string, fn func(value string) (string, bool, error)) (int, error) {
+	rewritten := 0
+	lastID := ""
+	for {
+		var rows []struct {
+			ID    string
+			Value string
+		}
+		err := db.Table(table).
+			Select("id, "+column+" AS value").
+			Where("id > ? AND "+column+" IS NOT NULL AND "+column+" != ''", lastID).
+			Order("id").Limit(500).
+			Scan(&rows).Error
+		if err != nil {
+			return rewritten, fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+		}
+		if len(rows) == 0 {
+			return rewritten, nil
+		}
+		lastID = rows[len(rows)-1].ID
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, row := range rows {
+				value, ok, err := fn(row.Value)
+				if err != nil {
+					return fmt.Errorf("failed to re-encrypt %s.%s of %s: %w", table, column, row.ID, err)
+				}
+				if !ok {
+					continue
+				}
+				if err := tx.Table(table).Where("id = ?", row.ID).UpdateColumn(column, value).Error; err != nil {
+					return err
+				}
+				rewritten++
+			}
+			return nil
+		})
+		if err != nil {
+			return rewritten, err
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/crypto"
+)
+
+func TestEncryptedSerializerCiphertextLookalike(t *testing.T) {
+	encryptor, err := crypto.NewEncryptor([]byte(crypto.DefaultKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, atRest := range []bool{false, true} {
+		SetContentEncryption(encryptor, atRest)
+		t.Cleanup(func() { SetContentEncryption(nil, false) })
+
+		db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		repo := NewMemoryRepository(db)
+
+		tests := []struct {
+			name      string
+			content   string
+			encrypted bool // Stored encrypted with at-rest encryption off
+		}{
+			{"plain", "hello", false},
+			{"ciphertext lookalike", "v1:0123abcd:x", true},
+		}
+		for _, tt := range tests {
+			memory := &model.Memory{ID: tt.name, SessionID: "s1", Role: model.RoleUser, Content: tt.content}
+			if err := repo.Create(memory); err != nil {
+				t.Fatalf("at_rest=%v %s: Create: %v", atRest, tt.name, err)
+			}
+
+			got, err := repo.GetByID(memory.ID)
+			if err != nil {
+				t.Fatalf("at_rest=%v %s: GetByID: %v", atRest, tt.name, err)
+			}
+			if got.Content != tt.content {
+				t.Errorf("at_rest=%v %s: content = %q, want %q", atRest, tt.name, got.Content, tt.content)
+			}
+
+			var stored string
+			if err := db.Table("memories").Select("content").Where("id = ?", memory.ID).Scan(&stored).Error; err != nil {
+				t.Fatal(err)
+			}
+			if want := atRest || tt.encrypted; (stored != tt.content) != want {
+				t.Errorf("at_rest=%v %s: stored %q, want encrypted %v", atRest, tt.name, stored, want)
+			}
+		}
+	}
+}
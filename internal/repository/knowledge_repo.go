@@ -23,10 +23,10 @@ func (r *KnowledgeRepository) Create(knowledge *model.Knowledge) error {
 	return r.db.Create(knowledge).Error
 }
 
-// GetByID retrieves a knowledge entry by ID
-func (r *KnowledgeRepository) GetByID(id string) (*model.Knowledge, error) {
+// GetByID retrieves a knowledge entry of a user by ID
+func (r *KnowledgeRepository) GetByID(userID, id string) (*model.Knowledge, error) {
 	var knowledge model.Knowledge
-	if err := r.db.First(&knowledge, "id = ?", id).Error; err != nil {
+	if err := r.db.Scopes(ownedBy(userID)).First(&knowledge, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -40,9 +40,9 @@ func (r *KnowledgeRepository) Update(knowledge *model.Knowledge) error {
 	return r.db.Save(knowledge).Error
 }
 
-// Delete deletes a knowledge entry by ID
-func (r *KnowledgeRepository) Delete(id string) error {
-	return r.db.Delete(&model.Knowledge{}, "id = ?", id).Error
+// Delete deletes a knowledge entry of a user by ID
+func (r *KnowledgeRepository) Delete(userID, id string) error {
+	return r.db.Scopes(ownedBy(userID)).Delete(&model.Knowledge{}, "id = ?", id).Error
 }
 
 // Supersede marks a knowledge entry as superseded by another
@@ -54,10 +54,13 @@ func (r *KnowledgeRepository) Supersede(oldID, newID string) error {
 		}).Error
 }
 
-// GetAll retrieves all knowledge entries
-func (r *KnowledgeRepository) GetAll(limit int) ([]model.Knowledge, error) {
+// GetAll retrieves the knowledge entries of a user, or only those of a workspace ("" = global pool) if workspaceID is set
+func (r *KnowledgeRepository) GetAll(userID string, workspaceID *string, limit int) ([]model.Knowledge, error) {
 	var knowledge []model.Knowledge
-	query := r.db.Order("created_at desc")
+	query := r.db.Scopes(ownedBy(userID)).Order("created_at desc")
+	if workspaceID != nil {
+		query = query.Where("workspace_id = ?", *workspaceID)
+	}
 	if limit > 0 {
 		query = query.Limit(limit)
 	}
@@ -67,11 +70,15 @@ func (r *KnowledgeRepository) GetAll(limit int) ([]model.Knowledge, error) {
 	return knowledge, nil
 }
 
-// GetAllActive retrieves all active knowledge (not superseded)
-func (r *KnowledgeRepository) GetAllActive(limit int) ([]model.Knowledge, error) {
+// GetAllActive retrieves the active knowledge (not superseded) of a user, optionally of a workspace only
+func (r *KnowledgeRepository) GetAllActive(userID string, workspaceID *string, limit int) ([]model.Knowledge, error) {
 	var knowledge []model.Knowledge
-	query := r.db.Where("superseded_by = '' OR superseded_by IS NULL").
+	query := r.db.Scopes(ownedBy(userID)).
+		Where("superseded_by = '' OR superseded_by IS NULL").
 		Order("created_at desc")
+	if workspaceID != nil {
+		query = query.Where("workspace_id = ?", *workspaceID)
+	}
 	if limit > 0 {
 		query = query.Limit(limit)
 	}
@@ -81,19 +88,19 @@ func (r *KnowledgeRepository) GetAllActive(limit int) ([]model.Knowledge, error)
 	return knowledge, nil
 }
 
-// Count returns the total number of knowledge entries
-func (r *KnowledgeRepository) Count() (int64, error) {
+// Count returns the number of knowledge entries of a user
+func (r *KnowledgeRepository) Count(userID string) (int64, error) {
 	var count int64
-	if err := r.db.Model(&model.Knowledge{}).Count(&count).Error; err != nil {
+	if err := r.db.Model(&model.Knowledge{}).Scopes(ownedBy(userID)).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-// CountActive returns the number of active knowledge entries
-func (r *KnowledgeRepository) CountActive() (int64, error) {
+// CountActive returns the number of active knowledge entries of a user
+func (r *KnowledgeRepository) CountActive(userID string) (int64, error) {
 	var count int64
-	if err := r.db.Model(&model.Knowledge{}).
+	if err := r.db.Model(&model.Knowledge{}).Scopes(ownedBy(userID)).
 		Where("superseded_by = '' OR superseded_by IS NULL").
 		Count(&count).Error; err != nil {
 		return 0, err
@@ -121,6 +128,15 @@ func (r *KnowledgeRepository) PromoteToLongTerm(id string) error {
 		}).Error
 }
 
+// ListAll retrieves the knowledge of all users, for maintenance such as re-embedding
+func (r *KnowledgeRepository) ListAll() ([]model.Knowledge, error) {
+	var knowledge []model.Knowledge
+	if err := r.db.Order("created_at asc").Find(&knowledge).Error; err != nil {
+		return nil, err
+	}
+	return knowledge, nil
+}
+
 // GetMidTermByTier retrieves knowledge by tier
 func (r *KnowledgeRepository) GetByTier(tier model.KnowledgeTier, activeOnly bool, limit int) ([]model.Knowledge, error) {
 	var knowledge []model.Knowledge
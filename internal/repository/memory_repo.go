@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -71,11 +72,121 @@ func (r *MemoryRepository) GetRecentBySessionID(sessionID string, limit int) ([]
 	return memories, nil
 }
 
+// GetBranch retrieves the messages on the path from the root to leafID, oldest first
+func (r *MemoryRepository) GetBranch(sessionID, leafID string) ([]model.Memory, error) {
+	if leafID == "" {
+		return nil, nil
+	}
+
+	memories, err := r.GetBySessionID(sessionID, 0)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.Memory, len(memories))
+	for _, m := range memories {
+		byID[m.ID] = m
+	}
+
+	var branch []model.Memory
+	for id := leafID; id != "" && len(branch) < len(memories); {
+		m, ok := byID[id]
+		if !ok {
+			break
+		}
+		branch = append(branch, m)
+		id = m.ParentID
+	}
+
+	for i, j := 0, len(branch)-1; i < j; i, j = i+1, j-1 {
+		branch[i], branch[j] = branch[j], branch[i]
+	}
+	return branch, nil
+}
+
+// GetRecentOnBranch retrieves the last limit messages of the branch ending at leafID
+func (r *MemoryRepository) GetRecentOnBranch(sessionID, leafID string, limit int) ([]model.Memory, error) {
+	branch, err := r.GetBranch(sessionID, leafID)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(branch) > limit {
+		branch = branch[len(branch)-limit:]
+	}
+	return branch, nil
+}
+
+// CopyBranch returns copies of the branch ending at leafID for another session, with
+// new IDs and the parent links remapped. The copies are not saved.
+func (r *MemoryRepository) CopyBranch(sessionID, leafID, targetSessionID string) ([]model.Memory, error) {
+	branch, err := r.GetBranch(sessionID, leafID)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID := ""
+	for i := range branch {
+		branch[i].ID = uuid.New().String()
+		branch[i].SessionID = targetSessionID
+		branch[i].ParentID = parentID
+		parentID = branch[i].ID
+	}
+	return branch, nil
+}
+
+// GetChildren retrieves the direct replies to a message, oldest first.
+// An empty parentID returns the first messages of the session.
+func (r *MemoryRepository) GetChildren(sessionID, parentID string) ([]model.Memory, error) {
+	var memories []model.Memory
+	if err := r.db.Where("session_id = ? AND parent_id = ?", sessionID, parentID).
+		Order("created_at asc").
+		Find(&memories).Error; err != nil {
+		return nil, err
+	}
+	return memories, nil
+}
+
+// GetLatestLeaf follows the most recent reply from messageID down to the end of its branch.
+// An empty messageID starts from the latest first message of the session.
+func (r *MemoryRepository) GetLatestLeaf(sessionID, messageID string) (string, error) {
+	memories, err := r.GetBySessionID(sessionID, 0)
+	if err != nil {
+		return "", err
+	}
+	// Ordered by creation time, so the last child seen per parent is the latest
+	latestChild := make(map[string]string, len(memories))
+	for _, m := range memories {
+		latestChild[m.ParentID] = m.ID
+	}
+
+	leaf := messageID
+	for i := 0; i < len(memories); i++ {
+		next, ok := latestChild[leaf]
+		if !ok {
+			break
+		}
+		leaf = next
+	}
+	return leaf, nil
+}
+
 // Delete deletes a memory by ID
 func (r *MemoryRepository) Delete(id string) error {
 	return r.db.Delete(&model.Memory{}, "id = ?", id).Error
 }
 
+// DeleteFromBranch deletes a memory and attaches its replies to its parent,
+// so the branches below it stay connected
+func (r *MemoryRepository) DeleteFromBranch(memory *model.Memory) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Memory{}).
+			Where("session_id = ? AND parent_id = ?", memory.SessionID, memory.ID).
+			Update("parent_id", memory.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Memory{}, "id = ?", memory.ID).Error
+	})
+}
+
 // DeleteBySessionID deletes all memories for a session
 func (r *MemoryRepository) DeleteBySessionID(sessionID string) error {
 	return r.db.Delete(&model.Memory{}, "session_id = ?", sessionID).Error
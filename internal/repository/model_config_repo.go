@@ -22,10 +22,10 @@ func (r *ModelConfigRepository) Create(config *model.ModelConfig) error {
 	return r.db.Create(config).Error
 }
 
-// GetByID retrieves a model config by ID
-func (r *ModelConfigRepository) GetByID(id string) (*model.ModelConfig, error) {
+// GetByID retrieves a model config of a provider visible to a user by ID
+func (r *ModelConfigRepository) GetByID(userID, id string) (*model.ModelConfig, error) {
 	var config model.ModelConfig
-	if err := r.db.Preload("Provider").First(&config, "id = ?", id).Error; err != nil {
+	if err := r.db.Preload("Provider").Scopes(visibleModelConfigs(userID)).First(&config, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -34,10 +34,10 @@ func (r *ModelConfigRepository) GetByID(id string) (*model.ModelConfig, error) {
 	return &config, nil
 }
 
-// GetAll retrieves all model configs
-func (r *ModelConfigRepository) GetAll() ([]model.ModelConfig, error) {
+// GetAll retrieves the model configs of the providers visible to a user
+func (r *ModelConfigRepository) GetAll(userID string) ([]model.ModelConfig, error) {
 	var configs []model.ModelConfig
-	if err := r.db.Preload("Provider").Order("created_at desc").Find(&configs).Error; err != nil {
+	if err := r.db.Preload("Provider").Scopes(visibleModelConfigs(userID)).Order("created_at desc").Find(&configs).Error; err != nil {
 		return nil, err
 	}
 	return configs, nil
@@ -52,27 +52,30 @@ func (r *ModelConfigRepository) GetByProvider(providerID string) ([]model.ModelC
 	return configs, nil
 }
 
-// GetByType retrieves all model configs of a specific type
-func (r *ModelConfigRepository) GetByType(configType model.ConfigType) ([]model.ModelConfig, error) {
+// GetByType retrieves the model configs of a specific type visible to a user
+func (r *ModelConfigRepository) GetByType(userID string, configType model.ConfigType) ([]model.ModelConfig, error) {
 	var configs []model.ModelConfig
-	if err := r.db.Preload("Provider").Where("config_type = ?", configType).Order("created_at desc").Find(&configs).Error; err != nil {
+	if err := r.db.Preload("Provider").Scopes(visibleModelConfigs(userID)).Where("config_type = ?", configType).Order("created_at desc").Find(&configs).Error; err != nil {
 		return nil, err
 	}
 	return configs, nil
 }
 
-// GetDefaultByType retrieves the default model config for a specific type
-func (r *ModelConfigRepository) GetDefaultByType(configType model.ConfigType) (*model.ModelConfig, error) {
+// GetDefaultByType retrieves the default model config for a specific type among those visible to a user
+func (r *ModelConfigRepository) GetDefaultByType(userID string, configType model.ConfigType) (*model.ModelConfig, error) {
 	var config model.ModelConfig
 	// First, try to find a default config of this type with an enabled provider
 	if err := r.db.Preload("Provider").
 		Joins("JOIN providers ON providers.id = model_configs.provider_id").
+		Scopes(visibleProviders(userID)).
 		Where("model_configs.config_type = ? AND model_configs.is_default = ? AND providers.enabled = ?", configType, true, true).
+		Order("providers.user_id desc"). // The user's own default before the shared one
 		First(&config).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// If no default, return the first config of this type with an enabled provider
 			if err := r.db.Preload("Provider").
 				Joins("JOIN providers ON providers.id = model_configs.provider_id").
+				Scopes(visibleProviders(userID)).
 				Where("model_configs.config_type = ? AND providers.enabled = ?", configType, true).
 				First(&config).Error; err != nil {
 				if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -102,17 +105,25 @@ func (r *ModelConfigRepository) DeleteByProvider(providerID string) error {
 	return r.db.Delete(&model.ModelConfig{}, "provider_id = ?", providerID).Error
 }
 
-// SetDefault sets a model config as default and unsets others of the same type
+// SetDefault sets a model config as default and unsets others of the same type. Defaults
+// are kept per provider owner: a user's own default takes precedence over the shared one.
 func (r *ModelConfigRepository) SetDefault(id string) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		// First get the config to know its type
+		// First get the config to know its type and owner
 		var config model.ModelConfig
-		if err := tx.First(&config, "id = ?", id).Error; err != nil {
+		if err := tx.Preload("Provider").First(&config, "id = ?", id).Error; err != nil {
 			return err
 		}
+		ownerID := ""
+		if config.Provider != nil {
+			ownerID = config.Provider.UserID
+		}
 
-		// Unset defaults only for configs of the same type
-		if err := tx.Model(&model.ModelConfig{}).Where("config_type = ? AND is_default = ?", config.ConfigType, true).Update("is_default", false).Error; err != nil {
+		// Unset defaults only for configs of the same type and owner
+		sameOwner := tx.Session(&gorm.Session{NewDB: true}).Model(&model.Provider{}).Select("id").Where("user_id = ?", ownerID)
+		if err := tx.Model(&model.ModelConfig{}).
+			Where("config_type = ? AND is_default = ? AND provider_id IN (?)", config.ConfigType, true, sameOwner).
+			Update("is_default", false).Error; err != nil {
 			return err
 		}
 		// Set the new default
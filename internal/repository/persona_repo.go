@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"gorm.io/gorm"
+)
+
+// PersonaRepository handles persona persistence
+type PersonaRepository struct {
+	db *DB
+}
+
+// NewPersonaRepository creates a new persona repository
+func NewPersonaRepository(db *DB) *PersonaRepository {
+	return &PersonaRepository{db: db}
+}
+
+// Create creates a new persona
+func (r *PersonaRepository) Create(persona *model.Persona) error {
+	return r.db.Create(persona).Error
+}
+
+// GetByID retrieves a persona visible to a user (their own or a shared one) by ID
+func (r *PersonaRepository) GetByID(userID, id string) (*model.Persona, error) {
+	var persona model.Persona
+	if err := r.db.Scopes(visiblePersonas(userID)).First(&persona, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	persona.Shared = persona.UserID == ""
+	return &persona, nil
+}
+
+// GetAll retrieves the personas visible to a user ordered by name
+func (r *PersonaRepository) GetAll(userID string) ([]model.Persona, error) {
+	var personas []model.Persona
+	if err := r.db.Scopes(visiblePersonas(userID)).Order("name asc").Find(&personas).Error; err != nil {
+		return nil, err
+	}
+	for i := range personas {
+		personas[i].Shared = personas[i].UserID == ""
+	}
+	return personas, nil
+}
+
+// Update updates a persona
+func (r *PersonaRepository) Update(persona *model.Persona) error {
+	return r.db.Save(persona).Error
+}
+
+// Delete deletes a persona and detaches it from the sessions using it
+func (r *PersonaRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Session{}).Where("persona_id = ?", id).UpdateColumn("persona_id", "").Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Persona{}, "id = ?", id).Error
+	})
+}
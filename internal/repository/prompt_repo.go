@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"gorm.io/gorm"
+)
+
+// PromptRepository handles prompt template persistence
+type PromptRepository struct {
+	db *DB
+}
+
+// NewPromptRepository creates a new prompt repository
+func NewPromptRepository(db *DB) *PromptRepository {
+	return &PromptRepository{db: db}
+}
+
+// GetByID retrieves a prompt template version by ID
+func (r *PromptRepository) GetByID(id string) (*model.PromptTemplate, error) {
+	var prompt model.PromptTemplate
+	if err := r.db.First(&prompt, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// GetActive retrieves the active version of a prompt in a language
+func (r *PromptRepository) GetActive(name, language string) (*model.PromptTemplate, error) {
+	var prompt model.PromptTemplate
+	if err := r.db.Where("name = ? AND language = ? AND active = ?", name, language, true).First(&prompt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// GetAllActive retrieves the active versions of all prompts, optionally filtered by kind and language
+func (r *PromptRepository) GetAllActive(kind model.PromptKind, language string) ([]model.PromptTemplate, error) {
+	query := r.db.Where("active = ?", true)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if language != "" {
+		query = query.Where("language = ?", language)
+	}
+
+	var prompts []model.PromptTemplate
+	if err := query.Order("kind asc, name asc, language asc").Find(&prompts).Error; err != nil {
+		return nil, err
+	}
+	return prompts, nil
+}
+
+// GetVersions retrieves all versions of a prompt in a language, newest first
+func (r *PromptRepository) GetVersions(name, language string) ([]model.PromptTemplate, error) {
+	var prompts []model.PromptTemplate
+	if err := r.db.Where("name = ? AND language = ?", name, language).Order("version desc").Find(&prompts).Error; err != nil {
+		return nil, err
+	}
+	return prompts, nil
+}
+
+// CreateVersion stores a prompt as the next version of its name and language and makes it active
+func (r *PromptRepository) CreateVersion(prompt *model.PromptTemplate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&model.PromptTemplate{}).
+			Where("name = ? AND language = ?", prompt.Name, prompt.Language).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		if err := deactivatePrompt(tx, prompt.Name, prompt.Language); err != nil {
+			return err
+		}
+		prompt.Version = latest + 1
+		prompt.Active = true
+		return tx.Create(prompt).Error
+	})
+}
+
+// Activate makes a version the active one of its name and language
+func (r *PromptRepository) Activate(prompt *model.PromptTemplate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deactivatePrompt(tx, prompt.Name, prompt.Language); err != nil {
+			return err
+		}
+		prompt.Active = true
+		return tx.Model(&model.PromptTemplate{}).Where("id = ?", prompt.ID).UpdateColumn("active", true).Error
+	})
+}
+
+// Delete deletes all versions of a prompt in a language
+func (r *PromptRepository) Delete(name, language string) error {
+	return r.db.Where("name = ? AND language = ?", name, language).Delete(&model.PromptTemplate{}).Error
+}
+
+func deactivatePrompt(tx *gorm.DB, name, language string) error {
+	return tx.Model(&model.PromptTemplate{}).
+		Where("name = ? AND language = ? AND active = ?", name, language, true).
+		UpdateColumn("active", false).Error
+}
@@ -22,10 +22,10 @@ func (r *ProviderRepository) Create(provider *model.Provider) error {
 	return r.db.Create(provider).Error
 }
 
-// GetByID retrieves a provider by ID
-func (r *ProviderRepository) GetByID(id string) (*model.Provider, error) {
+// GetByID retrieves a provider visible to a user (their own or a shared one) by ID
+func (r *ProviderRepository) GetByID(userID, id string) (*model.Provider, error) {
 	var provider model.Provider
-	if err := r.db.First(&provider, "id = ?", id).Error; err != nil {
+	if err := r.db.Scopes(visibleProviders(userID)).First(&provider, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -34,33 +34,47 @@ func (r *ProviderRepository) GetByID(id string) (*model.Provider, error) {
 	return &provider, nil
 }
 
-// GetAll retrieves all providers
-func (r *ProviderRepository) GetAll() ([]model.Provider, error) {
+// GetAll retrieves the providers visible to a user
+func (r *ProviderRepository) GetAll(userID string) ([]model.Provider, error) {
 	var providers []model.Provider
-	if err := r.db.Order("created_at desc").Find(&providers).Error; err != nil {
+	if err := r.db.Scopes(visibleProviders(userID)).Order("created_at desc").Find(&providers).Error; err != nil {
 		return nil, err
 	}
 	return providers, nil
 }
 
-// GetEnabled retrieves all enabled providers
-func (r *ProviderRepository) GetEnabled() ([]model.Provider, error) {
+// GetEnabled retrieves the enabled providers visible to a user
+func (r *ProviderRepository) GetEnabled(userID string) ([]model.Provider, error) {
 	var providers []model.Provider
-	if err := r.db.Where("enabled = ?", true).Order("created_at desc").Find(&providers).Error; err != nil {
+	if err := r.db.Scopes(visibleProviders(userID)).Where("enabled = ?", true).Order("created_at desc").Find(&providers).Error; err != nil {
 		return nil, err
 	}
 	return providers, nil
 }
 
-// GetByType retrieves providers by type
-func (r *ProviderRepository) GetByType(providerType model.ProviderType) ([]model.Provider, error) {
+// GetByType retrieves the providers of a type visible to a user
+func (r *ProviderRepository) GetByType(userID string, providerType model.ProviderType) ([]model.Provider, error) {
 	var providers []model.Provider
-	if err := r.db.Where("type = ?", providerType).Order("created_at desc").Find(&providers).Error; err != nil {
+	if err := r.db.Scopes(visibleProviders(userID)).Where("type = ?", providerType).Order("created_at desc").Find(&providers).Error; err != nil {
 		return nil, err
 	}
 	return providers, nil
 }
 
+// ListAll retrieves the providers of all users, for maintenance such as key rotation
+func (r *ProviderRepository) ListAll() ([]model.Provider, error) {
+	var providers []model.Provider
+	if err := r.db.Order("created_at asc").Find(&providers).Error; err != nil {
+		return nil, err
+	}
+	return providers, nil
+}
+
+// UpdateAPIKey replaces the encrypted API key of a provider
+func (r *ProviderRepository) UpdateAPIKey(id, apiKey string) error {
+	return r.db.Model(&model.Provider{}).Where("id = ?", id).UpdateColumn("api_key", apiKey).Error
+}
+
 // Update updates a provider
 func (r *ProviderRepository) Update(provider *model.Provider) error {
 	return r.db.Save(provider).Error
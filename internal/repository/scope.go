@@ -0,0 +1,36 @@
+package repository
+
+import "gorm.io/gorm"
+
+// ownedBy restricts a query to the rows of a user. The empty user owns the rows
+// created while authentication is disabled.
+func ownedBy(userID string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ?", userID)
+	}
+}
+
+// visibleProviders restricts a provider query to the user's own providers and the
+// shared ones
+func visibleProviders(userID string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("providers.user_id = ? OR providers.user_id = ''", userID)
+	}
+}
+
+// visiblePersonas restricts a persona query to the user's own personas and the shared
+// ones
+func visiblePersonas(userID string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ? OR user_id = ''", userID)
+	}
+}
+
+// visibleModelConfigs restricts a model config query to the configs of the providers
+// visible to the user
+func visibleModelConfigs(userID string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("model_configs.provider_id IN (?)",
+			db.Session(&gorm.Session{NewDB: true}).Table("providers").Select("id").Scopes(visibleProviders(userID)))
+	}
+}
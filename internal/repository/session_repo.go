@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"github.com/allwaysyou/llm-agent/internal/model"
 	"gorm.io/gorm"
@@ -22,10 +23,10 @@ func (r *SessionRepository) Create(session *model.Session) error {
 	return r.db.Create(session).Error
 }
 
-// GetByID retrieves a session by ID
-func (r *SessionRepository) GetByID(id string) (*model.Session, error) {
+// GetByID retrieves a session of a user by ID
+func (r *SessionRepository) GetByID(userID, id string) (*model.Session, error) {
 	var session model.Session
-	if err := r.db.First(&session, "id = ?", id).Error; err != nil {
+	if err := r.db.Scopes(ownedBy(userID)).First(&session, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -34,10 +35,13 @@ func (r *SessionRepository) GetByID(id string) (*model.Session, error) {
 	return &session, nil
 }
 
-// GetAll retrieves all sessions
-func (r *SessionRepository) GetAll(limit, offset int) ([]model.Session, error) {
+// GetAll retrieves the sessions of a user, or only those of a workspace ("" = global pool) if workspaceID is set
+func (r *SessionRepository) GetAll(userID string, workspaceID *string, limit, offset int) ([]model.Session, error) {
 	var sessions []model.Session
-	query := r.db.Order("updated_at desc")
+	query := r.db.Scopes(ownedBy(userID)).Order("updated_at desc")
+	if workspaceID != nil {
+		query = query.Where("workspace_id = ?", *workspaceID)
+	}
 	if limit > 0 {
 		query = query.Limit(limit)
 	}
@@ -55,12 +59,86 @@ func (r *SessionRepository) Update(session *model.Session) error {
 	return r.db.Save(session).Error
 }
 
-// Delete deletes a session by ID
-func (r *SessionRepository) Delete(id string) error {
-	return r.db.Delete(&model.Session{}, "id = ?", id).Error
+// Delete deletes a session of a user by ID
+func (r *SessionRepository) Delete(userID, id string) error {
+	return r.db.Scopes(ownedBy(userID)).Delete(&model.Session{}, "id = ?", id).Error
 }
 
-// UpdateSummary updates the summary of a session
+// UpdateSummary updates the summary of a session. It updates from a struct so that the
+// summary goes through its serializer.
 func (r *SessionRepository) UpdateSummary(id string, summary string) error {
-	return r.db.Model(&model.Session{}).Where("id = ?", id).Update("summary", summary).Error
+	return r.db.Model(&model.Session{}).Where("id = ?", id).Select("summary").Updates(&model.Session{Summary: summary}).Error
+}
+
+// UpdateActiveLeaf sets the last message of the session's active branch
+func (r *SessionRepository) UpdateActiveLeaf(id string, leafID string) error {
+	return r.db.Model(&model.Session{}).Where("id = ?", id).UpdateColumn("active_leaf_id", leafID).Error
+}
+
+// CreateFork creates a forked session together with its copied messages in one transaction
+func (r *SessionRepository) CreateFork(fork *model.Session, messages []model.Memory) error {
+	return r.CreateWithMessages(fork, messages)
+}
+
+// CreateWithMessages creates a session together with its messages in one transaction
+func (r *SessionRepository) CreateWithMessages(session *model.Session, messages []model.Memory) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&messages, 100).Error
+	})
+}
+
+// GetImportedFrom retrieves the sources of the sessions of a user imported from other
+// assistants' exports
+func (r *SessionRepository) GetImportedFrom(userID string) (map[string]bool, error) {
+	var sources []string
+	if err := r.db.Model(&model.Session{}).Scopes(ownedBy(userID)).
+		Where("imported_from <> ''").
+		Pluck("imported_from", &sources).Error; err != nil {
+		return nil, err
+	}
+	imported := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		imported[source] = true
+	}
+	return imported, nil
+}
+
+// GetForkIDs retrieves the IDs of the sessions forked from each of the given sessions
+func (r *SessionRepository) GetForkIDs(ids []string) (map[string][]string, error) {
+	var forks []model.Session
+	if err := r.db.Select("id", "forked_from_id").
+		Where("forked_from_id IN ?", ids).
+		Order("created_at asc").
+		Find(&forks).Error; err != nil {
+		return nil, err
+	}
+
+	forkIDs := make(map[string][]string)
+	for _, f := range forks {
+		forkIDs[f.ForkedFromID] = append(forkIDs[f.ForkedFromID], f.ID)
+	}
+	return forkIDs, nil
+}
+
+// MergeFork records when a fork was merged back into its origin and sets the origin's
+// summary in one transaction. It returns false, changing nothing, if the fork was
+// merged before.
+func (r *SessionRepository) MergeFork(forkID, originID, summary string, mergedAt time.Time) (bool, error) {
+	merged := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Session{}).Where("id = ? AND merged_at IS NULL", forkID).UpdateColumn("merged_at", mergedAt)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		merged = true
+		// From a struct so that the summary goes through its serializer
+		return tx.Model(&model.Session{}).Where("id = ?", originID).Select("summary").Updates(&model.Session{Summary: summary}).Error
+	})
+	return merged, err
 }
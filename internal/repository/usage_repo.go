@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+)
+
+// UsageRepository handles usage record persistence
+type UsageRepository struct {
+	db *DB
+}
+
+// NewUsageRepository creates a new usage repository
+func NewUsageRepository(db *DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// Create creates a new usage record
+func (r *UsageRepository) Create(record *model.UsageRecord) error {
+	return r.db.Create(record).Error
+}
+
+// Summarize aggregates usage records according to the query
+func (r *UsageRepository) Summarize(q model.UsageQuery) ([]model.UsageSummary, error) {
+	var keyExpr string
+	switch q.GroupBy {
+	case model.UsageGroupByDay, "":
+		keyExpr = "date(created_at, 'localtime')"
+	case model.UsageGroupBySession:
+		keyExpr = "session_id"
+	case model.UsageGroupByModel:
+		keyExpr = "model"
+	case model.UsageGroupByPurpose:
+		keyExpr = "purpose"
+	default:
+		return nil, fmt.Errorf("unsupported group_by: %s", q.GroupBy)
+	}
+
+	query := r.db.Model(&model.UsageRecord{}).
+		Select(keyExpr + " AS key, COUNT(*) AS requests, " +
+			"SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens, " +
+			"SUM(prompt_tokens + completion_tokens) AS total_tokens, SUM(cost) AS cost")
+	if q.SessionID != "" {
+		query = query.Where("session_id = ?", q.SessionID)
+	}
+	if q.From != nil {
+		query = query.Where("created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("created_at < ?", *q.To)
+	}
+
+	var summaries []model.UsageSummary
+	if err := query.Group(keyExpr).Order("key").Scan(&summaries).Error; err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// TotalsSince sums the usage of a provider and/or model config since the given time
+func (r *UsageRepository) TotalsSince(providerID, configID string, since time.Time) (model.UsageTotals, error) {
+	var totals model.UsageTotals
+	query := r.db.Model(&model.UsageRecord{}).
+		Select("COALESCE(SUM(prompt_tokens + completion_tokens), 0) AS tokens, COALESCE(SUM(cost), 0) AS cost").
+		Where("created_at >= ?", since)
+	if providerID != "" {
+		query = query.Where("provider_id = ?", providerID)
+	}
+	if configID != "" {
+		query = query.Where("config_id = ?", configID)
+	}
+	if err := query.Scan(&totals).Error; err != nil {
+		return totals, err
+	}
+	return totals, nil
+}
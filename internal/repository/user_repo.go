@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"gorm.io/gorm"
+)
+
+// UserRepository handles user and login session persistence
+type UserRepository struct {
+	db *DB
+}
+
+// NewUserRepository creates a new user repository
+func NewUserRepository(db *DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// Create creates a new user
+func (r *UserRepository) Create(user *model.User) error {
+	return r.db.Create(user).Error
+}
+
+// GetByID retrieves a user by ID
+func (r *UserRepository) GetByID(id string) (*model.User, error) {
+	var user model.User
+	if err := r.db.First(&user, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByUsername retrieves a user by username
+func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
+	var user model.User
+	if err := r.db.First(&user, "username = ?", username).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetAll retrieves all users ordered by username
+func (r *UserRepository) GetAll() ([]model.User, error) {
+	var users []model.User
+	if err := r.db.Order("username asc").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Count returns the number of users
+func (r *UserRepository) Count() (int64, error) {
+	var count int64
+	if err := r.db.Model(&model.User{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountAdmins returns the number of enabled admins
+func (r *UserRepository) CountAdmins() (int64, error) {
+	var count int64
+	if err := r.db.Model(&model.User{}).
+		Where("role = ? AND disabled = ?", model.UserRoleAdmin, false).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Update updates a user
+func (r *UserRepository) Update(user *model.User) error {
+	return r.db.Save(user).Error
+}
+
+// Delete deletes a user together with their login sessions, access tokens, sessions,
+// messages, knowledge, workspaces and private providers. It returns the IDs of the
+// deleted knowledge.
+func (r *UserRepository) Delete(id string) ([]string, error) {
+	var knowledgeIDs []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Knowledge{}).Where("user_id = ?", id).Pluck("id", &knowledgeIDs).Error; err != nil {
+			return err
+		}
+		for _, m := range []any{&model.AuthToken{}, &model.AccessToken{}, &model.Knowledge{}, &model.Memory{}, &model.Session{}, &model.Workspace{}, &model.Persona{}} {
+			if err := tx.Where("user_id = ?", id).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		providers := tx.Session(&gorm.Session{NewDB: true}).Model(&model.Provider{}).Select("id").Where("user_id = ?", id)
+		if err := tx.Where("provider_id IN (?)", providers).Delete(&model.ModelConfig{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&model.Provider{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.User{}, "id = ?", id).Error
+	})
+	return knowledgeIDs, err
+}
+
+// ClaimUnowned assigns the sessions, messages, knowledge and workspaces created while
+// authentication was disabled to a user. Providers and personas stay shared. It returns the IDs of
+// the claimed knowledge.
+func (r *UserRepository) ClaimUnowned(userID string) ([]string, error) {
+	var knowledgeIDs []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Knowledge{}).Where("user_id = ''").Pluck("id", &knowledgeIDs).Error; err != nil {
+			return err
+		}
+		for _, m := range []any{&model.Knowledge{}, &model.Memory{}, &model.Session{}, &model.Workspace{}} {
+			if err := tx.Model(m).Where("user_id = ''").UpdateColumn("user_id", userID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return knowledgeIDs, err
+}
+
+// CreateToken stores a login session
+func (r *UserRepository) CreateToken(token *model.AuthToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetToken retrieves an unexpired login session by token hash
+func (r *UserRepository) GetToken(tokenHash string) (*model.AuthToken, error) {
+	var token model.AuthToken
+	if err := r.db.First(&token, "token_hash = ? AND expires_at > ?", tokenHash, time.Now()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// TouchToken records the last use of a login session
+func (r *UserRepository) TouchToken(id string, usedAt time.Time) error {
+	return r.db.Model(&model.AuthToken{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+}
+
+// DeleteToken ends a login session
+func (r *UserRepository) DeleteToken(tokenHash string) error {
+	return r.db.Delete(&model.AuthToken{}, "token_hash = ?", tokenHash).Error
+}
+
+// DeleteTokensByUser ends all login sessions of a user
+func (r *UserRepository) DeleteTokensByUser(userID string) error {
+	return r.db.Delete(&model.AuthToken{}, "user_id = ?", userID).Error
+}
+
+// DeleteExpiredTokens removes expired login sessions
+func (r *UserRepository) DeleteExpiredTokens() (int64, error) {
+	result := r.db.Where("expires_at <= ?", time.Now()).Delete(&model.AuthToken{})
+	return result.RowsAffected, result.Error
+}
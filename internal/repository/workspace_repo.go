@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"gorm.io/gorm"
+)
+
+// WorkspaceRepository handles workspace persistence
+type WorkspaceRepository struct {
+	db *DB
+}
+
+// NewWorkspaceRepository creates a new workspace repository
+func NewWorkspaceRepository(db *DB) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// Create creates a new workspace
+func (r *WorkspaceRepository) Create(workspace *model.Workspace) error {
+	return r.db.Create(workspace).Error
+}
+
+// GetByID retrieves a workspace of a user by ID
+func (r *WorkspaceRepository) GetByID(userID, id string) (*model.Workspace, error) {
+	var workspace model.Workspace
+	if err := r.db.Scopes(ownedBy(userID)).First(&workspace, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// GetAll retrieves the workspaces of a user ordered by name
+func (r *WorkspaceRepository) GetAll(userID string) ([]model.Workspace, error) {
+	var workspaces []model.Workspace
+	if err := r.db.Scopes(ownedBy(userID)).Order("name asc").Find(&workspaces).Error; err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+// Update updates a workspace
+func (r *WorkspaceRepository) Update(workspace *model.Workspace) error {
+	return r.db.Save(workspace).Error
+}
+
+// Delete deletes a workspace together with its sessions, their messages and its
+// knowledge. It returns the IDs of the deleted knowledge.
+func (r *WorkspaceRepository) Delete(id string) ([]string, error) {
+	var knowledgeIDs []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Knowledge{}).Where("workspace_id = ?", id).Pluck("id", &knowledgeIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", id).Delete(&model.Knowledge{}).Error; err != nil {
+			return err
+		}
+		sessions := tx.Model(&model.Session{}).Select("id").Where("workspace_id = ?", id)
+		if err := tx.Where("session_id IN (?)", sessions).Delete(&model.Memory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", id).Delete(&model.Session{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Workspace{}, "id = ?", id).Error
+	})
+	return knowledgeIDs, err
+}
@@ -1,9 +1,11 @@
 package server
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/allwaysyou/llm-agent/internal/adapter"
 	"github.com/allwaysyou/llm-agent/internal/config"
@@ -11,7 +13,11 @@ import (
 	"github.com/allwaysyou/llm-agent/internal/model"
 	"github.com/allwaysyou/llm-agent/internal/pkg/crypto"
 	"github.com/allwaysyou/llm-agent/internal/pkg/embedding"
+	"github.com/allwaysyou/llm-agent/internal/pkg/httpclient"
 	"github.com/allwaysyou/llm-agent/internal/pkg/memory"
+	"github.com/allwaysyou/llm-agent/internal/pkg/modelinfo"
+	"github.com/allwaysyou/llm-agent/internal/pkg/prompt"
+	"github.com/allwaysyou/llm-agent/internal/pkg/redact"
 	"github.com/allwaysyou/llm-agent/internal/pkg/vector"
 	"github.com/allwaysyou/llm-agent/internal/repository"
 	"github.com/allwaysyou/llm-agent/internal/service"
@@ -25,15 +31,24 @@ type Handlers struct {
 	Chat        *handler.ChatHandler
 	Session     *handler.SessionHandler
 	Memory      *handler.MemoryHandler
+	Usage       *handler.UsageHandler
+	Persona     *handler.PersonaHandler
+	Prompt      *handler.PromptHandler
+	Workspace   *handler.WorkspaceHandler
+	User        *handler.UserHandler
+	AccessToken *handler.AccessTokenHandler
+	Backup      *handler.BackupHandler
+	Knowledge   *handler.KnowledgeTransferHandler
+	History     *handler.HistoryImportHandler
 }
 
 // Dependencies contains all initialized dependencies
 type Dependencies struct {
-	DB              *repository.DB
-	Encryptor       *crypto.Encryptor
-	VectorStore     *vector.VectorStore
-	AdapterFactory  *adapter.AdapterFactory
-	EmbedProvider   embedding.Provider
+	DB             *repository.DB
+	Encryptor      *crypto.Encryptor
+	VectorStore    *vector.VectorStore
+	AdapterFactory *adapter.AdapterFactory
+	EmbedProvider  embedding.Provider
 
 	// Services
 	ProviderService    *service.ProviderService
@@ -41,6 +56,16 @@ type Dependencies struct {
 	ChatService        *service.ChatService
 	MemoryService      *service.MemoryService
 	SummarizeService   *service.SummarizeService
+	SessionService     *service.SessionService
+	UsageService       *service.UsageService
+	PersonaService     *service.PersonaService
+	PromptService      *service.PromptService
+	WorkspaceService   *service.WorkspaceService
+	UserService        *service.UserService
+	AccessTokenService *service.AccessTokenService
+	BackupService      *service.BackupService
+	KnowledgeTransfer  *service.KnowledgeTransferService
+	HistoryImport      *service.HistoryImportService
 	MemoryManager      *memory.DefaultManager
 
 	// Handlers
@@ -54,6 +79,14 @@ type Dependencies struct {
 func Initialize(cfg *config.Config) (*Dependencies, error) {
 	deps := &Dependencies{Config: cfg}
 
+	// Initialize encryptor, it also decrypts content read from the database
+	encryptor, err := newEncryptor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	deps.Encryptor = encryptor
+	repository.SetContentEncryption(encryptor, cfg.Encryption.AtRest)
+
 	// Initialize database
 	db, err := repository.NewDB(cfg.Database.Path)
 	if err != nil {
@@ -61,24 +94,22 @@ func Initialize(cfg *config.Config) (*Dependencies, error) {
 	}
 	deps.DB = db
 
-	// Initialize encryptor
-	encryptionKey := cfg.Encryption.Key
-	if encryptionKey == "" {
-		log.Println("WARNING: Using auto-generated encryption key. Set LLM_AGENT_ENCRYPTION_KEY in production.")
-		encryptionKey = "01234567890123456789012345678901" // 32 bytes default
-	}
-
-	encryptor, err := crypto.NewEncryptor(encryptionKey)
-	if err != nil {
-		db.Close()
-		return nil, err
+	// Encrypt content stored before at-rest encryption was enabled
+	if cfg.Encryption.AtRest {
+		count, err := db.EncryptContent(false)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to encrypt existing content: %w", err)
+		}
+		if count > 0 {
+			log.Printf("Encrypted %d existing messages, knowledge entries and summaries", count)
+		}
 	}
-	deps.Encryptor = encryptor
 
 	// Initialize vector store
 	vectorPath := filepath.Join(cfg.Vector.Path, "vectors.json")
 	os.MkdirAll(cfg.Vector.Path, 0755)
-	vectorStore, err := vector.NewVectorStore(vectorPath)
+	vectorStore, err := vector.NewVectorStoreWithCipher(vectorPath, encryptor, cfg.Encryption.AtRest)
 	if err != nil {
 		db.Close()
 		return nil, err
@@ -91,6 +122,12 @@ func Initialize(cfg *config.Config) (*Dependencies, error) {
 	sessionRepo := repository.NewSessionRepository(db)
 	memoryRepo := repository.NewMemoryRepository(db)
 	knowledgeRepo := repository.NewKnowledgeRepository(db)
+	usageRepo := repository.NewUsageRepository(db)
+	personaRepo := repository.NewPersonaRepository(db)
+	promptRepo := repository.NewPromptRepository(db)
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	accessTokenRepo := repository.NewAccessTokenRepository(db)
 
 	// Initialize adapter factory with all providers
 	adapterFactory := adapter.NewAdapterFactory()
@@ -98,27 +135,57 @@ func Initialize(cfg *config.Config) (*Dependencies, error) {
 	adapterFactory.Register(model.ProviderClaude, adapter.NewClaudeAdapter)
 	adapterFactory.Register(model.ProviderAzure, adapter.NewAzureAdapter)
 	adapterFactory.Register(model.ProviderOllama, adapter.NewOllamaAdapter)
+	adapterFactory.Register(model.ProviderGemini, adapter.NewGeminiAdapter)
+	adapterFactory.Register(model.ProviderCustom, adapter.NewCustomAdapter)
 	deps.AdapterFactory = adapterFactory
 
 	// Initialize services
-	providerService := service.NewProviderService(providerRepo, modelConfigRepo, encryptor)
+	httpConfig := httpclient.Config{
+		ConnectTimeout:  time.Duration(cfg.HTTP.ConnectTimeoutSec) * time.Second,
+		ResponseTimeout: time.Duration(cfg.HTTP.ResponseTimeoutSec) * time.Second,
+		MaxRetries:      cfg.HTTP.MaxRetries,
+		BaseDelay:       time.Duration(cfg.HTTP.RetryBaseDelayMs) * time.Millisecond,
+		MaxDelay:        time.Duration(cfg.HTTP.RetryMaxDelayMs) * time.Millisecond,
+		ProxyURL:        cfg.HTTP.ProxyURL,
+	}
+	redactor, err := redact.New(cfg.Redaction.Types)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("invalid redaction config: %w", err)
+	}
+	providerService := service.NewProviderService(providerRepo, modelConfigRepo, encryptor, httpConfig, redactor, cfg.Redaction.Enabled)
+	if stale, err := providerService.StaleAPIKeys(); err != nil {
+		log.Printf("Warning: Failed to check provider API key encryption: %v", err)
+	} else if stale > 0 {
+		log.Printf("%d provider API keys are not encrypted with the current key (%s), run with -rotate-keys to re-encrypt them", stale, encryptor.CurrentKeyID())
+	}
 	modelConfigService := service.NewModelConfigService(modelConfigRepo, providerRepo)
 	deps.ProviderService = providerService
 	deps.ModelConfigService = modelConfigService
 
 	// Initialize embedding provider based on config
 	var embedProvider embedding.Provider
+	var embedCaps model.ModelCapabilities
 	switch cfg.Embedding.Provider {
 	case "ollama":
-		embedProvider = embedding.NewOllamaProvider(cfg.Embedding.BaseURL, cfg.Embedding.Model)
+		client, err := providerService.HTTPClient(nil)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		ollama := &model.Provider{Type: model.ProviderTypeOllama, BaseURL: cfg.Embedding.BaseURL}
+		embedProvider = providerService.RedactEmbeddings(ollama, embedding.NewOllamaProvider(cfg.Embedding.BaseURL, cfg.Embedding.Model, client))
+		embedCaps = modelinfo.Capabilities(&model.ModelConfig{Model: cfg.Embedding.Model})
 		log.Printf("Using Ollama embedding provider (model: %s, url: %s)", cfg.Embedding.Model, cfg.Embedding.BaseURL)
 	case "openai":
-		// Try to get embedding config from database
-		embeddingConfig, _ := modelConfigService.GetDefaultByType(model.ConfigTypeEmbedding)
+		// Try to get embedding config from database, among the shared providers
+		embeddingConfig, _ := modelConfigService.GetDefaultByType("", model.ConfigTypeEmbedding)
 		if embeddingConfig != nil && embeddingConfig.Provider != nil {
-			apiKey, err := providerService.GetDecryptedAPIKey(embeddingConfig.ProviderID)
+			adapterCfg, err := providerService.AdapterConfig(embeddingConfig.Provider)
 			if err == nil {
-				embedProvider = embedding.NewOpenAIProvider(apiKey, embeddingConfig.Provider.BaseURL, embeddingConfig.Model)
+				embedProvider = providerService.RedactEmbeddings(embeddingConfig.Provider,
+					embedding.NewOpenAIProvider(adapterCfg.APIKey, adapterCfg.BaseURL, embeddingConfig.Model, adapterCfg.HTTPClient))
+				embedCaps = modelinfo.Capabilities(embeddingConfig)
 				log.Printf("Using OpenAI embedding provider (model: %s)", embeddingConfig.Model)
 			}
 		}
@@ -127,51 +194,178 @@ func Initialize(cfg *config.Config) (*Dependencies, error) {
 	}
 	deps.EmbedProvider = embedProvider
 
+	// Vectors of different dimensions never match, warn when the embedding model changed
+	if stored := vectorStore.Dimension(); stored > 0 && embedCaps.EmbeddingDimension > 0 && stored != embedCaps.EmbeddingDimension {
+		log.Printf("WARNING: Vector store has %d-dimensional embeddings but the embedding model produces %d; new embeddings will be rejected until the store is rebuilt with -reembed",
+			stored, embedCaps.EmbeddingDimension)
+	}
+
+	// Initialize prompt library, storing the built-in defaults on first run
+	prompts := prompt.NewLibrary(promptRepo, cfg.Prompt.Language)
+	if err := prompts.SeedBuiltins(); err != nil {
+		log.Printf("Warning: Failed to seed built-in prompts: %v", err)
+	}
+
 	// Initialize memory manager
-	memoryManager := memory.NewManager(memoryRepo, knowledgeRepo, vectorStore, embedProvider, cfg.Memory)
+	memoryManager := memory.NewManager(memoryRepo, knowledgeRepo, vectorStore, embedProvider, prompts, cfg.Memory)
 	deps.MemoryManager = memoryManager
 
 	// Initialize services
-	memoryService := service.NewMemoryService(memoryRepo, knowledgeRepo, sessionRepo, vectorStore, embedProvider, cfg.Memory)
-	chatService := service.NewChatService(modelConfigService, providerService, sessionRepo, memoryManager, adapterFactory, cfg.LLM)
-	summarizeService := service.NewSummarizeService(sessionRepo, memoryRepo, modelConfigService, providerService, adapterFactory)
+	limitService := service.NewLimitService(usageRepo, cfg.LLM)
+	usageService := service.NewUsageService(usageRepo, limitService)
+	workspaceService := service.NewWorkspaceService(workspaceRepo, vectorStore)
+	memoryService := service.NewMemoryService(memoryRepo, knowledgeRepo, sessionRepo, workspaceService, vectorStore, embedProvider, cfg.Memory)
+	chatService := service.NewChatService(modelConfigService, providerService, sessionRepo, memoryRepo, personaRepo, workspaceService, memoryManager, adapterFactory, usageService, cfg.LLM)
+	summarizeService := service.NewSummarizeService(sessionRepo, memoryRepo, modelConfigService, providerService, adapterFactory, usageService, prompts)
+	sessionService := service.NewSessionService(sessionRepo, memoryRepo, personaRepo, workspaceService, modelConfigService, summarizeService)
+	personaService := service.NewPersonaService(personaRepo)
+	promptService := service.NewPromptService(promptRepo)
+	modelCatalogService := service.NewModelCatalogService(providerService, adapterFactory)
+	userService := service.NewUserService(userRepo, vectorStore, cfg.Auth)
+	accessTokenService := service.NewAccessTokenService(accessTokenRepo, userRepo)
+	backupService := service.NewBackupService(db, vectorStore, encryptor, cfg)
+	knowledgeTransfer := service.NewKnowledgeTransferService(knowledgeRepo, workspaceService, vectorStore, memoryManager, modelConfigService, providerService, adapterFactory, usageService, cfg.Memory.ImportMaxRecords)
+	historyImport := service.NewHistoryImportService(sessionRepo, workspaceService, memoryManager, embedProvider, modelConfigService, providerService, adapterFactory, usageService, cfg.Memory, cfg.LLM)
 	deps.MemoryService = memoryService
 	deps.ChatService = chatService
 	deps.SummarizeService = summarizeService
+	deps.SessionService = sessionService
+	deps.UsageService = usageService
+	deps.PersonaService = personaService
+	deps.PromptService = promptService
+	deps.WorkspaceService = workspaceService
+	deps.UserService = userService
+	deps.AccessTokenService = accessTokenService
+	deps.BackupService = backupService
+	deps.KnowledgeTransfer = knowledgeTransfer
+	deps.HistoryImport = historyImport
+
+	// Create the first admin when accounts are enabled
+	if err := userService.Bootstrap(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if count, err := userService.CleanupExpiredTokens(); err != nil {
+		log.Printf("Warning: Failed to clean up expired login sessions: %v", err)
+	} else if count > 0 {
+		log.Printf("Removed %d expired login sessions", count)
+	}
 
 	// Initialize handlers
 	deps.Handlers = &Handlers{
-		Provider:    handler.NewProviderHandler(providerService, adapterFactory),
+		Provider:    handler.NewProviderHandler(providerService, modelCatalogService, adapterFactory),
 		ModelConfig: handler.NewModelConfigHandler(modelConfigService, providerService, adapterFactory),
 		Chat:        handler.NewChatHandler(chatService),
-		Session:     handler.NewSessionHandler(sessionRepo, memoryRepo),
+		Session:     handler.NewSessionHandler(sessionRepo, memoryRepo, sessionService),
 		Memory:      handler.NewMemoryHandler(memoryService, summarizeService),
+		Usage:       handler.NewUsageHandler(usageService),
+		Persona:     handler.NewPersonaHandler(personaService),
+		Prompt:      handler.NewPromptHandler(promptService),
+		Workspace:   handler.NewWorkspaceHandler(workspaceService),
+		User:        handler.NewUserHandler(userService),
+		AccessToken: handler.NewAccessTokenHandler(accessTokenService),
+		Backup:      handler.NewBackupHandler(backupService),
+		Knowledge:   handler.NewKnowledgeTransferHandler(knowledgeTransfer),
+		History:     handler.NewHistoryImportHandler(historyImport, int64(cfg.Memory.ImportMaxUploadMB)<<20),
 	}
 
 	return deps, nil
 }
 
-// RegisterRoutes registers all API routes
-func RegisterRoutes(api *gin.RouterGroup, deps *Dependencies) {
+// newEncryptor creates the encryptor from the configured current and previous keys.
+// Passphrases are stretched with a salt kept next to the database.
+func newEncryptor(cfg *config.Config) (*crypto.Encryptor, error) {
+	secret := cfg.Encryption.Key
+	if secret == "" || secret == crypto.DefaultKey {
+		if cfg.Server.Mode == "release" {
+			return nil, fmt.Errorf("refusing to start in release mode with the default encryption key, set LLM_AGENT_ENCRYPTION_KEY")
+		}
+		log.Println("WARNING: Using the default encryption key. Set LLM_AGENT_ENCRYPTION_KEY in production.")
+		secret = crypto.DefaultKey
+	}
+
+	secrets := append([]string{secret}, cfg.Encryption.PreviousKeys...)
+	var salt []byte
+	for _, s := range secrets {
+		if crypto.IsPassphrase(s) {
+			if err := os.MkdirAll(filepath.Dir(cfg.Database.Path), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create database directory: %w", err)
+			}
+			var err error
+			salt, err = crypto.LoadOrCreateSalt(cfg.SaltPath())
+			if err != nil {
+				return nil, fmt.Errorf("failed to load encryption salt: %w", err)
+			}
+			break
+		}
+	}
+
+	keys := make([][]byte, len(secrets))
+	for i, s := range secrets {
+		key, err := crypto.ParseKey(s, salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key: %w", err)
+		}
+		keys[i] = key
+	}
+	return crypto.NewEncryptor(keys[0], keys[1:]...)
+}
+
+// RegisterRoutes registers all API routes. All but login require an authenticated
+// user when accounts are enabled; personal access tokens only reach the routes of
+// their scopes.
+func RegisterRoutes(public *gin.RouterGroup, deps *Dependencies) {
 	h := deps.Handlers
 
-	// Provider routes
-	providers := api.Group("/providers")
+	public.POST("/auth/login", h.User.Login)
+
+	api := public.Group("", handler.Authenticate(deps.UserService, deps.AccessTokenService))
+	chat := api.Group("", handler.RequireScope(model.ScopeChat))
+	knowledgeRead := api.Group("", handler.RequireScope(model.ScopeKnowledgeRead))
+	knowledgeWrite := api.Group("", handler.RequireScope(model.ScopeKnowledgeWrite))
+	full := api.Group("", handler.RequireScope(model.ScopeAdmin))
+	admin := full.Group("", handler.RequireAdmin())
+
+	// Auth routes
+	api.GET("/auth/me", h.User.Me)
+	api.POST("/auth/logout", h.User.Logout)
+	full.PUT("/auth/password", h.User.ChangePassword)
+
+	// Personal access token routes, only with accounts enabled
+	tokens := full.Group("/tokens", handler.RequireAuthEnabled(deps.UserService))
+	{
+		tokens.POST("", h.AccessToken.Create)
+		tokens.GET("", h.AccessToken.GetAll)
+		tokens.DELETE("/:id", h.AccessToken.Delete)
+	}
+
+	// User routes
+	users := admin.Group("/users")
+	{
+		users.POST("", h.User.Create)
+		users.GET("", h.User.GetAll)
+		users.PUT("/:id", h.User.Update)
+		users.DELETE("/:id", h.User.Delete)
+	}
+
+	// Provider routes, chat clients may list them to pick a model
+	chat.GET("/providers", h.Provider.GetAll)
+	chat.GET("/providers/:id", h.Provider.GetByID)
+	providers := full.Group("/providers")
 	{
 		providers.POST("", h.Provider.Create)
-		providers.GET("", h.Provider.GetAll)
-		providers.GET("/:id", h.Provider.GetByID)
 		providers.PUT("/:id", h.Provider.Update)
 		providers.DELETE("/:id", h.Provider.Delete)
 		providers.POST("/:id/test", h.Provider.Test)
+		providers.GET("/:id/models", h.Provider.ListModels)
 	}
 
 	// Model Config routes
-	models := api.Group("/models")
+	chat.GET("/models", h.ModelConfig.GetAll)
+	chat.GET("/models/:id", h.ModelConfig.GetByID)
+	models := full.Group("/models")
 	{
 		models.POST("", h.ModelConfig.Create)
-		models.GET("", h.ModelConfig.GetAll)
-		models.GET("/:id", h.ModelConfig.GetByID)
 		models.PUT("/:id", h.ModelConfig.Update)
 		models.DELETE("/:id", h.ModelConfig.Delete)
 		models.POST("/:id/test", h.ModelConfig.Test)
@@ -179,34 +373,92 @@ func RegisterRoutes(api *gin.RouterGroup, deps *Dependencies) {
 	}
 
 	// Chat routes
-	api.POST("/chat", h.Chat.Chat)
+	chat.POST("/chat", h.Chat.Chat)
 
 	// Session routes
-	sessions := api.Group("/sessions")
+	sessions := chat.Group("/sessions")
 	{
+		sessions.POST("", h.Session.Create)
 		sessions.GET("", h.Session.GetAll)
 		sessions.GET("/:id", h.Session.GetByID)
+		sessions.PUT("/:id", h.Session.Update)
 		sessions.DELETE("/:id", h.Session.Delete)
 		sessions.DELETE("/:id/messages/:messageId", h.Session.DeleteMessage)
+		sessions.POST("/:id/messages/:messageId/regenerate", h.Chat.Regenerate)
+		sessions.POST("/:id/messages/:messageId/edit", h.Chat.Edit)
+		sessions.PUT("/:id/branch", h.Session.SwitchBranch)
+		sessions.POST("/:id/fork", h.Session.Fork)
+		sessions.POST("/:id/merge", h.Session.Merge)
 		sessions.POST("/:id/summarize", h.Memory.Summarize)
 	}
 
-	// Memory routes
-	memories := api.Group("/memories")
+	// Chat history import routes
+	history := chat.Group("/history/import")
+	{
+		history.POST("", h.History.Import)
+		history.GET("/jobs", h.History.GetJobs)
+		history.GET("/jobs/:id", h.History.GetJob)
+		history.DELETE("/jobs/:id", h.History.CancelJob)
+	}
+
+	// Workspace routes
+	knowledgeRead.GET("/workspaces", h.Workspace.GetAll)
+	knowledgeRead.GET("/workspaces/:id", h.Workspace.GetByID)
+	workspaces := knowledgeWrite.Group("/workspaces")
+	{
+		workspaces.POST("", h.Workspace.Create)
+		workspaces.PUT("/:id", h.Workspace.Update)
+		workspaces.DELETE("/:id", h.Workspace.Delete)
+	}
+
+	// Persona routes
+	chat.GET("/personas", h.Persona.GetAll)
+	chat.GET("/personas/:id", h.Persona.GetByID)
+	personas := full.Group("/personas")
+	{
+		personas.POST("", h.Persona.Create)
+		personas.PUT("/:id", h.Persona.Update)
+		personas.DELETE("/:id", h.Persona.Delete)
+	}
+
+	// Prompt template routes, prompts are shared and only admins change them
+	prompts := chat.Group("/prompts")
+	{
+		prompts.GET("", h.Prompt.GetAll)
+		prompts.GET("/:id", h.Prompt.GetByID)
+		prompts.GET("/:id/versions", h.Prompt.GetVersions)
+		prompts.POST("/:id/render", h.Prompt.Render)
+	}
+	adminPrompts := admin.Group("/prompts")
 	{
-		memories.GET("/search", h.Memory.Search)
-		memories.POST("", h.Memory.Create)
+		adminPrompts.POST("", h.Prompt.Create)
+		adminPrompts.PUT("/:id", h.Prompt.Update)
+		adminPrompts.DELETE("/:id", h.Prompt.Delete)
+		adminPrompts.POST("/:id/activate", h.Prompt.Activate)
 	}
 
+	// Memory routes
+	knowledgeRead.GET("/memories/search", h.Memory.Search)
+	chat.POST("/memories", h.Memory.Create)
+
 	// Knowledge routes
-	knowledge := api.Group("/knowledge")
+	knowledgeRead.GET("/knowledge", h.Memory.GetAllKnowledge)
+	knowledgeRead.GET("/knowledge/export", h.Knowledge.Export)
+	knowledgeRead.GET("/knowledge/:id", h.Memory.GetKnowledge)
+	knowledge := knowledgeWrite.Group("/knowledge")
 	{
-		knowledge.GET("", h.Memory.GetAllKnowledge)
-		knowledge.GET("/:id", h.Memory.GetKnowledge)
 		knowledge.POST("", h.Memory.CreateKnowledge)
 		knowledge.PUT("/:id", h.Memory.UpdateKnowledge)
 		knowledge.DELETE("/:id", h.Memory.DeleteKnowledge)
+		knowledge.POST("/import", h.Knowledge.Import)
 	}
+
+	// Usage routes, usage is tracked for the whole deployment
+	admin.GET("/usage", h.Usage.Summary)
+
+	// Backup and restore of all data
+	admin.GET("/admin/backup", h.Backup.Backup)
+	admin.POST("/admin/restore", h.Backup.Restore)
 }
 
 // Close releases all resources
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAccessTokenNotFound = errors.New("access token not found")
+	ErrInvalidAccessToken  = errors.New("invalid access token")
+)
+
+// AccessTokenService handles personal access tokens
+type AccessTokenService struct {
+	repo     *repository.AccessTokenRepository
+	userRepo *repository.UserRepository
+}
+
+// NewAccessTokenService creates a new access token service
+func NewAccessTokenService(repo *repository.AccessTokenRepository, userRepo *repository.UserRepository) *AccessTokenService {
+	return &AccessTokenService{repo: repo, userRepo: userRepo}
+}
+
+// IsAccessToken returns true if the bearer token is a personal access token rather
+// than a login session
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, model.AccessTokenPrefix)
+}
+
+// Create creates a personal access token of a user. The token itself is returned
+// only here.
+func (s *AccessTokenService) Create(userID string, req *model.CreateAccessTokenRequest) (*model.CreateAccessTokenResponse, error) {
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAccessToken)
+	}
+	for _, scope := range req.Scopes {
+		if !validScope(scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAccessToken, scope)
+		}
+	}
+	if req.ExpiresInDays < 0 {
+		return nil, fmt.Errorf("%w: expires_in_days must not be negative", ErrInvalidAccessToken)
+	}
+
+	random, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	token := model.AccessTokenPrefix + random
+
+	accessToken := model.AccessToken{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: hashToken(token),
+		Hint:      token[:len(model.AccessTokenPrefix)+6],
+		Scopes:    req.Scopes,
+		CreatedAt: time.Now(),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		accessToken.ExpiresAt = &expiresAt
+	}
+	if err := s.repo.Create(&accessToken); err != nil {
+		return nil, fmt.Errorf("failed to create access token: %w", err)
+	}
+
+	return &model.CreateAccessTokenResponse{AccessToken: accessToken, Token: token}, nil
+}
+
+// GetAll returns the access tokens of a user
+func (s *AccessTokenService) GetAll(userID string) ([]model.AccessToken, error) {
+	return s.repo.GetAll(userID)
+}
+
+// Delete revokes an access token of a user
+func (s *AccessTokenService) Delete(userID, id string) error {
+	deleted, err := s.repo.Delete(userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete access token: %w", err)
+	}
+	if !deleted {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// Authenticate returns the user and the token of a personal access token
+func (s *AccessTokenService) Authenticate(token string) (*model.User, *model.AccessToken, error) {
+	accessToken, err := s.repo.GetByHash(hashToken(token))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+	if accessToken == nil {
+		return nil, nil, ErrUnauthenticated
+	}
+
+	user, err := s.userRepo.GetByID(accessToken.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || user.Disabled {
+		return nil, nil, ErrUnauthenticated
+	}
+
+	if err := s.repo.Touch(accessToken.ID, time.Now()); err != nil {
+		log.Printf("[AccessTokenService:Authenticate] Failed to record token use: %v", err)
+	}
+	return user, accessToken, nil
+}
+
+// validScope returns true if the scope is known
+func validScope(scope model.TokenScope) bool {
+	for _, s := range model.ValidTokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
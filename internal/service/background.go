@@ -0,0 +1,42 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/allwaysyou/llm-agent/internal/adapter"
+	"github.com/allwaysyou/llm-agent/internal/model"
+)
+
+// backgroundAdapter creates the adapter for background LLM calls of a user, such as
+// knowledge extraction and conflict detection: the given model config, or the user's
+// default chat config. The adapter is nil if there is no default chat config. It is not
+// metered yet, so that callers can attribute the usage to a session.
+func backgroundAdapter(modelConfigs *ModelConfigService, providers *ProviderService, factory *adapter.AdapterFactory, userID, configID string) (*model.ModelConfig, adapter.LLMAdapter, error) {
+	var modelConfig *model.ModelConfig
+	var err error
+	if configID != "" {
+		modelConfig, err = modelConfigs.GetByID(userID, configID)
+		if err == nil && modelConfig == nil {
+			return nil, nil, ErrModelConfigNotFound
+		}
+	} else {
+		modelConfig, err = modelConfigs.GetDefaultByType(userID, model.ConfigTypeChat)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get config: %w", err)
+	}
+	if modelConfig == nil || modelConfig.Provider == nil {
+		return nil, nil, nil
+	}
+
+	adapterCfg, err := providers.ModelAdapterConfig(modelConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	llm, err := factory.Create(modelConfig.Provider.Type, adapterCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create adapter: %w", err)
+	}
+	return modelConfig, providers.Redact(modelConfig.Provider, llm), nil
+}
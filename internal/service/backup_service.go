@@ -0,0 +1,403 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/config"
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/crypto"
+	"github.com/allwaysyou/llm-agent/internal/pkg/vector"
+	"github.com/allwaysyou/llm-agent/internal/repository"
+	"go.yaml.in/yaml/v3"
+)
+
+var (
+	ErrInvalidBackup     = errors.New("invalid backup archive")
+	ErrBackupPassphrase  = errors.New("invalid backup passphrase")
+	ErrBackupKeyMismatch = errors.New("backup is encrypted with a key that is not configured")
+)
+
+// Files of a backup archive
+const (
+	backupManifestFile = "manifest.json"
+	backupDatabaseFile = "llm.db"
+	backupVectorsFile  = "vectors.json"
+	backupConfigFile   = "config.yaml"
+	backupSaltFile     = "encryption.salt"
+)
+
+// BackupService creates and restores archives of all data: the database, the vector
+// store and the config
+type BackupService struct {
+	db          *repository.DB
+	vectorStore *vector.VectorStore
+	encryptor   *crypto.Encryptor
+	config      *config.Config
+}
+
+// NewBackupService creates a new backup service
+func NewBackupService(db *repository.DB, vectorStore *vector.VectorStore, encryptor *crypto.Encryptor, cfg *config.Config) *BackupService {
+	return &BackupService{db: db, vectorStore: vectorStore, encryptor: encryptor, config: cfg}
+}
+
+// Backup writes a zip archive of all data to w. Provider API keys (and content with
+// at-rest encryption) are encrypted with the current key, or with a key derived from
+// the passphrase if one is given, so that the archive can be restored on an install
+// with different keys.
+func (s *BackupService) Backup(w io.Writer, passphrase string) (*model.BackupManifest, error) {
+	dir, err := os.MkdirTemp("", "llm-agent-backup-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, backupDatabaseFile)
+	if err := s.db.Snapshot(dbPath); err != nil {
+		return nil, err
+	}
+
+	manifest := &model.BackupManifest{
+		FormatVersion: model.BackupFormatVersion,
+		CreatedAt:     time.Now(),
+		AtRest:        s.config.Encryption.AtRest,
+	}
+	archiveEncryptor := s.encryptor
+	if passphrase != "" {
+		salt, err := crypto.NewSalt()
+		if err != nil {
+			return nil, err
+		}
+		if archiveEncryptor, err = passphraseEncryptor(passphrase, salt); err != nil {
+			return nil, err
+		}
+		manifest.PassphraseSalt = hex.EncodeToString(salt)
+	}
+	manifest.KeyID = archiveEncryptor.CurrentKeyID()
+
+	if err := repository.RecryptSnapshot(dbPath, s.encryptor, archiveEncryptor, s.config.Encryption.AtRest); err != nil {
+		return nil, fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+
+	vectors, err := s.vectorStore.Snapshot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot vector store: %w", err)
+	}
+	if passphrase != "" || s.config.Encryption.AtRest {
+		encrypted, err := archiveEncryptor.Encrypt(string(vectors))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt vector store: %w", err)
+		}
+		vectors = []byte(encrypted)
+	}
+	manifest.Documents = s.vectorStore.Count()
+
+	zw := zip.NewWriter(w)
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeZipFile(zw, backupManifestFile, manifestData); err != nil {
+		return nil, err
+	}
+	dbData, err := os.Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer dbData.Close()
+	if err := copyZipFile(zw, backupDatabaseFile, dbData); err != nil {
+		return nil, err
+	}
+	if err := writeZipFile(zw, backupVectorsFile, vectors); err != nil {
+		return nil, err
+	}
+
+	if s.config.File != "" {
+		data, err := os.ReadFile(s.config.File)
+		if err == nil {
+			data, err = redactConfig(data)
+		}
+		if err != nil {
+			log.Printf("[BackupService:Backup] Skipping config file: %v", err)
+		} else if err := writeZipFile(zw, backupConfigFile, data); err != nil {
+			return nil, err
+		}
+	}
+
+	// A passphrase key of the install needs its salt, unless the archive has its own key
+	if passphrase == "" {
+		if salt, err := os.ReadFile(s.config.SaltPath()); err == nil {
+			if err := writeZipFile(zw, backupSaltFile, salt); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Restore replaces all data with the contents of the backup archive at path. The
+// passphrase is required for archives created with one; other archives must be
+// encrypted with one of the configured keys. The config file of the archive is not
+// applied.
+func (s *BackupService) Restore(path, passphrase string) (*model.RestoreResult, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{backupManifestFile, backupDatabaseFile, backupVectorsFile} {
+		if files[name] == nil {
+			return nil, fmt.Errorf("%w: %s is missing", ErrInvalidBackup, name)
+		}
+	}
+
+	var manifest model.BackupManifest
+	manifestData, err := readZipFile(files[backupManifestFile])
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > model.BackupFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalidBackup, manifest.FormatVersion)
+	}
+
+	archiveEncryptor := s.encryptor
+	if manifest.PassphraseSalt != "" {
+		if passphrase == "" {
+			return nil, fmt.Errorf("%w: the backup requires its passphrase", ErrBackupPassphrase)
+		}
+		salt, err := hex.DecodeString(manifest.PassphraseSalt)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid passphrase salt", ErrInvalidBackup)
+		}
+		if archiveEncryptor, err = passphraseEncryptor(passphrase, salt); err != nil {
+			return nil, err
+		}
+		if archiveEncryptor.CurrentKeyID() != manifest.KeyID {
+			return nil, fmt.Errorf("%w: wrong passphrase", ErrBackupPassphrase)
+		}
+	} else if !s.encryptor.HasKey(manifest.KeyID) {
+		// A passphrase key derives a different key from the salt of each install, so try
+		// the configured passphrases with the salt of the install the backup comes from
+		archiveEncryptor = nil
+		if files[backupSaltFile] != nil {
+			data, err := readZipFile(files[backupSaltFile])
+			if err != nil {
+				return nil, err
+			}
+			salt, err := crypto.ParseSalt(data)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+			}
+			if archiveEncryptor, err = s.saltedEncryptor(salt); err != nil {
+				return nil, err
+			}
+		}
+		if archiveEncryptor == nil || !archiveEncryptor.HasKey(manifest.KeyID) {
+			return nil, fmt.Errorf("%w: add key %s to encryption.previous_keys or create the backup with a passphrase", ErrBackupKeyMismatch, manifest.KeyID)
+		}
+	}
+
+	// Decode the vector store first, so that a broken archive leaves the data untouched
+	vectors, err := readZipFile(files[backupVectorsFile])
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(string(vectors), "v1:") {
+		plaintext, err := archiveEncryptor.Decrypt(string(vectors))
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to decrypt vector store: %v", ErrInvalidBackup, err)
+		}
+		vectors = []byte(plaintext)
+	}
+	if !json.Valid(vectors) {
+		return nil, fmt.Errorf("%w: invalid vector store", ErrInvalidBackup)
+	}
+
+	dir, err := os.MkdirTemp("", "llm-agent-restore-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, backupDatabaseFile)
+	if err := extractZipFile(files[backupDatabaseFile], dbPath); err != nil {
+		return nil, err
+	}
+	if err := repository.RecryptSnapshot(dbPath, archiveEncryptor, s.encryptor, s.config.Encryption.AtRest); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+
+	// Bring a backup of an older version up to the current schema
+	migrated, err := repository.NewDB(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	migrated.Close()
+
+	// Write the vector store next to its file first and swap it in as the database
+	// restore commits, so that a failure leaves both untouched
+	staged, err := s.vectorStore.StageRestore(vectors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore vector store: %w", err)
+	}
+	if err := s.db.RestoreFrom(dbPath, staged.Commit); err != nil {
+		staged.Discard()
+		return nil, err
+	}
+
+	log.Printf("[BackupService:Restore] Restored backup of %s", manifest.CreatedAt.Format(time.RFC3339))
+	return &model.RestoreResult{Manifest: manifest, Documents: s.vectorStore.Count()}, nil
+}
+
+// saltedEncryptor derives the configured passphrase keys with the salt of another
+// install, nil if no passphrase key is configured
+func (s *BackupService) saltedEncryptor(salt []byte) (*crypto.Encryptor, error) {
+	var keys [][]byte
+	for _, secret := range append([]string{s.config.Encryption.Key}, s.config.Encryption.PreviousKeys...) {
+		if secret == "" || !crypto.IsPassphrase(secret) {
+			continue
+		}
+		key, err := crypto.DeriveKey(secret, salt)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return crypto.NewEncryptor(keys[0], keys[1:]...)
+}
+
+// passphraseEncryptor creates the encryptor of an archive from its passphrase
+func passphraseEncryptor(passphrase string, salt []byte) (*crypto.Encryptor, error) {
+	key, err := crypto.DeriveKey(passphrase, salt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBackupPassphrase, err)
+	}
+	return crypto.NewEncryptor(key)
+}
+
+// redactConfig blanks the secrets of a config file: the encryption keys and the admin
+// password
+func redactConfig(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	for _, path := range [][]string{{"encryption", "key"}, {"auth", "admin_password"}} {
+		if node := yamlValue(&doc, path...); node != nil {
+			*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: yaml.DoubleQuotedStyle, LineComment: node.LineComment}
+		}
+	}
+	if node := yamlValue(&doc, "encryption", "previous_keys"); node != nil {
+		*node = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle, LineComment: node.LineComment}
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// yamlValue returns the node of a nested mapping key, nil if absent
+func yamlValue(node *yaml.Node, keys ...string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func copyZipFile(zw *zip.Writer, name string, r io.Reader) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	return data, nil
+}
+
+func extractZipFile(f *zip.File, path string) error {
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	defer r.Close()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	return out.Close()
+}
@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/allwaysyou/llm-agent/internal/adapter"
@@ -11,6 +13,7 @@ import (
 	"github.com/allwaysyou/llm-agent/internal/model"
 	"github.com/allwaysyou/llm-agent/internal/pkg/constants"
 	"github.com/allwaysyou/llm-agent/internal/pkg/memory"
+	"github.com/allwaysyou/llm-agent/internal/pkg/modelinfo"
 	"github.com/allwaysyou/llm-agent/internal/repository"
 	"github.com/google/uuid"
 )
@@ -20,102 +23,394 @@ type ChatService struct {
 	modelConfigService *ModelConfigService
 	providerService    *ProviderService
 	sessionRepo        *repository.SessionRepository
+	memoryRepo         *repository.MemoryRepository
+	personaRepo        *repository.PersonaRepository
+	workspaceService   *WorkspaceService
 	memoryManager      *memory.DefaultManager
 	adapterFactory     *adapter.AdapterFactory
+	usageService       *UsageService
 	llmConfig          config.LLMDefaults
 }
 
+// ErrInvalidParent is returned when a request continues from a message that is not
+// part of the session, or regenerates a reply to a message that is not a user message
+var ErrInvalidParent = errors.New("invalid parent message")
+
+// ErrMessageNotFound is returned when a message to edit or regenerate does not exist in the session
+var ErrMessageNotFound = errors.New("message not found")
+
 // NewChatService creates a new chat service
 func NewChatService(
 	modelConfigService *ModelConfigService,
 	providerService *ProviderService,
 	sessionRepo *repository.SessionRepository,
+	memoryRepo *repository.MemoryRepository,
+	personaRepo *repository.PersonaRepository,
+	workspaceService *WorkspaceService,
 	memoryManager *memory.DefaultManager,
 	adapterFactory *adapter.AdapterFactory,
+	usageService *UsageService,
 	llmCfg config.LLMDefaults,
 ) *ChatService {
 	return &ChatService{
 		modelConfigService: modelConfigService,
 		providerService:    providerService,
 		sessionRepo:        sessionRepo,
+		memoryRepo:         memoryRepo,
+		personaRepo:        personaRepo,
+		workspaceService:   workspaceService,
 		memoryManager:      memoryManager,
 		adapterFactory:     adapterFactory,
+		usageService:       usageService,
 		llmConfig:          llmCfg,
 	}
 }
 
-// getModelConfigAndAPIKey gets the model config and decrypts the API key
-func (s *ChatService) getModelConfigAndAPIKey(configID string, configType model.ConfigType) (providerType model.ProviderType, apiKey, baseURL, modelName string, maxTokens int, temperature float64, configIDOut string, err error) {
+// getModelConfig gets the requested model config, or the default one for the type,
+// among those visible to the user
+func (s *ChatService) getModelConfig(userID, configID string, configType model.ConfigType) (*model.ModelConfig, error) {
 	var modelConfig *model.ModelConfig
+	var err error
 	if configID != "" {
-		modelConfig, err = s.modelConfigService.GetByID(configID)
+		modelConfig, err = s.modelConfigService.GetByID(userID, configID)
 	} else {
-		modelConfig, err = s.modelConfigService.GetDefaultByType(configType)
+		modelConfig, err = s.modelConfigService.GetDefaultByType(userID, configType)
 	}
 
 	if err != nil {
-		return "", "", "", "", 0, 0, "", fmt.Errorf("failed to get config: %w", err)
+		return nil, fmt.Errorf("failed to get config: %w", err)
 	}
 	if modelConfig == nil || modelConfig.Provider == nil {
-		return "", "", "", "", 0, 0, "", fmt.Errorf("no LLM config available")
+		return nil, fmt.Errorf("no LLM config available")
 	}
+	return modelConfig, nil
+}
 
-	apiKey, err = s.providerService.GetDecryptedAPIKey(modelConfig.ProviderID)
+// chatCandidate is a model config that may serve a chat request
+type chatCandidate struct {
+	modelConfig *model.ModelConfig
+	limitOnly   bool // Only tried when the previous candidates hit their usage limits
+}
+
+// candidateConfigs returns the model configs to try in order: the requested (or default)
+// config followed by its fallback chain. When no config was requested explicitly, the
+// other enabled chat configs are appended as a last resort for exhausted usage limits.
+func (s *ChatService) candidateConfigs(userID, configID string) ([]chatCandidate, error) {
+	primary, err := s.getModelConfig(userID, configID, model.ConfigTypeChat)
 	if err != nil {
-		return "", "", "", "", 0, 0, "", fmt.Errorf("failed to decrypt API key: %w", err)
+		return nil, err
+	}
+
+	candidates := []chatCandidate{{modelConfig: primary}}
+	seen := map[string]bool{primary.ID: true}
+	for _, fallbackID := range primary.FallbackIDs {
+		if seen[fallbackID] {
+			continue
+		}
+		seen[fallbackID] = true
+
+		fallback, err := s.modelConfigService.GetByID(userID, fallbackID)
+		if err != nil || fallback == nil || fallback.Provider == nil || !fallback.Provider.Enabled {
+			log.Printf("[ChatService:candidateConfigs] Skipping unavailable fallback config %s", fallbackID)
+			continue
+		}
+		candidates = append(candidates, chatCandidate{modelConfig: fallback})
 	}
-	return modelConfig.Provider.Type, apiKey, modelConfig.Provider.BaseURL, modelConfig.Model, modelConfig.MaxTokens, modelConfig.Temperature, modelConfig.ID, nil
+
+	if configID == "" {
+		others, err := s.modelConfigService.GetByType(userID, model.ConfigTypeChat)
+		if err == nil {
+			for i := range others {
+				other := &others[i]
+				if seen[other.ID] || other.Provider == nil || !other.Provider.Enabled {
+					continue
+				}
+				seen[other.ID] = true
+				candidates = append(candidates, chatCandidate{modelConfig: other, limitOnly: true})
+			}
+		}
+	}
+
+	return candidates, nil
 }
 
-// Chat processes a chat request and returns a response
-func (s *ChatService) Chat(ctx context.Context, req *model.ChatRequest) (*model.ChatResponse, error) {
-	log.Printf("[ChatService:Chat] Starting - SessionID=%s, ConfigID=%s, MsgCount=%d",
-		req.SessionID, req.ConfigID, len(req.Messages))
+// withFailover calls fn with each candidate config in turn until one succeeds.
+// Retryable errors (network, 429, 5xx) fail over to the next candidate once the HTTP
+// client has given up retrying; any other error is returned immediately.
+func (s *ChatService) withFailover(ctx context.Context, candidates []chatCandidate, sampling *model.SamplingParams, fn func(modelConfig *model.ModelConfig, llm adapter.LLMAdapter) error) (*model.ModelConfig, adapter.LLMAdapter, error) {
+	var lastErr error
+	for _, candidate := range candidates {
+		modelConfig := candidate.modelConfig
+		if candidate.limitOnly && !isLimitError(lastErr) {
+			break
+		}
 
-	// Get LLM config
-	providerType, apiKey, baseURL, modelName, maxTokens, temperature, configID, err := s.getModelConfigAndAPIKey(req.ConfigID, model.ConfigTypeChat)
+		// The adapter is built first so that a config that cannot be used takes no RPM slot
+		llmAdapter, err := s.createAdapter(modelConfig, sampling)
+		if err != nil {
+			log.Printf("[ChatService:withFailover] Skipping config %s: %v", modelConfig.ID, err)
+			lastErr = err
+			continue
+		}
+
+		if err := s.usageService.CheckLimits(modelConfig, model.UsagePurposeChat); err != nil {
+			log.Printf("[ChatService:withFailover] Skipping config %s: %v", modelConfig.ID, err)
+			lastErr = err
+			continue
+		}
+
+		err = fn(modelConfig, llmAdapter)
+		if err == nil {
+			if lastErr != nil {
+				log.Printf("[ChatService:withFailover] Served by fallback config %s (%s)", modelConfig.ID, modelConfig.Model)
+			}
+			return modelConfig, llmAdapter, nil
+		}
+		if !adapter.CanFailover(err) {
+			return nil, nil, err
+		}
+		log.Printf("[ChatService:withFailover] Config %s (%s) failed: %v", modelConfig.ID, modelConfig.Model, err)
+		lastErr = err
+	}
+	return nil, nil, lastErr
+}
+
+func isLimitError(err error) bool {
+	return errors.Is(err, ErrBudgetExceeded) || errors.Is(err, ErrRateLimited)
+}
+
+// createAdapter creates an adapter for the model config, applying the request's
+// sampling overrides (if any) on top of the config's parameters
+func (s *ChatService) createAdapter(modelConfig *model.ModelConfig, sampling *model.SamplingParams) (adapter.LLMAdapter, error) {
+	adapterCfg, err := s.providerService.ModelAdapterConfig(modelConfig)
 	if err != nil {
-		log.Printf("[ChatService:Chat] Error getting config: %v", err)
 		return nil, err
 	}
-	log.Printf("[ChatService:Chat] Using config - ID=%s, Provider=%s, Model=%s",
-		configID, providerType, modelName)
-
-	// Create adapter
-	adapterCfg := adapter.AdapterConfig{
-		APIKey:      apiKey,
-		BaseURL:     baseURL,
-		Model:       modelName,
-		MaxTokens:   maxTokens,
-		Temperature: temperature,
+	if sampling != nil {
+		adapterCfg.Sampling = adapterCfg.Sampling.Merge(*sampling)
 	}
 
-	llmAdapter, err := s.adapterFactory.Create(providerType, adapterCfg)
+	llmAdapter, err := s.adapterFactory.Create(modelConfig.Provider.Type, adapterCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create adapter: %w", err)
 	}
+	return s.providerService.Redact(modelConfig.Provider, llmAdapter), nil
+}
+
+// requestConfigID returns the config requested for the chat, falling back to the
+// session's model override. An override whose config was deleted is ignored.
+func (s *ChatService) requestConfigID(session *model.Session, req *model.ChatRequest) string {
+	if req.ConfigID != "" || session == nil || session.OverrideConfigID == "" {
+		return req.ConfigID
+	}
+	override, err := s.modelConfigService.GetByID(session.UserID, session.OverrideConfigID)
+	if err != nil || override == nil {
+		log.Printf("[ChatService:requestConfigID] Session %s overrides unavailable config %s, using the default",
+			session.ID, session.OverrideConfigID)
+		return ""
+	}
+	return override.ID
+}
+
+// sessionSettings returns the system prompt and sampling overrides for a chat in the
+// session. The persona's prompt comes first, followed by the session's own prompt.
+// Sampling parameters take precedence in the order persona < session < request, all
+// on top of the model config's parameters.
+func (s *ChatService) sessionSettings(session *model.Session, requested *model.SamplingParams) (string, model.SamplingParams) {
+	var prompts []string
+	var sampling model.SamplingParams
+
+	if session.PersonaID != "" {
+		persona, err := s.personaRepo.GetByID(session.UserID, session.PersonaID)
+		if err != nil {
+			log.Printf("[ChatService:sessionSettings] Failed to get persona %s: %v", session.PersonaID, err)
+		} else if persona != nil {
+			if persona.SystemPrompt != "" {
+				prompts = append(prompts, persona.SystemPrompt)
+			}
+			sampling = persona.Sampling
+		}
+	}
+	if session.SystemPrompt != "" {
+		prompts = append(prompts, session.SystemPrompt)
+	}
+
+	sampling = sampling.Merge(session.Sampling)
+	if requested != nil {
+		sampling = sampling.Merge(*requested)
+	}
+	return strings.Join(prompts, "\n\n"), sampling
+}
+
+// resolveParent returns the message a request continues from: the requested parent, or
+// the end of the session's active branch. Nil means the conversation starts fresh.
+// A request without messages regenerates the reply to its parent, which must be a user message.
+func (s *ChatService) resolveParent(session *model.Session, req *model.ChatRequest) (*model.Memory, error) {
+	regenerate := len(req.Messages) == 0
+	parentID := req.ParentID
+	if parentID == "" {
+		if regenerate {
+			return nil, fmt.Errorf("%w: a parent message is required to regenerate", ErrInvalidParent)
+		}
+		parentID = session.ActiveLeafID
+	}
+	if parentID == model.RootMessageID {
+		if regenerate {
+			return nil, fmt.Errorf("%w: a parent message is required to regenerate", ErrInvalidParent)
+		}
+		return nil, nil
+	}
+	if parentID == "" {
+		return nil, nil
+	}
+
+	parent, err := s.memoryRepo.GetByID(parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent message: %w", err)
+	}
+	if parent == nil || parent.SessionID != session.ID {
+		return nil, fmt.Errorf("%w: message %s not found in session", ErrInvalidParent, parentID)
+	}
+	if regenerate && parent.Role != model.RoleUser {
+		return nil, fmt.Errorf("%w: can only regenerate the reply to a user message", ErrInvalidParent)
+	}
+	return parent, nil
+}
+
+// sessionMessage gets a message and checks that it belongs to a session of the user
+func (s *ChatService) sessionMessage(userID, sessionID, messageID string) (*model.Memory, error) {
+	session, err := s.sessionRepo.GetByID(userID, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	message, err := s.memoryRepo.GetByID(messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	if message == nil || message.SessionID != sessionID {
+		return nil, ErrMessageNotFound
+	}
+	return message, nil
+}
+
+// RegenerateRequest builds a chat request that generates another reply on a new branch.
+// For an assistant message the user message it answered is replied to again; for a
+// user message a new reply to it is generated.
+func (s *ChatService) RegenerateRequest(userID, sessionID, messageID string, req *model.RegenerateRequest) (*model.ChatRequest, error) {
+	message, err := s.sessionMessage(userID, sessionID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID := message.ID
+	if message.Role == model.RoleAssistant {
+		parentID = message.ParentID
+	}
+	if parentID == "" {
+		return nil, fmt.Errorf("%w: message has no user message to reply to", ErrInvalidParent)
+	}
+
+	return &model.ChatRequest{
+		SessionID: sessionID,
+		ConfigID:  req.ConfigID,
+		ParentID:  parentID,
+		Stream:    req.Stream,
+		Sampling:  req.Sampling,
+	}, nil
+}
+
+// EditRequest builds a chat request that sends the edited content of a user message
+// as a sibling of the original, starting a new branch
+func (s *ChatService) EditRequest(userID, sessionID, messageID string, req *model.EditMessageRequest) (*model.ChatRequest, error) {
+	message, err := s.sessionMessage(userID, sessionID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Role != model.RoleUser {
+		return nil, fmt.Errorf("%w: only user messages can be edited", ErrInvalidParent)
+	}
 
-	// Get or create session
+	parentID := message.ParentID
+	if parentID == "" {
+		parentID = model.RootMessageID
+	}
+
+	return &model.ChatRequest{
+		SessionID: sessionID,
+		ConfigID:  req.ConfigID,
+		ParentID:  parentID,
+		Messages:  []model.Message{{Role: model.RoleUser, Content: req.Content}},
+		Stream:    req.Stream,
+		Sampling:  req.Sampling,
+	}, nil
+}
+
+// Chat processes a chat request of a user and returns a response
+func (s *ChatService) Chat(ctx context.Context, userID string, req *model.ChatRequest) (*model.ChatResponse, error) {
+	log.Printf("[ChatService:Chat] Starting - SessionID=%s, ConfigID=%s, MsgCount=%d",
+		req.SessionID, req.ConfigID, len(req.Messages))
+
+	// Get existing session
 	var session *model.Session
+	var err error
 	if req.SessionID != "" {
-		session, err = s.sessionRepo.GetByID(req.SessionID)
+		session, err = s.sessionRepo.GetByID(userID, req.SessionID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get session: %w", err)
 		}
 	}
 
+	// Get LLM config and its fallback chain
+	candidates, err := s.candidateConfigs(userID, s.requestConfigID(session, req))
+	if err != nil {
+		log.Printf("[ChatService:Chat] Error getting config: %v", err)
+		return nil, err
+	}
+
+	// Knowledge is scoped to the session's workspace, or the requested one for a new session
+	workspaceID := req.WorkspaceID
+	if session != nil {
+		workspaceID = session.WorkspaceID
+	}
+	scope, err := s.workspaceService.Scope(userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create session
 	if session == nil {
 		session = &model.Session{
-			ID:        uuid.New().String(),
-			Title:     generateTitle(req.Messages, s.llmConfig.TitleMaxLength),
-			ConfigID:  configID,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			ID:          uuid.New().String(),
+			UserID:      userID,
+			Title:       generateTitle(req.Messages, s.llmConfig.TitleMaxLength),
+			ConfigID:    candidates[0].modelConfig.ID,
+			WorkspaceID: scope.WorkspaceID,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
 		}
 		if err := s.sessionRepo.Create(session); err != nil {
 			return nil, fmt.Errorf("failed to create session: %w", err)
 		}
+	} else if session.ActiveLeafID == "" && session.Title == constants.DefaultSessionTitle {
+		// Session created up front with its settings, title it after the first message
+		session.Title = generateTitle(req.Messages, s.llmConfig.TitleMaxLength)
+	}
+	systemPrompt, sampling := s.sessionSettings(session, req.Sampling)
+
+	// Continue from the requested message or the end of the active branch
+	parent, err := s.resolveParent(session, req)
+	if err != nil {
+		return nil, err
 	}
+	parentID := ""
+	if parent != nil {
+		parentID = parent.ID
+	}
+	regenerate := len(req.Messages) == 0
 
 	// Build messages with context (includes semantic search for long-term memory)
 	query := ""
@@ -125,14 +420,35 @@ func (s *ChatService) Chat(ctx context.Context, req *model.ChatRequest) (*model.
 			break
 		}
 	}
-	log.Printf("[ChatService:Chat] Building context - SessionID=%s, Query='%.50s...'", session.ID, query)
-	contextMessages, _ := s.memoryManager.BuildContext(ctx, session.ID, query)
-	messages := append(contextMessages, req.Messages...)
+	if regenerate {
+		query = parent.Content
+	}
+	log.Printf("[ChatService:Chat] Building context - SessionID=%s, ParentID=%s, Query='%.50s...'", session.ID, parentID, query)
+	contextMessages, err := s.memoryManager.BuildContext(ctx, session.ID, parentID, query, scope)
+	if err != nil && regenerate {
+		// The message being replied to comes from the stored history
+		return nil, fmt.Errorf("failed to build context: %w", err)
+	}
+	var messages []model.Message
+	if systemPrompt != "" {
+		// The session's instructions come before the memory context
+		messages = append(messages, model.Message{Role: model.RoleSystem, Content: systemPrompt})
+	}
+	messages = append(messages, contextMessages...)
+	messages = append(messages, req.Messages...)
+	keepLast := max(len(req.Messages), 1)
 	log.Printf("[ChatService:Chat] Context built - ContextMsgs=%d, TotalMsgs=%d", len(contextMessages), len(messages))
 
-	// Call LLM
+	// Call LLM, failing over along the chain
 	log.Printf("[ChatService:Chat] Calling LLM...")
-	resp, err := llmAdapter.Chat(ctx, messages)
+	var resp *model.ChatResponse
+	modelConfig, llmAdapter, err := s.withFailover(ctx, candidates, &sampling, func(mc *model.ModelConfig, llm adapter.LLMAdapter) error {
+		log.Printf("[ChatService:Chat] Using config - ID=%s, Provider=%s, Model=%s",
+			mc.ID, mc.Provider.Type, mc.Model)
+		var err error
+		resp, err = llm.Chat(ctx, fitContextWindow(mc, llm, messages, keepLast))
+		return err
+	})
 	if err != nil {
 		log.Printf("[ChatService:Chat] LLM call failed: %v", err)
 		return nil, fmt.Errorf("LLM chat failed: %w", err)
@@ -140,27 +456,47 @@ func (s *ChatService) Chat(ctx context.Context, req *model.ChatRequest) (*model.
 	log.Printf("[ChatService:Chat] LLM response received - ContentLen=%d", len(resp.Message.Content))
 
 	resp.SessionID = session.ID
+	resp.ConfigID = modelConfig.ID
+	resp.Model = modelConfig.Model
+	resp.Provider = modelConfig.Provider.Type
 
-	// Save user messages via MemoryManager (generates embeddings)
+	// Save user messages via MemoryManager (generates embeddings), chained onto the branch
 	log.Printf("[ChatService:Chat] Saving user messages...")
-	for _, msg := range req.Messages {
-		if _, err := s.memoryManager.SaveConversationMemory(ctx, session.ID, msg.Role, msg.Content); err != nil {
-			log.Printf("[ChatService:Chat] Failed to save user memory: %v", err)
-		}
-	}
+	leafID := s.saveRequestMessages(ctx, session, parentID, req.Messages)
 
 	// Save assistant response via MemoryManager (generates embeddings)
 	log.Printf("[ChatService:Chat] Saving assistant response...")
-	if _, err := s.memoryManager.SaveConversationMemory(ctx, session.ID, model.RoleAssistant, resp.Message.Content); err != nil {
+	assistantMemory, err := s.memoryManager.SaveConversationMemory(ctx, memory.SaveMemoryOptions{
+		SessionID: session.ID,
+		UserID:    session.UserID,
+		ParentID:  leafID,
+		Role:      model.RoleAssistant,
+		Content:   resp.Message.Content,
+		Reasoning: resp.Message.Reasoning,
+		ConfigID:  modelConfig.ID,
+		Usage:     resp.Usage,
+	})
+	if err != nil {
 		log.Printf("[ChatService:Chat] Failed to save assistant memory: %v", err)
 	}
 
-	// Extract and save knowledge asynchronously (only if key signals detected)
-	if memory.ShouldTriggerExtraction(query) {
+	// Record token usage for cost accounting
+	memoryID := ""
+	if assistantMemory != nil {
+		memoryID = assistantMemory.ID
+		leafID = assistantMemory.ID
+	}
+	resp.MessageID = memoryID
+	s.usageService.Record(modelConfig, session.ID, memoryID, model.UsagePurposeChat, resp.Usage)
+
+	// Extract and save knowledge asynchronously (only if key signals detected).
+	// A regenerated reply answers a user message that was already processed.
+	if !regenerate && memory.ShouldTriggerExtraction(query) {
 		log.Printf("[ChatService:Chat] Key signals detected, starting async knowledge extraction...")
+		meteredAdapter := s.usageService.Meter(llmAdapter, modelConfig, session.ID)
 		go func() {
 			log.Printf("[ChatService:Chat:Async] ProcessConversation starting...")
-			if err := s.memoryManager.ProcessConversation(context.Background(), session.ID, query, resp.Message.Content, llmAdapter); err != nil {
+			if err := s.memoryManager.ProcessConversation(context.Background(), session.ID, scope, query, resp.Message.Content, meteredAdapter); err != nil {
 				log.Printf("[ChatService:Chat:Async] Failed to extract knowledge: %v", err)
 			} else {
 				log.Printf("[ChatService:Chat:Async] ProcessConversation completed")
@@ -170,7 +506,8 @@ func (s *ChatService) Chat(ctx context.Context, req *model.ChatRequest) (*model.
 		log.Printf("[ChatService:Chat] No key signals detected, skipping knowledge extraction")
 	}
 
-	// Update session timestamp
+	// Update session timestamp and make the new reply the active branch
+	session.ActiveLeafID = leafID
 	session.UpdatedAt = time.Now()
 	_ = s.sessionRepo.Update(session)
 
@@ -178,55 +515,68 @@ func (s *ChatService) Chat(ctx context.Context, req *model.ChatRequest) (*model.
 	return resp, nil
 }
 
-// ChatStream processes a chat request and returns a streaming response
-func (s *ChatService) ChatStream(ctx context.Context, req *model.ChatRequest) (<-chan model.StreamChunk, string, error) {
+// ChatStream processes a chat request of a user and returns a streaming response
+func (s *ChatService) ChatStream(ctx context.Context, userID string, req *model.ChatRequest) (<-chan model.StreamChunk, *model.ChatStreamInfo, error) {
 	log.Printf("[ChatService:ChatStream] Starting - SessionID=%s, ConfigID=%s, MsgCount=%d",
 		req.SessionID, req.ConfigID, len(req.Messages))
 
-	// Get LLM config
-	providerType, apiKey, baseURL, modelName, maxTokens, temperature, configID, err := s.getModelConfigAndAPIKey(req.ConfigID, model.ConfigTypeChat)
+	// Get existing session
+	var session *model.Session
+	var err error
+	if req.SessionID != "" {
+		session, err = s.sessionRepo.GetByID(userID, req.SessionID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+	}
+
+	// Get LLM config and its fallback chain
+	candidates, err := s.candidateConfigs(userID, s.requestConfigID(session, req))
 	if err != nil {
 		log.Printf("[ChatService:ChatStream] Error getting config: %v", err)
-		return nil, "", err
+		return nil, nil, err
 	}
-	log.Printf("[ChatService:ChatStream] Using config - ID=%s, Provider=%s, Model=%s",
-		configID, providerType, modelName)
 
-	// Create adapter
-	adapterCfg := adapter.AdapterConfig{
-		APIKey:      apiKey,
-		BaseURL:     baseURL,
-		Model:       modelName,
-		MaxTokens:   maxTokens,
-		Temperature: temperature,
+	// Knowledge is scoped to the session's workspace, or the requested one for a new session
+	workspaceID := req.WorkspaceID
+	if session != nil {
+		workspaceID = session.WorkspaceID
 	}
-
-	llmAdapter, err := s.adapterFactory.Create(providerType, adapterCfg)
+	scope, err := s.workspaceService.Scope(userID, workspaceID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create adapter: %w", err)
-	}
-
-	// Get or create session
-	var session *model.Session
-	if req.SessionID != "" {
-		session, err = s.sessionRepo.GetByID(req.SessionID)
-		if err != nil {
-			return nil, "", fmt.Errorf("failed to get session: %w", err)
-		}
+		return nil, nil, err
 	}
 
+	// Create session
 	if session == nil {
 		session = &model.Session{
-			ID:        uuid.New().String(),
-			Title:     generateTitle(req.Messages, s.llmConfig.TitleMaxLength),
-			ConfigID:  configID,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			ID:          uuid.New().String(),
+			UserID:      userID,
+			Title:       generateTitle(req.Messages, s.llmConfig.TitleMaxLength),
+			ConfigID:    candidates[0].modelConfig.ID,
+			WorkspaceID: scope.WorkspaceID,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
 		}
 		if err := s.sessionRepo.Create(session); err != nil {
-			return nil, "", fmt.Errorf("failed to create session: %w", err)
+			return nil, nil, fmt.Errorf("failed to create session: %w", err)
 		}
+	} else if session.ActiveLeafID == "" && session.Title == constants.DefaultSessionTitle {
+		// Session created up front with its settings, title it after the first message
+		session.Title = generateTitle(req.Messages, s.llmConfig.TitleMaxLength)
 	}
+	systemPrompt, sampling := s.sessionSettings(session, req.Sampling)
+
+	// Continue from the requested message or the end of the active branch
+	parent, err := s.resolveParent(session, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	parentID := ""
+	if parent != nil {
+		parentID = parent.ID
+	}
+	regenerate := len(req.Messages) == 0
 
 	// Build messages with context (includes semantic search for long-term memory)
 	query := ""
@@ -236,25 +586,49 @@ func (s *ChatService) ChatStream(ctx context.Context, req *model.ChatRequest) (<
 			break
 		}
 	}
-	log.Printf("[ChatService:ChatStream] Building context - SessionID=%s, Query='%.50s...'", session.ID, query)
-	contextMessages, _ := s.memoryManager.BuildContext(ctx, session.ID, query)
-	messages := append(contextMessages, req.Messages...)
+	if regenerate {
+		query = parent.Content
+	}
+	log.Printf("[ChatService:ChatStream] Building context - SessionID=%s, ParentID=%s, Query='%.50s...'", session.ID, parentID, query)
+	contextMessages, err := s.memoryManager.BuildContext(ctx, session.ID, parentID, query, scope)
+	if err != nil && regenerate {
+		// The message being replied to comes from the stored history
+		return nil, nil, fmt.Errorf("failed to build context: %w", err)
+	}
+	var messages []model.Message
+	if systemPrompt != "" {
+		// The session's instructions come before the memory context
+		messages = append(messages, model.Message{Role: model.RoleSystem, Content: systemPrompt})
+	}
+	messages = append(messages, contextMessages...)
+	messages = append(messages, req.Messages...)
+	keepLast := max(len(req.Messages), 1)
 	log.Printf("[ChatService:ChatStream] Context built - ContextMsgs=%d, TotalMsgs=%d", len(contextMessages), len(messages))
 
-	// Save user messages via MemoryManager (generates embeddings)
+	// Save user messages via MemoryManager (generates embeddings), chained onto the branch
 	log.Printf("[ChatService:ChatStream] Saving user messages...")
-	for _, msg := range req.Messages {
-		if _, err := s.memoryManager.SaveConversationMemory(ctx, session.ID, msg.Role, msg.Content); err != nil {
-			log.Printf("[ChatService:ChatStream] Failed to save user memory: %v", err)
-		}
+	leafID := s.saveRequestMessages(ctx, session, parentID, req.Messages)
+	if leafID != session.ActiveLeafID {
+		// The reply is attached once the stream completes; until then the branch ends here
+		session.ActiveLeafID = leafID
+		_ = s.sessionRepo.Update(session)
 	}
 
-	// Call LLM with streaming
+	// Call LLM with streaming, failing over along the chain until a stream is opened
 	log.Printf("[ChatService:ChatStream] Starting LLM stream...")
-	stream, err := llmAdapter.ChatStream(ctx, messages)
+	var stream <-chan model.StreamChunk
+	var sent []model.Message
+	modelConfig, llmAdapter, err := s.withFailover(ctx, candidates, &sampling, func(mc *model.ModelConfig, llm adapter.LLMAdapter) error {
+		log.Printf("[ChatService:ChatStream] Using config - ID=%s, Provider=%s, Model=%s",
+			mc.ID, mc.Provider.Type, mc.Model)
+		var err error
+		sent = fitContextWindow(mc, llm, messages, keepLast)
+		stream, err = llm.ChatStream(ctx, sent)
+		return err
+	})
 	if err != nil {
 		log.Printf("[ChatService:ChatStream] LLM stream failed: %v", err)
-		return nil, "", fmt.Errorf("LLM chat stream failed: %w", err)
+		return nil, nil, fmt.Errorf("LLM chat stream failed: %w", err)
 	}
 
 	// Wrap stream to save response
@@ -262,28 +636,62 @@ func (s *ChatService) ChatStream(ctx context.Context, req *model.ChatRequest) (<
 	go func() {
 		defer close(outCh)
 
-		var fullContent string
+		var fullContent, fullReasoning string
 		var saved bool
 		for chunk := range stream {
 			outCh <- chunk
 			fullContent += chunk.Delta
+			fullReasoning += chunk.Reasoning
 
 			if chunk.Done && !saved {
 				saved = true
 				log.Printf("[ChatService:ChatStream:Async] Stream done - ContentLen=%d", len(fullContent))
 
+				// Not every provider reports usage when streaming, fall back to an estimate
+				usage := chunk.Usage
+				if usage == nil {
+					usage = estimateUsage(llmAdapter, sent, fullReasoning+fullContent)
+				}
+
+				// A stopped answer is incomplete, keep only the usage it cost
+				if chunk.Error != "" {
+					log.Printf("[ChatService:ChatStream:Async] Stream stopped by the provider, not saving the reply: %s", chunk.Error)
+					s.usageService.Record(modelConfig, session.ID, "", model.UsagePurposeChat, usage)
+					continue
+				}
+
 				// Save assistant response via MemoryManager (generates embeddings)
 				log.Printf("[ChatService:ChatStream:Async] Saving assistant response...")
-				if _, err := s.memoryManager.SaveConversationMemory(context.Background(), session.ID, model.RoleAssistant, fullContent); err != nil {
+				assistantMemory, err := s.memoryManager.SaveConversationMemory(context.Background(), memory.SaveMemoryOptions{
+					SessionID: session.ID,
+					UserID:    session.UserID,
+					ParentID:  leafID,
+					Role:      model.RoleAssistant,
+					Content:   fullContent,
+					Reasoning: fullReasoning,
+					ConfigID:  modelConfig.ID,
+					Usage:     usage,
+				})
+				if err != nil {
 					log.Printf("[ChatService:ChatStream:Async] Failed to save assistant memory: %v", err)
 				}
 
-				// Extract and save knowledge asynchronously (only if key signals detected)
-				if memory.ShouldTriggerExtraction(query) {
+				// Record token usage for cost accounting
+				memoryID := ""
+				if assistantMemory != nil {
+					memoryID = assistantMemory.ID
+					leafID = assistantMemory.ID
+				}
+				s.usageService.Record(modelConfig, session.ID, memoryID, model.UsagePurposeChat, usage)
+
+				// Extract and save knowledge asynchronously (only if key signals detected).
+				// A regenerated reply answers a user message that was already processed.
+				if !regenerate && memory.ShouldTriggerExtraction(query) {
 					log.Printf("[ChatService:ChatStream:Async] Key signals detected, starting knowledge extraction...")
+					meteredAdapter := s.usageService.Meter(llmAdapter, modelConfig, session.ID)
 					go func(userQuery, assistantResp string) {
 						log.Printf("[ChatService:ChatStream:Async:Knowledge] ProcessConversation starting...")
-						if err := s.memoryManager.ProcessConversation(context.Background(), session.ID, userQuery, assistantResp, llmAdapter); err != nil {
+						if err := s.memoryManager.ProcessConversation(context.Background(), session.ID, scope, userQuery, assistantResp, meteredAdapter); err != nil {
 							log.Printf("[ChatService:ChatStream:Async:Knowledge] Failed: %v", err)
 						} else {
 							log.Printf("[ChatService:ChatStream:Async:Knowledge] ProcessConversation completed")
@@ -293,7 +701,8 @@ func (s *ChatService) ChatStream(ctx context.Context, req *model.ChatRequest) (<
 					log.Printf("[ChatService:ChatStream:Async] No key signals detected, skipping knowledge extraction")
 				}
 
-				// Update session
+				// Update session and make the new reply the active branch
+				session.ActiveLeafID = leafID
 				session.UpdatedAt = time.Now()
 				_ = s.sessionRepo.Update(session)
 			}
@@ -301,7 +710,84 @@ func (s *ChatService) ChatStream(ctx context.Context, req *model.ChatRequest) (<
 	}()
 
 	log.Printf("[ChatService:ChatStream] Stream started - SessionID=%s", session.ID)
-	return outCh, session.ID, nil
+	return outCh, &model.ChatStreamInfo{
+		SessionID: session.ID,
+		ParentID:  leafID,
+		ConfigID:  modelConfig.ID,
+		Model:     modelConfig.Model,
+		Provider:  modelConfig.Provider.Type,
+	}, nil
+}
+
+// saveRequestMessages saves the request's messages as a chain below parentID and
+// returns the ID of the last one saved (parentID if none were)
+func (s *ChatService) saveRequestMessages(ctx context.Context, session *model.Session, parentID string, messages []model.Message) string {
+	leafID := parentID
+	for _, msg := range messages {
+		saved, err := s.memoryManager.SaveConversationMemory(ctx, memory.SaveMemoryOptions{
+			SessionID: session.ID,
+			UserID:    session.UserID,
+			ParentID:  leafID,
+			Role:      msg.Role,
+			Content:   msg.Content,
+		})
+		if err != nil {
+			log.Printf("[ChatService:saveRequestMessages] Failed to save user memory: %v", err)
+			continue
+		}
+		leafID = saved.ID
+	}
+	return leafID
+}
+
+// fitContextWindow drops the oldest history messages until the prompt leaves room for
+// max_tokens of completion within the model's context window. System messages and the
+// last keepLast messages (the request itself) are always kept; history is cut so that
+// it starts with a user turn. Models with an unknown context window are not trimmed.
+func fitContextWindow(mc *model.ModelConfig, llm adapter.LLMAdapter, messages []model.Message, keepLast int) []model.Message {
+	window := modelinfo.Capabilities(mc).ContextWindow
+	if window == 0 {
+		return messages
+	}
+	budget := window - mc.MaxTokens
+
+	total := 0
+	for _, msg := range messages {
+		total += llm.CountTokens(msg.Content)
+	}
+	if total <= budget {
+		return messages
+	}
+
+	fitted := make([]model.Message, 0, len(messages))
+	dropped := 0
+	trimming := true
+	for i, msg := range messages {
+		history := msg.Role != model.RoleSystem && i < len(messages)-keepLast
+		if history && trimming && (total > budget || msg.Role != model.RoleUser) {
+			total -= llm.CountTokens(msg.Content)
+			dropped++
+			continue
+		}
+		if history {
+			trimming = false
+		}
+		fitted = append(fitted, msg)
+	}
+
+	log.Printf("[ChatService:fitContextWindow] Dropped %d history messages to fit %s context window (%d tokens, ~%d used)",
+		dropped, mc.Model, window, total)
+	return fitted
+}
+
+// estimateUsage estimates token usage with the adapter's tokenizer
+func estimateUsage(llm adapter.LLMAdapter, messages []model.Message, completion string) *model.Usage {
+	usage := &model.Usage{CompletionTokens: llm.CountTokens(completion)}
+	for _, msg := range messages {
+		usage.PromptTokens += llm.CountTokens(msg.Content)
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
 }
 
 // generateTitle generates a title from the first message
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/adapter"
+	"github.com/allwaysyou/llm-agent/internal/config"
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/chatimport"
+	"github.com/allwaysyou/llm-agent/internal/pkg/embedding"
+	"github.com/allwaysyou/llm-agent/internal/pkg/memory"
+	"github.com/allwaysyou/llm-agent/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidHistory        = errors.New("invalid chat history export")
+	ErrExtractionUnavailable = errors.New("knowledge extraction needs an embedding provider and a chat model config")
+	ErrExtractionJobNotFound = errors.New("extraction job not found")
+)
+
+// rateLimitBackoff is how long an extraction job waits when a usage limit or the
+// provider reports too many requests
+const rateLimitBackoff = time.Minute
+
+// HistoryImportService imports the chat history of other assistants' data exports and
+// extracts knowledge from it in the background
+type HistoryImportService struct {
+	sessionRepo        *repository.SessionRepository
+	workspaces         *WorkspaceService
+	memoryManager      memory.Manager
+	embedProvider      embedding.Provider
+	modelConfigService *ModelConfigService
+	providerService    *ProviderService
+	adapterFactory     *adapter.AdapterFactory
+	usageService       *UsageService
+	memoryConfig       config.MemoryConfig
+	llmConfig          config.LLMDefaults
+
+	mu   sync.Mutex
+	jobs map[string]*extractionJob // Extraction jobs since startup, by ID
+}
+
+// extractionJob is a running or finished extraction job
+type extractionJob struct {
+	model.ExtractionJob
+	cancel context.CancelFunc
+}
+
+// extractionTurn is a user message and the reply to it
+type extractionTurn struct {
+	sessionID     string
+	userMsg       string
+	assistantResp string
+}
+
+// NewHistoryImportService creates a new history import service
+func NewHistoryImportService(
+	sessionRepo *repository.SessionRepository,
+	workspaces *WorkspaceService,
+	memoryManager memory.Manager,
+	embedProvider embedding.Provider,
+	modelConfigService *ModelConfigService,
+	providerService *ProviderService,
+	adapterFactory *adapter.AdapterFactory,
+	usageService *UsageService,
+	memoryCfg config.MemoryConfig,
+	llmCfg config.LLMDefaults,
+) *HistoryImportService {
+	return &HistoryImportService{
+		sessionRepo:        sessionRepo,
+		workspaces:         workspaces,
+		memoryManager:      memoryManager,
+		embedProvider:      embedProvider,
+		modelConfigService: modelConfigService,
+		providerService:    providerService,
+		adapterFactory:     adapterFactory,
+		usageService:       usageService,
+		memoryConfig:       memoryCfg,
+		llmConfig:          llmCfg,
+		jobs:               make(map[string]*extractionJob),
+	}
+}
+
+// Import creates a session in the workspace ("" = global pool) for each conversation of a
+// ChatGPT or Claude export, keeping titles, timestamps and branches. Conversations the
+// user imported before are skipped. If extract is set, knowledge is extracted from the
+// turns of the imported conversations by a background job using the given model config,
+// or the default chat config.
+func (s *HistoryImportService) Import(ctx context.Context, userID string, r io.ReaderAt, size int64, workspaceID string, extract bool, configID string) (*model.HistoryImportResult, error) {
+	scope, err := s.workspaces.Scope(userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	source, conversations, err := chatimport.Parse(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHistory, err)
+	}
+
+	var modelConfig *model.ModelConfig
+	var llm adapter.LLMAdapter
+	if extract {
+		if s.embedProvider == nil {
+			return nil, ErrExtractionUnavailable
+		}
+		modelConfig, llm, err = backgroundAdapter(s.modelConfigService, s.providerService, s.adapterFactory, userID, configID)
+		if err != nil {
+			return nil, err
+		}
+		if llm == nil {
+			return nil, ErrExtractionUnavailable
+		}
+	}
+
+	imported, err := s.sessionRepo.GetImportedFrom(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get imported sessions: %w", err)
+	}
+
+	result := &model.HistoryImportResult{Source: string(source), Conversations: len(conversations)}
+	var turns []extractionTurn
+	for i := range conversations {
+		conv := &conversations[i]
+		importedFrom := ""
+		if conv.ID != "" {
+			importedFrom = string(source) + ":" + conv.ID
+		}
+		if len(conv.Messages) == 0 || imported[importedFrom] {
+			result.Skipped++
+			continue
+		}
+
+		session, messages := s.buildSession(userID, workspaceID, importedFrom, conv)
+		if err := s.sessionRepo.CreateWithMessages(session, messages); err != nil {
+			log.Printf("[HistoryImport:Import] Failed to import conversation %s: %v", conv.ID, err)
+			result.Errors = append(result.Errors, fmt.Sprintf("conversation %d (%s): %v", i+1, session.Title, err))
+			continue
+		}
+		if importedFrom != "" {
+			imported[importedFrom] = true
+		}
+		result.Imported++
+		result.Messages += len(messages)
+
+		if extract {
+			turns = append(turns, branchTurns(session, messages)...)
+		}
+	}
+	log.Printf("[HistoryImport:Import] Imported %d of %d %s conversations (%d messages), skipped %d",
+		result.Imported, result.Conversations, source, result.Messages, result.Skipped)
+
+	if len(turns) > 0 {
+		result.Job = s.startExtraction(userID, scope, turns, modelConfig, llm)
+	}
+	return result, nil
+}
+
+// buildSession converts a conversation to a session of the user and its messages
+func (s *HistoryImportService) buildSession(userID, workspaceID, importedFrom string, conv *chatimport.Conversation) (*model.Session, []model.Memory) {
+	session := &model.Session{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		Title:        conv.Title,
+		WorkspaceID:  workspaceID,
+		CreatedAt:    conv.CreatedAt,
+		UpdatedAt:    conv.UpdatedAt,
+		ImportedFrom: importedFrom,
+	}
+
+	ids := make(map[string]string, len(conv.Messages)) // New ID of each message
+	messages := make([]model.Memory, 0, len(conv.Messages))
+	for _, m := range conv.Messages {
+		id := uuid.New().String()
+		ids[m.ID] = id
+		messages = append(messages, model.Memory{
+			ID:        id,
+			SessionID: session.ID,
+			UserID:    userID,
+			ParentID:  ids[m.ParentID],
+			Role:      m.Role,
+			Content:   m.Content,
+			Reasoning: m.Reasoning,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+
+	session.ActiveLeafID = ids[conv.CurrentID]
+	if session.ActiveLeafID == "" {
+		session.ActiveLeafID = messages[len(messages)-1].ID
+	}
+	if session.Title == "" {
+		var firstUser []model.Message
+		for _, m := range messages {
+			if m.Role == model.RoleUser {
+				firstUser = []model.Message{{Role: m.Role, Content: m.Content}}
+				break
+			}
+		}
+		session.Title = generateTitle(firstUser, s.llmConfig.TitleMaxLength)
+	}
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = messages[0].CreatedAt
+	}
+	if session.UpdatedAt.IsZero() {
+		for _, m := range messages {
+			if m.CreatedAt.After(session.UpdatedAt) {
+				session.UpdatedAt = m.CreatedAt
+			}
+		}
+	}
+	for i := range messages {
+		if messages[i].CreatedAt.IsZero() {
+			messages[i].CreatedAt = session.CreatedAt
+		}
+	}
+	return session, messages
+}
+
+// branchTurns returns the turns of the session's active branch worth extracting
+// knowledge from, chosen like those of live chats
+func branchTurns(session *model.Session, messages []model.Memory) []extractionTurn {
+	byID := make(map[string]*model.Memory, len(messages))
+	for i := range messages {
+		byID[messages[i].ID] = &messages[i]
+	}
+	var branch []*model.Memory
+	for m := byID[session.ActiveLeafID]; m != nil; m = byID[m.ParentID] {
+		branch = append(branch, m)
+	}
+
+	// The branch runs from the leaf to the root
+	var turns []extractionTurn
+	for i := len(branch) - 1; i > 0; i-- {
+		user, reply := branch[i], branch[i-1]
+		if user.Role != model.RoleUser || reply.Role != model.RoleAssistant || !memory.ShouldTriggerExtraction(user.Content) {
+			continue
+		}
+		turns = append(turns, extractionTurn{sessionID: session.ID, userMsg: user.Content, assistantResp: reply.Content})
+	}
+	return turns
+}
+
+// startExtraction starts a background job extracting knowledge from the turns
+func (s *HistoryImportService) startExtraction(userID string, scope memory.Scope, turns []extractionTurn, modelConfig *model.ModelConfig, llm adapter.LLMAdapter) *model.ExtractionJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &extractionJob{
+		ExtractionJob: model.ExtractionJob{
+			ID:          uuid.New().String(),
+			UserID:      userID,
+			WorkspaceID: scope.WorkspaceID,
+			Status:      model.ExtractionRunning,
+			Turns:       len(turns),
+			CreatedAt:   time.Now(),
+		},
+		cancel: cancel,
+	}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	snapshot := job.ExtractionJob
+	s.mu.Unlock()
+
+	log.Printf("[HistoryImport:Extract] Starting job %s - Turns=%d, RPM=%d", job.ID, len(turns), s.memoryConfig.ImportExtractionRPM)
+	go s.runExtraction(ctx, job, scope, turns, modelConfig, llm)
+	return &snapshot
+}
+
+// runExtraction processes the turns of a job one after another, at most
+// ImportExtractionRPM per minute. The job waits while a usage limit or the provider
+// rate limits it, and stops once a budget is used up.
+func (s *HistoryImportService) runExtraction(ctx context.Context, job *extractionJob, scope memory.Scope, turns []extractionTurn, modelConfig *model.ModelConfig, llm adapter.LLMAdapter) {
+	defer job.cancel()
+	ticker := time.NewTicker(time.Minute / time.Duration(s.memoryConfig.ImportExtractionRPM))
+	defer ticker.Stop()
+
+	status, message := model.ExtractionCompleted, ""
+loop:
+	for i := 0; i < len(turns); {
+		turn := turns[i]
+		metered := s.usageService.Meter(llm, modelConfig, turn.sessionID)
+		err := s.memoryManager.ProcessConversation(ctx, turn.sessionID, scope, turn.userMsg, turn.assistantResp, metered)
+		if ctx.Err() != nil {
+			status = model.ExtractionCanceled
+			break
+		}
+		var apiErr *adapter.APIError
+		if errors.Is(err, ErrBudgetExceeded) || (errors.As(err, &apiErr) && apiErr.Kind == adapter.ErrorKindQuota) {
+			// Retrying cannot succeed until the budget or quota is raised
+			status, message = model.ExtractionFailed, err.Error()
+			break
+		}
+
+		wait := ticker.C
+		if errors.Is(err, ErrRateLimited) || (errors.As(err, &apiErr) && apiErr.Kind == adapter.ErrorKindRateLimit) {
+			// Retry the same turn
+			log.Printf("[HistoryImport:Extract] Job %s rate limited, retrying turn %d in %s: %v", job.ID, i+1, rateLimitBackoff, err)
+			wait = time.After(rateLimitBackoff)
+		} else {
+			if err != nil {
+				log.Printf("[HistoryImport:Extract] Job %s failed to process turn %d: %v", job.ID, i+1, err)
+			}
+			s.mu.Lock()
+			job.Processed++
+			if err != nil {
+				job.Failed++
+			}
+			s.mu.Unlock()
+			if i++; i == len(turns) {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			status = model.ExtractionCanceled
+			break loop
+		case <-wait:
+		}
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	job.Status = status
+	job.Error = message
+	job.FinishedAt = &now
+	log.Printf("[HistoryImport:Extract] Job %s %s - Processed=%d/%d, Failed=%d", job.ID, status, job.Processed, job.Turns, job.Failed)
+	s.mu.Unlock()
+}
+
+// GetJobs returns the extraction jobs of a user since startup, newest first
+func (s *HistoryImportService) GetJobs(userID string) []model.ExtractionJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]model.ExtractionJob, 0)
+	for _, job := range s.jobs {
+		if job.UserID == userID {
+			jobs = append(jobs, job.ExtractionJob)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// GetJob returns an extraction job of a user
+func (s *HistoryImportService) GetJob(userID, id string) (*model.ExtractionJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return nil, ErrExtractionJobNotFound
+	}
+	snapshot := job.ExtractionJob
+	return &snapshot, nil
+}
+
+// CancelJob stops a running extraction job of a user after the turn in progress.
+// Knowledge extracted so far is kept.
+func (s *HistoryImportService) CancelJob(userID, id string) (*model.ExtractionJob, error) {
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok || job.UserID != userID {
+		return nil, ErrExtractionJobNotFound
+	}
+
+	job.cancel()
+	return s.GetJob(userID, id)
+}
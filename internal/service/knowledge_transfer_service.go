@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/allwaysyou/llm-agent/internal/adapter"
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/knowledgeio"
+	"github.com/allwaysyou/llm-agent/internal/pkg/memory"
+	"github.com/allwaysyou/llm-agent/internal/pkg/vector"
+	"github.com/allwaysyou/llm-agent/internal/repository"
+)
+
+// ErrInvalidKnowledgeFile is returned when an imported knowledge file cannot be read
+var ErrInvalidKnowledgeFile = errors.New("invalid knowledge file")
+
+// KnowledgeTransferService exports and imports a user's knowledge in portable formats
+type KnowledgeTransferService struct {
+	knowledgeRepo      *repository.KnowledgeRepository
+	workspaces         *WorkspaceService
+	vectorStore        *vector.VectorStore
+	memoryManager      *memory.DefaultManager
+	modelConfigService *ModelConfigService
+	providerService    *ProviderService
+	adapterFactory     *adapter.AdapterFactory
+	usageService       *UsageService
+	maxRecords         int // Max records of an imported file
+}
+
+// NewKnowledgeTransferService creates a new knowledge transfer service
+func NewKnowledgeTransferService(
+	knowledgeRepo *repository.KnowledgeRepository,
+	workspaces *WorkspaceService,
+	vectorStore *vector.VectorStore,
+	memoryManager *memory.DefaultManager,
+	modelConfigService *ModelConfigService,
+	providerService *ProviderService,
+	adapterFactory *adapter.AdapterFactory,
+	usageService *UsageService,
+	maxRecords int,
+) *KnowledgeTransferService {
+	return &KnowledgeTransferService{
+		knowledgeRepo:      knowledgeRepo,
+		workspaces:         workspaces,
+		vectorStore:        vectorStore,
+		memoryManager:      memoryManager,
+		modelConfigService: modelConfigService,
+		providerService:    providerService,
+		adapterFactory:     adapterFactory,
+		usageService:       usageService,
+		maxRecords:         maxRecords,
+	}
+}
+
+// Export writes the user's knowledge, or that of a workspace ("" = global pool), to w and
+// returns the number of entries written. Superseded entries are included unless
+// activeOnly is set, so that supersession chains survive the round trip.
+func (s *KnowledgeTransferService) Export(ctx context.Context, userID string, workspaceID *string, activeOnly bool, format knowledgeio.Format, w io.Writer) (int, error) {
+	if workspaceID != nil {
+		if _, err := s.workspaces.Scope(userID, *workspaceID); err != nil {
+			return 0, err
+		}
+	}
+
+	var knowledge []model.Knowledge
+	var err error
+	if activeOnly {
+		knowledge, err = s.knowledgeRepo.GetAllActive(userID, workspaceID, 0)
+	} else {
+		knowledge, err = s.knowledgeRepo.GetAll(userID, workspaceID, 0)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get knowledge: %w", err)
+	}
+
+	records := make([]model.KnowledgeRecord, 0, len(knowledge))
+	for _, k := range knowledge {
+		rec := model.KnowledgeRecord{
+			ID:           k.ID,
+			Content:      k.Content,
+			WorkspaceID:  k.WorkspaceID,
+			SupersededBy: k.SupersededBy,
+			Tier:         k.Tier,
+			HitCount:     k.HitCount,
+			LastHitAt:    k.LastHitAt,
+			PromotedAt:   k.PromotedAt,
+			CreatedAt:    k.CreatedAt,
+			UpdatedAt:    k.UpdatedAt,
+		}
+		if doc, ok := s.vectorStore.Get(k.ID); ok && doc.MetaData != nil {
+			rec.Category = model.KnowledgeCategory(doc.MetaData.Category)
+			rec.Source = model.KnowledgeSource(doc.MetaData.Source)
+			rec.Importance = doc.MetaData.Importance
+		}
+		records = append(records, rec)
+	}
+
+	if err := knowledgeio.Write(w, format, records); err != nil {
+		return 0, fmt.Errorf("failed to write knowledge: %w", err)
+	}
+	return len(records), nil
+}
+
+// Import reads knowledge records from r and adds them to the user's knowledge. Records go
+// to the given workspace ("" = global pool), or if none is given to the workspace they
+// name when the user has it, else to the global pool. Duplicates are detected with the
+// given model config, or the default chat config; without one, only records with the
+// same content as existing knowledge count as duplicates. The import runs within the
+// request, so files with more than the configured number of records are rejected and
+// canceling the request stops it.
+func (s *KnowledgeTransferService) Import(ctx context.Context, userID string, r io.Reader, format knowledgeio.Format, workspaceID *string, configID string) (*model.KnowledgeImportResult, error) {
+	records, err := knowledgeio.Read(r, format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKnowledgeFile, err)
+	}
+	if len(records) > s.maxRecords {
+		return nil, fmt.Errorf("%w: %d records, at most %d can be imported at once", ErrInvalidKnowledgeFile, len(records), s.maxRecords)
+	}
+
+	if workspaceID != nil {
+		if _, err := s.workspaces.Scope(userID, *workspaceID); err != nil {
+			return nil, err
+		}
+	}
+	owned := make(map[string]bool)
+	for i := range records {
+		rec := &records[i]
+		if workspaceID != nil {
+			rec.WorkspaceID = *workspaceID
+			continue
+		}
+		if rec.WorkspaceID == "" {
+			continue
+		}
+		ok, seen := owned[rec.WorkspaceID]
+		if !seen {
+			_, err := s.workspaces.Scope(userID, rec.WorkspaceID)
+			if err != nil && !errors.Is(err, ErrWorkspaceNotFound) {
+				return nil, err
+			}
+			ok = err == nil
+			owned[rec.WorkspaceID] = ok
+		}
+		if !ok {
+			rec.WorkspaceID = ""
+		}
+	}
+
+	llm, err := s.conflictAdapter(userID, configID)
+	if err != nil {
+		return nil, err
+	}
+	return s.memoryManager.ImportKnowledge(ctx, userID, records, llm), nil
+}
+
+// conflictAdapter creates the adapter used to detect conflicts, nil if the user has no
+// default chat config
+func (s *KnowledgeTransferService) conflictAdapter(userID, configID string) (adapter.LLMAdapter, error) {
+	modelConfig, llm, err := backgroundAdapter(s.modelConfigService, s.providerService, s.adapterFactory, userID, configID)
+	if err != nil {
+		return nil, err
+	}
+	if llm == nil {
+		log.Printf("[KnowledgeTransfer:Import] No LLM config available, only exact duplicates are detected")
+		return nil, nil
+	}
+	return s.usageService.Meter(llm, modelConfig, ""), nil
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/config"
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/repository"
+)
+
+// Errors returned when a usage limit is reached
+var (
+	ErrBudgetExceeded error = limitError("usage budget exceeded")
+	ErrRateLimited    error = limitError("rate limit exceeded")
+)
+
+// ErrMixedCurrency is returned when a provider cost budget would cover model configs
+// priced in different currencies, whose costs cannot be added up
+var ErrMixedCurrency = errors.New("model configs of a provider with a cost budget must use the same currency")
+
+// limitError is a usage limit error, recognized by adapter.IsLimitError
+type limitError string
+
+func (e limitError) Error() string { return string(e) }
+
+// UsageLimit marks the error as a usage limit
+func (e limitError) UsageLimit() bool { return true }
+
+// LimitService enforces the budgets and rate limits of providers and model configs
+type LimitService struct {
+	usageRepo       *repository.UsageRepository
+	backgroundRatio float64
+
+	mu       sync.Mutex
+	requests map[string][]time.Time // Request times within the last minute, by scope key
+}
+
+// limitScope is a provider or model config whose limits apply to a call
+type limitScope struct {
+	key        string
+	name       string
+	providerID string
+	configID   string
+	limits     model.UsageLimits
+}
+
+// NewLimitService creates a new limit service
+func NewLimitService(usageRepo *repository.UsageRepository, llmCfg config.LLMDefaults) *LimitService {
+	return &LimitService{
+		usageRepo:       usageRepo,
+		backgroundRatio: llmCfg.BackgroundBudgetRatio,
+		requests:        make(map[string][]time.Time),
+	}
+}
+
+// Check verifies that a call for the given purpose may be made with the model config.
+// Background calls are held back once backgroundRatio of any limit is used, so that
+// interactive chat keeps working up to the full limit.
+// A successful check counts as one request towards the RPM limits.
+func (s *LimitService) Check(modelConfig *model.ModelConfig, purpose model.UsagePurpose) error {
+	ratio := 1.0
+	if purpose.IsBackground() {
+		ratio = s.backgroundRatio
+	}
+
+	var scopes []limitScope
+	if modelConfig.Provider != nil {
+		scopes = append(scopes, limitScope{
+			key:        "provider:" + modelConfig.ProviderID,
+			name:       "provider " + modelConfig.Provider.Name,
+			providerID: modelConfig.ProviderID,
+			limits:     modelConfig.Provider.Limits,
+		})
+	}
+	scopes = append(scopes, limitScope{
+		key:      "config:" + modelConfig.ID,
+		name:     "model " + modelConfig.Model,
+		configID: modelConfig.ID,
+		limits:   modelConfig.Limits,
+	})
+
+	for _, scope := range scopes {
+		if err := s.checkBudget(scope, ratio); err != nil {
+			return err
+		}
+	}
+	return s.acquire(scopes, ratio)
+}
+
+// checkBudget compares the usage of the current day and month with the scope's budgets
+func (s *LimitService) checkBudget(scope limitScope, ratio float64) error {
+	limits := scope.limits
+	if !limits.HasBudget() {
+		return nil
+	}
+
+	now := time.Now()
+	if limits.DailyTokens > 0 || limits.DailyCost > 0 {
+		dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		totals, err := s.usageRepo.TotalsSince(scope.providerID, scope.configID, dayStart)
+		if err != nil {
+			return fmt.Errorf("failed to get usage: %w", err)
+		}
+		if err := checkTotals(scope.name, "daily", totals, limits.DailyTokens, limits.DailyCost, ratio); err != nil {
+			return err
+		}
+	}
+	if limits.MonthlyTokens > 0 || limits.MonthlyCost > 0 {
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		totals, err := s.usageRepo.TotalsSince(scope.providerID, scope.configID, monthStart)
+		if err != nil {
+			return fmt.Errorf("failed to get usage: %w", err)
+		}
+		if err := checkTotals(scope.name, "monthly", totals, limits.MonthlyTokens, limits.MonthlyCost, ratio); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkTotals(name, period string, totals model.UsageTotals, maxTokens int64, maxCost, ratio float64) error {
+	if maxTokens > 0 && float64(totals.Tokens) >= float64(maxTokens)*ratio {
+		return fmt.Errorf("%w: %s %s token budget used (%d/%d)", ErrBudgetExceeded, name, period, totals.Tokens, maxTokens)
+	}
+	if maxCost > 0 && totals.Cost >= maxCost*ratio {
+		return fmt.Errorf("%w: %s %s cost budget used (%.4f/%.4f)", ErrBudgetExceeded, name, period, totals.Cost, maxCost)
+	}
+	return nil
+}
+
+// acquire takes a request slot in every scope with an RPM limit, or none if any is full
+func (s *LimitService) acquire(scopes []limitScope, ratio float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-time.Minute)
+	for _, scope := range scopes {
+		rpm := scope.limits.RequestsPerMinute
+		if rpm <= 0 {
+			continue
+		}
+
+		recent := s.requests[scope.key]
+		for len(recent) > 0 && recent[0].Before(cutoff) {
+			recent = recent[1:]
+		}
+		s.requests[scope.key] = recent
+
+		if float64(len(recent)) >= float64(rpm)*ratio {
+			return fmt.Errorf("%w: %s allows %d requests per minute", ErrRateLimited, scope.name, rpm)
+		}
+	}
+
+	for _, scope := range scopes {
+		if scope.limits.RequestsPerMinute > 0 {
+			s.requests[scope.key] = append(s.requests[scope.key], now)
+		}
+	}
+	return nil
+}
+
+// checkBudgetCurrency verifies that a provider with a cost budget prices all its model configs in one currency
+func checkBudgetCurrency(provider *model.Provider, configs []model.ModelConfig) error {
+	if provider.Limits.DailyCost <= 0 && provider.Limits.MonthlyCost <= 0 {
+		return nil
+	}
+	for _, config := range configs {
+		if config.Currency != configs[0].Currency {
+			return fmt.Errorf("%w: provider %s has configs in %s and %s", ErrMixedCurrency, provider.Name, configs[0].Currency, config.Currency)
+		}
+	}
+	return nil
+}
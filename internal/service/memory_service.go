@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -14,11 +15,15 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrKnowledgeNotFound is returned when a knowledge entry does not exist
+var ErrKnowledgeNotFound = errors.New("knowledge not found")
+
 // MemoryService handles memory storage and retrieval with semantic search
 type MemoryService struct {
 	memoryRepo    *repository.MemoryRepository
 	knowledgeRepo *repository.KnowledgeRepository
 	sessionRepo   *repository.SessionRepository
+	workspaces    *WorkspaceService
 	vectorStore   *vector.VectorStore
 	embedProvider embedding.Provider
 	config        config.MemoryConfig
@@ -29,6 +34,7 @@ func NewMemoryService(
 	memoryRepo *repository.MemoryRepository,
 	knowledgeRepo *repository.KnowledgeRepository,
 	sessionRepo *repository.SessionRepository,
+	workspaces *WorkspaceService,
 	vectorStore *vector.VectorStore,
 	embedProvider embedding.Provider,
 	cfg config.MemoryConfig,
@@ -37,17 +43,29 @@ func NewMemoryService(
 		memoryRepo:    memoryRepo,
 		knowledgeRepo: knowledgeRepo,
 		sessionRepo:   sessionRepo,
+		workspaces:    workspaces,
 		vectorStore:   vectorStore,
 		embedProvider: embedProvider,
 		config:        cfg,
 	}
 }
 
-// SaveMemory saves a conversation memory
-func (s *MemoryService) SaveMemory(ctx context.Context, sessionID string, role model.MessageRole, content string) (*model.Memory, error) {
+// SaveMemory saves a conversation memory at the end of the active branch of a session
+// of the user
+func (s *MemoryService) SaveMemory(ctx context.Context, userID, sessionID string, role model.MessageRole, content string) (*model.Memory, error) {
+	session, err := s.sessionRepo.GetByID(userID, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
 	memory := &model.Memory{
 		ID:        uuid.New().String(),
 		SessionID: sessionID,
+		UserID:    session.UserID,
+		ParentID:  session.ActiveLeafID,
 		Role:      role,
 		Content:   content,
 		CreatedAt: time.Now(),
@@ -57,15 +75,40 @@ func (s *MemoryService) SaveMemory(ctx context.Context, sessionID string, role m
 		return nil, fmt.Errorf("failed to save memory: %w", err)
 	}
 
+	if err := s.sessionRepo.UpdateActiveLeaf(sessionID, memory.ID); err != nil {
+		return nil, fmt.Errorf("failed to update active branch: %w", err)
+	}
+
 	return memory, nil
 }
 
-// SearchMemories searches for relevant knowledge using semantic similarity
-func (s *MemoryService) SearchMemories(ctx context.Context, query string, sessionID string, limit int) ([]model.KnowledgeSearchResult, error) {
+// SearchMemories searches the user's knowledge using semantic similarity. The search
+// covers the knowledge a chat in the workspace (or else the session's workspace) sees,
+// or all of the user's knowledge if neither is given.
+func (s *MemoryService) SearchMemories(ctx context.Context, userID, query string, sessionID string, workspaceID *string, limit int) ([]model.KnowledgeSearchResult, error) {
 	if s.embedProvider == nil {
 		return nil, fmt.Errorf("embedding provider not configured")
 	}
 
+	if workspaceID == nil && sessionID != "" {
+		session, err := s.sessionRepo.GetByID(userID, sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		if session == nil {
+			return nil, ErrSessionNotFound
+		}
+		workspaceID = &session.WorkspaceID
+	}
+	var workspaceIDs []string
+	if workspaceID != nil {
+		scope, err := s.workspaces.Scope(userID, *workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		workspaceIDs = scope.WorkspaceIDs()
+	}
+
 	if limit <= 0 {
 		limit = s.config.DefaultSearchLimit
 	}
@@ -79,8 +122,10 @@ func (s *MemoryService) SearchMemories(ctx context.Context, query string, sessio
 	// Search in vector store (knowledge only)
 	// Use context_relevance_threshold to filter out low-relevance results
 	filter := &vector.SearchFilter{
-		ActiveOnly: true,
-		MinScore:   s.config.ContextRelevanceThreshold,
+		UserID:       &userID,
+		WorkspaceIDs: workspaceIDs,
+		ActiveOnly:   true,
+		MinScore:     s.config.ContextRelevanceThreshold,
 	}
 	results := s.vectorStore.Search(queryEmb, limit, filter)
 
@@ -92,7 +137,7 @@ func (s *MemoryService) SearchMemories(ctx context.Context, query string, sessio
 			continue
 		}
 
-		knowledge, err := s.knowledgeRepo.GetByID(r.Document.ID)
+		knowledge, err := s.knowledgeRepo.GetByID(userID, r.Document.ID)
 		if err != nil || knowledge == nil {
 			continue
 		}
@@ -107,30 +152,31 @@ func (s *MemoryService) SearchMemories(ctx context.Context, query string, sessio
 	return searchResults, nil
 }
 
-// GetAllKnowledge returns all knowledge entries
-func (s *MemoryService) GetAllKnowledge(ctx context.Context, activeOnly bool, limit int) ([]model.Knowledge, error) {
+// GetAllKnowledge returns all knowledge entries of the user, or those of a workspace
+// ("" = global pool)
+func (s *MemoryService) GetAllKnowledge(ctx context.Context, userID string, workspaceID *string, activeOnly bool, limit int) ([]model.Knowledge, error) {
 	if limit <= 0 {
 		limit = 100
 	}
 	if activeOnly {
-		return s.knowledgeRepo.GetAllActive(limit)
+		return s.knowledgeRepo.GetAllActive(userID, workspaceID, limit)
 	}
-	return s.knowledgeRepo.GetAll(limit)
+	return s.knowledgeRepo.GetAll(userID, workspaceID, limit)
 }
 
-// GetKnowledge returns a single knowledge entry by ID
-func (s *MemoryService) GetKnowledge(ctx context.Context, id string) (*model.Knowledge, error) {
-	return s.knowledgeRepo.GetByID(id)
+// GetKnowledge returns a single knowledge entry of the user by ID
+func (s *MemoryService) GetKnowledge(ctx context.Context, userID, id string) (*model.Knowledge, error) {
+	return s.knowledgeRepo.GetByID(userID, id)
 }
 
-// UpdateKnowledge updates a knowledge entry
-func (s *MemoryService) UpdateKnowledge(ctx context.Context, id string, content string) (*model.Knowledge, error) {
-	knowledge, err := s.knowledgeRepo.GetByID(id)
+// UpdateKnowledge updates a knowledge entry of the user
+func (s *MemoryService) UpdateKnowledge(ctx context.Context, userID, id string, content string) (*model.Knowledge, error) {
+	knowledge, err := s.knowledgeRepo.GetByID(userID, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get knowledge: %w", err)
 	}
 	if knowledge == nil {
-		return nil, fmt.Errorf("knowledge not found")
+		return nil, ErrKnowledgeNotFound
 	}
 
 	knowledge.Content = content
@@ -144,16 +190,23 @@ func (s *MemoryService) UpdateKnowledge(ctx context.Context, id string, content
 	if s.embedProvider != nil {
 		emb, err := s.embedProvider.GetEmbedding(ctx, content)
 		if err == nil {
+			metadata := &vector.DocumentMetadata{
+				Role:     constants.RoleKnowledge,
+				Source:   "manual",
+				IsActive: true,
+			}
+			if existing, ok := s.vectorStore.Get(id); ok && existing.MetaData != nil {
+				metadata = existing.MetaData
+			}
+			metadata.UserID = knowledge.UserID
+			metadata.WorkspaceID = knowledge.WorkspaceID
+
 			s.vectorStore.Delete(id)
 			doc := vector.Document{
 				ID:        id,
 				Content:   content,
 				Embedding: emb,
-				MetaData: &vector.DocumentMetadata{
-					Role:     constants.RoleKnowledge,
-					Source:   "manual",
-					IsActive: true,
-				},
+				MetaData:  metadata,
 			}
 			s.vectorStore.Add(doc)
 		}
@@ -162,26 +215,41 @@ func (s *MemoryService) UpdateKnowledge(ctx context.Context, id string, content
 	return knowledge, nil
 }
 
-// DeleteKnowledge deletes a knowledge entry
-func (s *MemoryService) DeleteKnowledge(ctx context.Context, id string) error {
+// DeleteKnowledge deletes a knowledge entry of the user
+func (s *MemoryService) DeleteKnowledge(ctx context.Context, userID, id string) error {
+	knowledge, err := s.knowledgeRepo.GetByID(userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to get knowledge: %w", err)
+	}
+	if knowledge == nil {
+		return ErrKnowledgeNotFound
+	}
+
 	// Delete from vector store first
 	s.vectorStore.Delete(id)
 
 	// Delete from database
-	if err := s.knowledgeRepo.Delete(id); err != nil {
+	if err := s.knowledgeRepo.Delete(userID, id); err != nil {
 		return fmt.Errorf("failed to delete knowledge: %w", err)
 	}
 
 	return nil
 }
 
-// CreateKnowledge creates a new knowledge entry manually
-func (s *MemoryService) CreateKnowledge(ctx context.Context, content string) (*model.Knowledge, error) {
+// CreateKnowledge creates a new knowledge entry of the user manually in a workspace
+// ("" = global pool)
+func (s *MemoryService) CreateKnowledge(ctx context.Context, userID, content, workspaceID string) (*model.Knowledge, error) {
+	if _, err := s.workspaces.Scope(userID, workspaceID); err != nil {
+		return nil, err
+	}
+
 	knowledge := &model.Knowledge{
-		ID:        uuid.New().String(),
-		Content:   content,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Content:     content,
+		WorkspaceID: workspaceID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 
 	if err := s.knowledgeRepo.Create(knowledge); err != nil {
@@ -197,9 +265,11 @@ func (s *MemoryService) CreateKnowledge(ctx context.Context, content string) (*m
 				Content:   content,
 				Embedding: emb,
 				MetaData: &vector.DocumentMetadata{
-					Role:     constants.RoleKnowledge,
-					Source:   "manual",
-					IsActive: true,
+					UserID:      userID,
+					WorkspaceID: workspaceID,
+					Role:        constants.RoleKnowledge,
+					Source:      "manual",
+					IsActive:    true,
 				},
 			}
 			s.vectorStore.Add(doc)
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/adapter"
+	"github.com/allwaysyou/llm-agent/internal/model"
+)
+
+const (
+	modelCatalogTTL = 10 * time.Minute
+
+	// catalogPlaceholderModel satisfies adapter constructors that require a model
+	// (Azure, custom); listing does not depend on it
+	catalogPlaceholderModel = "catalog"
+)
+
+// ErrModelListingUnsupported is returned when a provider's adapter cannot list models
+var ErrModelListingUnsupported = errors.New("model listing is not supported by this provider")
+
+// catalogEntry is a cached model list, valid while the provider is unchanged
+type catalogEntry struct {
+	models          []model.ModelInfo
+	fetchedAt       time.Time
+	providerUpdated time.Time
+}
+
+// ModelCatalogService discovers and caches the models offered by providers
+type ModelCatalogService struct {
+	providerService *ProviderService
+	adapterFactory  *adapter.AdapterFactory
+
+	mu    sync.Mutex
+	cache map[string]catalogEntry // By provider ID
+}
+
+// NewModelCatalogService creates a new model catalog service
+func NewModelCatalogService(providerService *ProviderService, adapterFactory *adapter.AdapterFactory) *ModelCatalogService {
+	return &ModelCatalogService{
+		providerService: providerService,
+		adapterFactory:  adapterFactory,
+		cache:           make(map[string]catalogEntry),
+	}
+}
+
+// ListModels returns the models available from a provider, served from cache unless
+// refresh is set, the entry expired or the provider was updated since it was fetched.
+// Returns nil, nil when the provider does not exist or is not visible to the user.
+func (s *ModelCatalogService) ListModels(ctx context.Context, userID, providerID string, refresh bool) ([]model.ModelInfo, error) {
+	provider, err := s.providerService.GetByID(userID, providerID)
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		s.Invalidate(providerID)
+		return nil, nil
+	}
+
+	if !refresh {
+		s.mu.Lock()
+		entry, ok := s.cache[providerID]
+		s.mu.Unlock()
+		if ok && time.Since(entry.fetchedAt) < modelCatalogTTL && entry.providerUpdated.Equal(provider.UpdatedAt) {
+			return entry.models, nil
+		}
+	}
+
+	adapterCfg, err := s.providerService.AdapterConfig(provider)
+	if err != nil {
+		return nil, err
+	}
+	adapterCfg.Model = catalogPlaceholderModel
+
+	llm, err := s.adapterFactory.Create(provider.Type, adapterCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := llm.(adapter.ModelLister)
+	if !ok {
+		return nil, ErrModelListingUnsupported
+	}
+
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[providerID] = catalogEntry{
+		models:          models,
+		fetchedAt:       time.Now(),
+		providerUpdated: provider.UpdatedAt,
+	}
+	s.mu.Unlock()
+
+	return models, nil
+}
+
+// Invalidate drops the cached model list of a provider
+func (s *ModelCatalogService) Invalidate(providerID string) {
+	s.mu.Lock()
+	delete(s.cache, providerID)
+	s.mu.Unlock()
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/modelinfo"
 	"github.com/allwaysyou/llm-agent/internal/repository"
 	"github.com/google/uuid"
 )
@@ -23,39 +24,65 @@ func NewModelConfigService(repo *repository.ModelConfigRepository, providerRepo
 	}
 }
 
-// Create creates a new model config
-func (s *ModelConfigService) Create(req *model.CreateModelConfigRequest) (*model.ModelConfig, error) {
+// Create creates a new model config for a provider the user manages
+func (s *ModelConfigService) Create(user *model.User, req *model.CreateModelConfigRequest) (*model.ModelConfig, error) {
 	// Verify provider exists
-	provider, err := s.providerRepo.GetByID(req.ProviderID)
+	provider, err := s.providerRepo.GetByID(user.ID, req.ProviderID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get provider: %w", err)
 	}
 	if provider == nil {
 		return nil, fmt.Errorf("provider not found")
 	}
+	if !canManageProvider(user, provider) {
+		return nil, ErrForbidden
+	}
+
+	configID := uuid.New().String()
+	if err := s.validateFallbacks(user.ID, configID, req.FallbackIDs); err != nil {
+		return nil, err
+	}
+
+	temperature := 0.7
+	if req.Temperature != nil {
+		temperature = *req.Temperature
+	}
 
 	config := &model.ModelConfig{
-		ID:          uuid.New().String(),
-		ProviderID:  req.ProviderID,
-		Model:       req.Model,
-		MaxTokens:   req.MaxTokens,
-		Temperature: req.Temperature,
-		ConfigType:  req.ConfigType,
-		IsDefault:   req.IsDefault,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:              configID,
+		ProviderID:      req.ProviderID,
+		Model:           req.Model,
+		MaxTokens:       req.MaxTokens,
+		Temperature:     temperature,
+		Sampling:        req.Sampling,
+		ConfigType:      req.ConfigType,
+		IsDefault:       req.IsDefault,
+		PromptPrice:     req.PromptPrice,
+		CompletionPrice: req.CompletionPrice,
+		Currency:        req.Currency,
+		Limits:          req.Limits,
+		FallbackIDs:     req.FallbackIDs,
+		Capabilities:    req.Capabilities,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	if err := config.EffectiveSampling().Validate(); err != nil {
+		return nil, err
 	}
 
 	// Set defaults
 	if config.MaxTokens == 0 {
 		config.MaxTokens = 4096
 	}
-	if config.Temperature == 0 {
-		config.Temperature = 0.7
-	}
 	if config.ConfigType == "" {
 		config.ConfigType = model.ConfigTypeChat
 	}
+	if config.Currency == "" {
+		config.Currency = "USD"
+	}
+	if err := s.checkBudgetCurrency(provider, config); err != nil {
+		return nil, err
+	}
 
 	if err := s.repo.Create(config); err != nil {
 		return nil, fmt.Errorf("failed to create model config: %w", err)
@@ -69,43 +96,71 @@ func (s *ModelConfigService) Create(req *model.CreateModelConfigRequest) (*model
 	}
 
 	// Fetch with provider data
-	return s.repo.GetByID(config.ID)
+	return s.repo.GetByID(user.ID, config.ID)
 }
 
-// GetByID retrieves a model config by ID
-func (s *ModelConfigService) GetByID(id string) (*model.ModelConfig, error) {
-	return s.repo.GetByID(id)
+// ToResponse converts a model config to its response, including its effective capabilities
+func (s *ModelConfigService) ToResponse(config *model.ModelConfig) model.ModelConfigResponse {
+	resp := config.ToResponse()
+	caps := modelinfo.Capabilities(config)
+	resp.EffectiveCapabilities = &caps
+	return resp
 }
 
-// GetAll retrieves all model configs
-func (s *ModelConfigService) GetAll() ([]model.ModelConfig, error) {
-	return s.repo.GetAll()
+// GetByID retrieves a model config visible to a user by ID
+func (s *ModelConfigService) GetByID(userID, id string) (*model.ModelConfig, error) {
+	return s.repo.GetByID(userID, id)
 }
 
-// GetByProvider retrieves all model configs for a specific provider
-func (s *ModelConfigService) GetByProvider(providerID string) ([]model.ModelConfig, error) {
+// GetAll retrieves the model configs visible to a user
+func (s *ModelConfigService) GetAll(userID string) ([]model.ModelConfig, error) {
+	return s.repo.GetAll(userID)
+}
+
+// GetByProvider retrieves all model configs for a provider visible to a user
+func (s *ModelConfigService) GetByProvider(userID, providerID string) ([]model.ModelConfig, error) {
+	provider, err := s.providerRepo.GetByID(userID, providerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider: %w", err)
+	}
+	if provider == nil {
+		return []model.ModelConfig{}, nil
+	}
 	return s.repo.GetByProvider(providerID)
 }
 
-// GetByType retrieves all model configs of a specific type
-func (s *ModelConfigService) GetByType(configType model.ConfigType) ([]model.ModelConfig, error) {
-	return s.repo.GetByType(configType)
+// GetByType retrieves the model configs of a specific type visible to a user
+func (s *ModelConfigService) GetByType(userID string, configType model.ConfigType) ([]model.ModelConfig, error) {
+	return s.repo.GetByType(userID, configType)
 }
 
-// GetDefaultByType retrieves the default model config for a specific type
-func (s *ModelConfigService) GetDefaultByType(configType model.ConfigType) (*model.ModelConfig, error) {
-	return s.repo.GetDefaultByType(configType)
+// GetDefaultByType retrieves the default model config for a specific type: the user's
+// own default, else the shared one
+func (s *ModelConfigService) GetDefaultByType(userID string, configType model.ConfigType) (*model.ModelConfig, error) {
+	return s.repo.GetDefaultByType(userID, configType)
 }
 
-// Update updates a model config
-func (s *ModelConfigService) Update(id string, req *model.UpdateModelConfigRequest) (*model.ModelConfig, error) {
-	config, err := s.repo.GetByID(id)
+// getManaged retrieves a model config of a provider the user manages
+func (s *ModelConfigService) getManaged(user *model.User, id string) (*model.ModelConfig, error) {
+	config, err := s.repo.GetByID(user.ID, id)
 	if err != nil {
 		return nil, err
 	}
 	if config == nil {
 		return nil, fmt.Errorf("model config not found")
 	}
+	if config.Provider == nil || !canManageProvider(user, config.Provider) {
+		return nil, ErrForbidden
+	}
+	return config, nil
+}
+
+// Update updates a model config
+func (s *ModelConfigService) Update(user *model.User, id string, req *model.UpdateModelConfigRequest) (*model.ModelConfig, error) {
+	config, err := s.getManaged(user, id)
+	if err != nil {
+		return nil, err
+	}
 
 	// Update fields
 	if req.Model != "" {
@@ -117,12 +172,44 @@ func (s *ModelConfigService) Update(id string, req *model.UpdateModelConfigReque
 	if req.Temperature != nil {
 		config.Temperature = *req.Temperature
 	}
+	if req.Sampling != nil {
+		config.Sampling = *req.Sampling
+	}
+	if err := config.EffectiveSampling().Validate(); err != nil {
+		return nil, err
+	}
 	if req.ConfigType != "" {
 		config.ConfigType = req.ConfigType
 	}
 	if req.IsDefault != nil && *req.IsDefault {
 		config.IsDefault = true
 	}
+	if req.PromptPrice != nil {
+		config.PromptPrice = *req.PromptPrice
+	}
+	if req.CompletionPrice != nil {
+		config.CompletionPrice = *req.CompletionPrice
+	}
+	if req.Currency != "" {
+		config.Currency = req.Currency
+		if config.Provider != nil {
+			if err := s.checkBudgetCurrency(config.Provider, config); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req.Limits != nil {
+		config.Limits = *req.Limits
+	}
+	if req.FallbackIDs != nil {
+		if err := s.validateFallbacks(user.ID, config.ID, req.FallbackIDs); err != nil {
+			return nil, err
+		}
+		config.FallbackIDs = req.FallbackIDs
+	}
+	if req.Capabilities != nil {
+		config.Capabilities = *req.Capabilities
+	}
 
 	config.UpdatedAt = time.Now()
 
@@ -137,15 +224,57 @@ func (s *ModelConfigService) Update(id string, req *model.UpdateModelConfigReque
 		}
 	}
 
-	return s.repo.GetByID(config.ID)
+	return s.repo.GetByID(user.ID, config.ID)
 }
 
 // Delete deletes a model config
-func (s *ModelConfigService) Delete(id string) error {
+func (s *ModelConfigService) Delete(user *model.User, id string) error {
+	if _, err := s.getManaged(user, id); err != nil {
+		return err
+	}
 	return s.repo.Delete(id)
 }
 
 // SetDefault sets a model config as default for its type
-func (s *ModelConfigService) SetDefault(id string) error {
+func (s *ModelConfigService) SetDefault(user *model.User, id string) error {
+	if _, err := s.getManaged(user, id); err != nil {
+		return err
+	}
 	return s.repo.SetDefault(id)
 }
+
+// checkBudgetCurrency verifies that saving config keeps the provider's model configs in one
+// currency if the provider has a cost budget
+func (s *ModelConfigService) checkBudgetCurrency(provider *model.Provider, config *model.ModelConfig) error {
+	configs, err := s.repo.GetByProvider(provider.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get model configs: %w", err)
+	}
+	saved := []model.ModelConfig{*config}
+	for _, other := range configs {
+		if other.ID != config.ID {
+			saved = append(saved, other)
+		}
+	}
+	return checkBudgetCurrency(provider, saved)
+}
+
+// validateFallbacks checks that every fallback config is a chat config visible to the user and is not the config itself
+func (s *ModelConfigService) validateFallbacks(userID, id string, fallbackIDs []string) error {
+	for _, fallbackID := range fallbackIDs {
+		if fallbackID == id {
+			return fmt.Errorf("model config cannot fall back to itself")
+		}
+		fallback, err := s.repo.GetByID(userID, fallbackID)
+		if err != nil {
+			return fmt.Errorf("failed to get fallback config: %w", err)
+		}
+		if fallback == nil {
+			return fmt.Errorf("fallback config not found: %s", fallbackID)
+		}
+		if fallback.ConfigType != model.ConfigTypeChat {
+			return fmt.Errorf("fallback config %s is a %s config, only chat configs can be fallbacks", fallbackID, fallback.ConfigType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/repository"
+	"github.com/google/uuid"
+)
+
+// ErrPersonaNotFound is returned when a persona does not exist
+var ErrPersonaNotFound = errors.New("persona not found")
+
+// PersonaService handles persona business logic
+type PersonaService struct {
+	repo *repository.PersonaRepository
+}
+
+// canManagePersona reports whether a user may change a persona: their own personas,
+// and shared ones for admins
+func canManagePersona(user *model.User, persona *model.Persona) bool {
+	if persona.UserID == "" {
+		return user.IsAdmin()
+	}
+	return persona.UserID == user.ID
+}
+
+// NewPersonaService creates a new persona service
+func NewPersonaService(repo *repository.PersonaRepository) *PersonaService {
+	return &PersonaService{repo: repo}
+}
+
+// Create creates a new persona of the user, or a shared one if an admin asks for it
+func (s *PersonaService) Create(user *model.User, req *model.CreatePersonaRequest) (*model.Persona, error) {
+	ownerID := user.ID
+	if req.Shared {
+		if !user.IsAdmin() {
+			return nil, ErrForbidden
+		}
+		ownerID = ""
+	}
+	if err := req.Sampling.Validate(); err != nil {
+		return nil, err
+	}
+
+	persona := &model.Persona{
+		ID:           uuid.New().String(),
+		UserID:       ownerID,
+		Name:         req.Name,
+		Description:  req.Description,
+		SystemPrompt: req.SystemPrompt,
+		Sampling:     req.Sampling,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Shared:       ownerID == "",
+	}
+
+	if err := s.repo.Create(persona); err != nil {
+		return nil, fmt.Errorf("failed to create persona: %w", err)
+	}
+	return persona, nil
+}
+
+// GetByID retrieves a persona visible to a user by ID
+func (s *PersonaService) GetByID(userID, id string) (*model.Persona, error) {
+	return s.repo.GetByID(userID, id)
+}
+
+// GetAll retrieves the personas visible to a user
+func (s *PersonaService) GetAll(userID string) ([]model.Persona, error) {
+	return s.repo.GetAll(userID)
+}
+
+// getManaged retrieves a persona the user may change
+func (s *PersonaService) getManaged(user *model.User, id string) (*model.Persona, error) {
+	persona, err := s.repo.GetByID(user.ID, id)
+	if err != nil {
+		return nil, err
+	}
+	if persona == nil {
+		return nil, ErrPersonaNotFound
+	}
+	if !canManagePersona(user, persona) {
+		return nil, ErrForbidden
+	}
+	return persona, nil
+}
+
+// Update updates a persona
+func (s *PersonaService) Update(user *model.User, id string, req *model.UpdatePersonaRequest) (*model.Persona, error) {
+	persona, err := s.getManaged(user, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		persona.Name = req.Name
+	}
+	if req.Description != nil {
+		persona.Description = *req.Description
+	}
+	if req.SystemPrompt != nil {
+		persona.SystemPrompt = *req.SystemPrompt
+	}
+	if req.Sampling != nil {
+		if err := req.Sampling.Validate(); err != nil {
+			return nil, err
+		}
+		persona.Sampling = *req.Sampling
+	}
+	persona.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(persona); err != nil {
+		return nil, fmt.Errorf("failed to update persona: %w", err)
+	}
+	return persona, nil
+}
+
+// Delete deletes a persona. Sessions using it keep their own settings.
+func (s *PersonaService) Delete(user *model.User, id string) error {
+	if _, err := s.getManaged(user, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/prompt"
+	"github.com/allwaysyou/llm-agent/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPromptNotFound = errors.New("prompt not found")
+	ErrPromptExists   = errors.New("prompt already exists")
+	ErrInvalidPrompt  = errors.New("invalid prompt")
+	ErrBuiltinPrompt  = errors.New("built-in prompt defaults cannot be deleted, activate an earlier version instead")
+)
+
+// promptNamePattern restricts prompt names to what can follow a slash in the chat input
+var promptNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// PromptService handles the prompt template store
+type PromptService struct {
+	repo *repository.PromptRepository
+}
+
+// NewPromptService creates a new prompt service
+func NewPromptService(repo *repository.PromptRepository) *PromptService {
+	return &PromptService{repo: repo}
+}
+
+// GetAll retrieves the active version of every prompt, optionally filtered by kind and language
+func (s *PromptService) GetAll(kind model.PromptKind, language string) ([]model.PromptTemplate, error) {
+	return s.repo.GetAllActive(kind, language)
+}
+
+// GetByID retrieves a prompt version
+func (s *PromptService) GetByID(id string) (*model.PromptTemplate, error) {
+	p, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt: %w", err)
+	}
+	if p == nil {
+		return nil, ErrPromptNotFound
+	}
+	return p, nil
+}
+
+// GetVersions retrieves all versions of a prompt's name and language, newest first
+func (s *PromptService) GetVersions(id string) ([]model.PromptTemplate, error) {
+	p, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetVersions(p.Name, p.Language)
+}
+
+// Create creates a slash prompt, or a new language variant of a built-in prompt
+func (s *PromptService) Create(req *model.CreatePromptRequest) (*model.PromptTemplate, error) {
+	if !promptNamePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name may only contain lowercase letters, digits, '-' and '_'", ErrInvalidPrompt)
+	}
+	if _, err := prompt.Parse(req.Content); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrompt, err)
+	}
+
+	versions, err := s.repo.GetVersions(req.Name, req.Language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt versions: %w", err)
+	}
+	if len(versions) > 0 {
+		return nil, ErrPromptExists
+	}
+
+	kind := model.PromptKindSlash
+	if prompt.IsBuiltin(req.Name) {
+		kind = model.PromptKindBuiltin
+	}
+
+	p := &model.PromptTemplate{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		Language:    req.Language,
+		Kind:        kind,
+		Description: req.Description,
+		Content:     req.Content,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.repo.CreateVersion(p); err != nil {
+		return nil, fmt.Errorf("failed to create prompt: %w", err)
+	}
+	return p, nil
+}
+
+// Update saves the content as a new version of the prompt and makes it active
+func (s *PromptService) Update(id string, req *model.UpdatePromptRequest) (*model.PromptTemplate, error) {
+	current, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := prompt.Parse(req.Content); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrompt, err)
+	}
+
+	description := current.Description
+	if req.Description != nil {
+		description = *req.Description
+	}
+
+	p := &model.PromptTemplate{
+		ID:          uuid.New().String(),
+		Name:        current.Name,
+		Language:    current.Language,
+		Kind:        current.Kind,
+		Description: description,
+		Content:     req.Content,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.repo.CreateVersion(p); err != nil {
+		return nil, fmt.Errorf("failed to save prompt version: %w", err)
+	}
+	return p, nil
+}
+
+// Activate makes a version the one in use, e.g. to roll back an edit
+func (s *PromptService) Activate(id string) (*model.PromptTemplate, error) {
+	p, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Activate(p); err != nil {
+		return nil, fmt.Errorf("failed to activate prompt: %w", err)
+	}
+	return p, nil
+}
+
+// Delete deletes all versions of a prompt's name and language. The languages a
+// built-in prompt ships with cannot be deleted.
+func (s *PromptService) Delete(id string) error {
+	p, err := s.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if prompt.HasDefault(p.Name, p.Language) {
+		return ErrBuiltinPrompt
+	}
+	return s.repo.Delete(p.Name, p.Language)
+}
+
+// Render fills in a prompt version's variables
+func (s *PromptService) Render(id string, variables map[string]any) (string, error) {
+	p, err := s.GetByID(id)
+	if err != nil {
+		return "", err
+	}
+	content, err := prompt.Render(p.Content, variables)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPrompt, err)
+	}
+	return content, nil
+}
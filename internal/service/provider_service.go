@@ -1,37 +1,93 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
+	"github.com/allwaysyou/llm-agent/internal/adapter"
 	"github.com/allwaysyou/llm-agent/internal/model"
 	"github.com/allwaysyou/llm-agent/internal/pkg/crypto"
+	"github.com/allwaysyou/llm-agent/internal/pkg/embedding"
+	"github.com/allwaysyou/llm-agent/internal/pkg/httpclient"
+	"github.com/allwaysyou/llm-agent/internal/pkg/jsonschema"
+	"github.com/allwaysyou/llm-agent/internal/pkg/modelinfo"
+	"github.com/allwaysyou/llm-agent/internal/pkg/redact"
 	"github.com/allwaysyou/llm-agent/internal/repository"
 	"github.com/google/uuid"
 )
 
+// ErrForbidden is returned when a user may see but not change a resource, e.g. a
+// member editing a shared provider
+var ErrForbidden = errors.New("permission denied")
+
+// ErrInvalidProvider is returned for invalid provider settings
+var ErrInvalidProvider = errors.New("invalid provider")
+
+// canManageProvider reports whether a user may change a provider and its model configs:
+// their own providers, and shared ones for admins
+func canManageProvider(user *model.User, provider *model.Provider) bool {
+	if provider.UserID == "" {
+		return user.IsAdmin()
+	}
+	return provider.UserID == user.ID
+}
+
 // ProviderService handles Provider business logic
 type ProviderService struct {
 	repo            *repository.ProviderRepository
 	modelConfigRepo *repository.ModelConfigRepository
 	encryptor       *crypto.Encryptor
+	httpConfig      httpclient.Config
+	redactor        *redact.Redactor
+	redactByDefault bool // Redact for providers in auto mode
+
+	mu      sync.Mutex
+	clients map[string]*http.Client // HTTP clients by proxy URL, shared across adapters
 }
 
 // NewProviderService creates a new provider service
-func NewProviderService(repo *repository.ProviderRepository, modelConfigRepo *repository.ModelConfigRepository, encryptor *crypto.Encryptor) *ProviderService {
+func NewProviderService(repo *repository.ProviderRepository, modelConfigRepo *repository.ModelConfigRepository, encryptor *crypto.Encryptor, httpConfig httpclient.Config, redactor *redact.Redactor, redactByDefault bool) *ProviderService {
 	return &ProviderService{
 		repo:            repo,
 		modelConfigRepo: modelConfigRepo,
 		encryptor:       encryptor,
+		httpConfig:      httpConfig,
+		redactor:        redactor,
+		redactByDefault: redactByDefault,
+		clients:         make(map[string]*http.Client),
 	}
 }
 
-// Create creates a new provider
-func (s *ProviderService) Create(req *model.CreateProviderRequest) (*model.Provider, error) {
+// Create creates a new provider, private to the user unless an admin shares it
+func (s *ProviderService) Create(user *model.User, req *model.CreateProviderRequest) (*model.Provider, error) {
+	ownerID := user.ID
+	if req.Shared {
+		if !user.IsAdmin() {
+			return nil, ErrForbidden
+		}
+		ownerID = ""
+	}
+	if err := validRedactionMode(req.Redaction); err != nil {
+		return nil, err
+	}
+	if req.APIKey == "" && needsAPIKey(req.Type, req.Options) {
+		return nil, fmt.Errorf("%w: api_key is required", ErrInvalidProvider)
+	}
+
 	// Encrypt API key
-	encryptedKey, err := s.encryptor.Encrypt(req.APIKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to encrypt API key: %w", err)
+	var encryptedKey string
+	if req.APIKey != "" {
+		var err error
+		if encryptedKey, err = s.encryptor.Encrypt(req.APIKey); err != nil {
+			return nil, fmt.Errorf("failed to encrypt API key: %w", err)
+		}
 	}
 
 	enabled := true
@@ -45,7 +101,12 @@ func (s *ProviderService) Create(req *model.CreateProviderRequest) (*model.Provi
 		Type:      req.Type,
 		APIKey:    encryptedKey,
 		BaseURL:   req.BaseURL,
+		ProxyURL:  req.ProxyURL,
 		Enabled:   enabled,
+		UserID:    ownerID,
+		Limits:    req.Limits,
+		Options:   req.Options,
+		Redaction: req.Redaction,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -57,14 +118,26 @@ func (s *ProviderService) Create(req *model.CreateProviderRequest) (*model.Provi
 	return provider, nil
 }
 
-// GetByID retrieves a provider by ID
-func (s *ProviderService) GetByID(id string) (*model.Provider, error) {
-	return s.repo.GetByID(id)
+// needsAPIKey reports whether a provider cannot be called without an API key. Ollama
+// and custom providers with auth_scheme none send no credentials.
+func needsAPIKey(providerType model.ProviderType, options model.ProviderOptions) bool {
+	switch providerType {
+	case model.ProviderTypeOllama:
+		return false
+	case model.ProviderTypeCustom:
+		return options.AuthScheme != model.AuthSchemeNone
+	}
+	return true
+}
+
+// GetByID retrieves a provider visible to a user by ID
+func (s *ProviderService) GetByID(userID, id string) (*model.Provider, error) {
+	return s.repo.GetByID(userID, id)
 }
 
-// GetByIDWithModels retrieves a provider by ID with its models
-func (s *ProviderService) GetByIDWithModels(id string) (*model.ProviderResponse, error) {
-	provider, err := s.repo.GetByID(id)
+// GetByIDWithModels retrieves a provider visible to a user by ID with its models
+func (s *ProviderService) GetByIDWithModels(userID, id string) (*model.ProviderResponse, error) {
+	provider, err := s.repo.GetByID(userID, id)
 	if err != nil {
 		return nil, err
 	}
@@ -79,32 +152,48 @@ func (s *ProviderService) GetByIDWithModels(id string) (*model.ProviderResponse,
 
 	resp := provider.ToResponse()
 	resp.Models = make([]model.ModelConfigResponse, len(models))
-	for i, m := range models {
+	for i := range models {
+		m := &models[i]
+		m.Provider = provider
 		resp.Models[i] = m.ToResponse()
+		caps := modelinfo.Capabilities(m)
+		resp.Models[i].EffectiveCapabilities = &caps
 	}
 
 	return &resp, nil
 }
 
-// GetAll retrieves all providers
-func (s *ProviderService) GetAll() ([]model.Provider, error) {
-	return s.repo.GetAll()
+// GetAll retrieves the providers visible to a user
+func (s *ProviderService) GetAll(userID string) ([]model.Provider, error) {
+	return s.repo.GetAll(userID)
 }
 
-// GetEnabled retrieves all enabled providers
-func (s *ProviderService) GetEnabled() ([]model.Provider, error) {
-	return s.repo.GetEnabled()
+// GetEnabled retrieves the enabled providers visible to a user
+func (s *ProviderService) GetEnabled(userID string) ([]model.Provider, error) {
+	return s.repo.GetEnabled(userID)
 }
 
-// Update updates a provider
-func (s *ProviderService) Update(id string, req *model.UpdateProviderRequest) (*model.Provider, error) {
-	provider, err := s.repo.GetByID(id)
+// getManaged retrieves a provider the user may change
+func (s *ProviderService) getManaged(user *model.User, id string) (*model.Provider, error) {
+	provider, err := s.repo.GetByID(user.ID, id)
 	if err != nil {
 		return nil, err
 	}
 	if provider == nil {
 		return nil, fmt.Errorf("provider not found")
 	}
+	if !canManageProvider(user, provider) {
+		return nil, ErrForbidden
+	}
+	return provider, nil
+}
+
+// Update updates a provider
+func (s *ProviderService) Update(user *model.User, id string, req *model.UpdateProviderRequest) (*model.Provider, error) {
+	provider, err := s.getManaged(user, id)
+	if err != nil {
+		return nil, err
+	}
 
 	// Update fields
 	if req.Name != "" {
@@ -123,9 +212,31 @@ func (s *ProviderService) Update(id string, req *model.UpdateProviderRequest) (*
 	if req.BaseURL != "" {
 		provider.BaseURL = req.BaseURL
 	}
+	if req.ProxyURL != nil {
+		provider.ProxyURL = *req.ProxyURL
+	}
 	if req.Enabled != nil {
 		provider.Enabled = *req.Enabled
 	}
+	if req.Limits != nil {
+		provider.Limits = *req.Limits
+		configs, err := s.modelConfigRepo.GetByProvider(provider.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get model configs: %w", err)
+		}
+		if err := checkBudgetCurrency(provider, configs); err != nil {
+			return nil, err
+		}
+	}
+	if req.Options != nil {
+		provider.Options = *req.Options
+	}
+	if req.Redaction != nil {
+		if err := validRedactionMode(*req.Redaction); err != nil {
+			return nil, err
+		}
+		provider.Redaction = *req.Redaction
+	}
 
 	provider.UpdatedAt = time.Now()
 
@@ -137,7 +248,11 @@ func (s *ProviderService) Update(id string, req *model.UpdateProviderRequest) (*
 }
 
 // Delete deletes a provider and all its models
-func (s *ProviderService) Delete(id string) error {
+func (s *ProviderService) Delete(user *model.User, id string) error {
+	if _, err := s.getManaged(user, id); err != nil {
+		return err
+	}
+
 	// First delete all models associated with this provider
 	if err := s.modelConfigRepo.DeleteByProvider(id); err != nil {
 		return fmt.Errorf("failed to delete provider models: %w", err)
@@ -150,9 +265,54 @@ func (s *ProviderService) DecryptAPIKey(encryptedKey string) (string, error) {
 	return s.encryptor.Decrypt(encryptedKey)
 }
 
-// GetDecryptedAPIKey gets the decrypted API key for a provider
-func (s *ProviderService) GetDecryptedAPIKey(id string) (string, error) {
-	provider, err := s.repo.GetByID(id)
+// StaleAPIKeys returns the number of provider API keys not encrypted with the current key
+func (s *ProviderService) StaleAPIKeys() (int, error) {
+	providers, err := s.repo.ListAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list providers: %w", err)
+	}
+	stale := 0
+	for _, p := range providers {
+		if p.APIKey != "" && s.encryptor.NeedsRotation(p.APIKey) {
+			stale++
+		}
+	}
+	return stale, nil
+}
+
+// RotateAPIKeys re-encrypts the API keys of all providers with the current key and
+// returns the number re-encrypted. It stops at the first key that cannot be decrypted
+// with the configured keys.
+func (s *ProviderService) RotateAPIKeys() (int, error) {
+	providers, err := s.repo.ListAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list providers: %w", err)
+	}
+
+	rotated := 0
+	for _, p := range providers {
+		if p.APIKey == "" || !s.encryptor.NeedsRotation(p.APIKey) {
+			continue
+		}
+		apiKey, err := s.encryptor.Decrypt(p.APIKey)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to decrypt API key of provider %s (%s): %w", p.Name, p.ID, err)
+		}
+		encrypted, err := s.encryptor.Encrypt(apiKey)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to encrypt API key: %w", err)
+		}
+		if err := s.repo.UpdateAPIKey(p.ID, encrypted); err != nil {
+			return rotated, fmt.Errorf("failed to update provider %s: %w", p.ID, err)
+		}
+		rotated++
+	}
+	return rotated, nil
+}
+
+// GetDecryptedAPIKey gets the decrypted API key for a provider visible to a user
+func (s *ProviderService) GetDecryptedAPIKey(userID, id string) (string, error) {
+	provider, err := s.repo.GetByID(userID, id)
 	if err != nil {
 		return "", err
 	}
@@ -161,3 +321,222 @@ func (s *ProviderService) GetDecryptedAPIKey(id string) (string, error) {
 	}
 	return s.encryptor.Decrypt(provider.APIKey)
 }
+
+// HTTPClient returns the HTTP client for a provider, honoring its proxy override
+func (s *ProviderService) HTTPClient(provider *model.Provider) (*http.Client, error) {
+	proxyURL := s.httpConfig.ProxyURL
+	if provider != nil && provider.ProxyURL != "" {
+		proxyURL = provider.ProxyURL
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if client, ok := s.clients[proxyURL]; ok {
+		return client, nil
+	}
+
+	cfg := s.httpConfig
+	cfg.ProxyURL = proxyURL
+	client, err := httpclient.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.clients[proxyURL] = client
+	return client, nil
+}
+
+// AdapterConfig builds the provider part of an adapter config: decrypted API key,
+// base URL and HTTP client. Model settings are left to the caller.
+func (s *ProviderService) AdapterConfig(provider *model.Provider) (adapter.AdapterConfig, error) {
+	var apiKey string
+	if provider.APIKey != "" {
+		var err error
+		if apiKey, err = s.encryptor.Decrypt(provider.APIKey); err != nil {
+			return adapter.AdapterConfig{}, fmt.Errorf("failed to decrypt API key: %w", err)
+		}
+	}
+
+	client, err := s.HTTPClient(provider)
+	if err != nil {
+		return adapter.AdapterConfig{}, err
+	}
+
+	return adapter.AdapterConfig{
+		APIKey:     apiKey,
+		BaseURL:    provider.BaseURL,
+		HTTPClient: client,
+		Options:    provider.Options,
+	}, nil
+}
+
+// ModelAdapterConfig builds the adapter config of a model config: its provider's, with
+// the model, max tokens, sampling and the per-model overrides of a custom provider's
+// declared capabilities
+func (s *ProviderService) ModelAdapterConfig(modelConfig *model.ModelConfig) (adapter.AdapterConfig, error) {
+	adapterCfg, err := s.AdapterConfig(modelConfig.Provider)
+	if err != nil {
+		return adapter.AdapterConfig{}, err
+	}
+	adapterCfg.Model = modelConfig.Model
+	adapterCfg.MaxTokens = modelConfig.MaxTokens
+	adapterCfg.Sampling = modelConfig.EffectiveSampling()
+
+	caps := modelinfo.Capabilities(modelConfig)
+	adapterCfg.Options.Capabilities.StreamUsage = caps.StreamUsage
+	adapterCfg.Options.Capabilities.Tools = caps.Tools
+	adapterCfg.Options.Capabilities.Reasoning = caps.Reasoning
+	return adapterCfg, nil
+}
+
+// validRedactionMode checks the redaction mode of a provider request
+func validRedactionMode(mode model.RedactionMode) error {
+	switch mode {
+	case model.RedactionAuto, model.RedactionOn, model.RedactionOff:
+		return nil
+	}
+	return fmt.Errorf("%w: unknown redaction mode %q", ErrInvalidProvider, mode)
+}
+
+// ShouldRedact reports whether personal information is redacted from prompts sent to
+// the provider: per its redaction mode, or in auto mode for cloud providers if
+// redaction is enabled
+func (s *ProviderService) ShouldRedact(provider *model.Provider) bool {
+	switch provider.Redaction {
+	case model.RedactionOn:
+		return true
+	case model.RedactionOff:
+		return false
+	}
+	return s.redactByDefault && !isLocalProvider(provider)
+}
+
+// isLocalProvider returns true if the provider runs on this machine or the local
+// network, e.g. Ollama or LM Studio
+func isLocalProvider(provider *model.Provider) bool {
+	if provider.BaseURL == "" {
+		// Ollama defaults to localhost, every other type to its cloud API
+		return provider.Type == model.ProviderTypeOllama
+	}
+	u, err := url.Parse(provider.BaseURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+}
+
+// Redact wraps an adapter of the provider so that personal information in prompts is
+// replaced with placeholders, which are restored in the responses. Adapters of
+// providers not redacted are returned as is.
+func (s *ProviderService) Redact(provider *model.Provider, llm adapter.LLMAdapter) adapter.LLMAdapter {
+	if s.redactor == nil || !s.ShouldRedact(provider) {
+		return llm
+	}
+	return &redactingAdapter{LLMAdapter: llm, redactor: s.redactor}
+}
+
+// RedactEmbeddings wraps an embedding provider served by the provider so that personal
+// information in the embedded texts is replaced with placeholders too. Embedding
+// providers not redacted are returned as is.
+func (s *ProviderService) RedactEmbeddings(provider *model.Provider, embedder embedding.Provider) embedding.Provider {
+	if s.redactor == nil || !s.ShouldRedact(provider) {
+		return embedder
+	}
+	return &redactingEmbedder{Provider: embedder, redactor: s.redactor}
+}
+
+// redactingEmbedder redacts the texts embedded through the wrapped provider. Every text
+// gets its own placeholders, so the same kind of value embeds alike in all texts.
+type redactingEmbedder struct {
+	embedding.Provider
+	redactor *redact.Redactor
+}
+
+func (e *redactingEmbedder) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return e.Provider.GetEmbedding(ctx, e.redactor.NewSession().Redact(text))
+}
+
+func (e *redactingEmbedder) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	redacted := make([]string, len(texts))
+	for i, text := range texts {
+		redacted[i] = e.redactor.NewSession().Redact(text)
+	}
+	return e.Provider.GetEmbeddings(ctx, redacted)
+}
+
+// redactingAdapter redacts the prompts sent through the wrapped adapter
+type redactingAdapter struct {
+	adapter.LLMAdapter
+	redactor *redact.Redactor
+}
+
+// redact returns the messages with personal information replaced, and the session
+// restoring it
+func (a *redactingAdapter) redact(messages []model.Message) ([]model.Message, *redact.Session) {
+	session := a.redactor.NewSession()
+	redacted := make([]model.Message, len(messages))
+	for i, msg := range messages {
+		msg.Content = session.Redact(msg.Content)
+		redacted[i] = msg
+	}
+	if session.Len() > 0 {
+		log.Printf("[ProviderService:Redact] Replaced %d values in the prompt to %s", session.Len(), a.Name())
+	}
+	return redacted, session
+}
+
+func (a *redactingAdapter) Chat(ctx context.Context, messages []model.Message) (*model.ChatResponse, error) {
+	redacted, session := a.redact(messages)
+	resp, err := a.LLMAdapter.Chat(ctx, redacted)
+	if err != nil {
+		return nil, err
+	}
+	resp.Message.Content = session.Restore(resp.Message.Content)
+	resp.Message.Reasoning = session.Restore(resp.Message.Reasoning)
+	return resp, nil
+}
+
+func (a *redactingAdapter) ChatJSON(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error) {
+	redacted, session := a.redact(messages)
+	resp, err := a.LLMAdapter.ChatJSON(ctx, redacted, format)
+	if err != nil {
+		return nil, err
+	}
+	resp.Message.Content = session.Restore(resp.Message.Content)
+	resp.Message.Reasoning = session.Restore(resp.Message.Reasoning)
+	return resp, nil
+}
+
+func (a *redactingAdapter) ChatStream(ctx context.Context, messages []model.Message) (<-chan model.StreamChunk, error) {
+	redacted, session := a.redact(messages)
+	stream, err := a.LLMAdapter.ChatStream(ctx, redacted)
+	if err != nil || session.Len() == 0 {
+		return stream, err
+	}
+
+	content := session.NewStreamRestorer()
+	reasoning := session.NewStreamRestorer()
+	out := make(chan model.StreamChunk, cap(stream))
+	go func() {
+		defer close(out)
+		for chunk := range stream {
+			hadText := chunk.Delta != "" || chunk.Reasoning != ""
+			chunk.Delta = content.Write(chunk.Delta)
+			chunk.Reasoning = reasoning.Write(chunk.Reasoning)
+			if chunk.Done {
+				chunk.Delta += content.Flush()
+				chunk.Reasoning += reasoning.Flush()
+			} else if hadText && chunk.Delta == "" && chunk.Reasoning == "" {
+				// Held back as a partial placeholder
+				continue
+			}
+			out <- chunk
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/constants"
+	"github.com/allwaysyou/llm-agent/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrNotAFork            = errors.New("session is not a fork")
+	ErrForkMerged          = errors.New("fork was already merged")
+	ErrForkUnchanged       = errors.New("fork has no messages since it was forked")
+	ErrModelConfigNotFound = errors.New("model config not found")
+)
+
+// SessionService handles session settings, forking sessions and merging forks back
+type SessionService struct {
+	sessionRepo        *repository.SessionRepository
+	memoryRepo         *repository.MemoryRepository
+	personaRepo        *repository.PersonaRepository
+	workspaceService   *WorkspaceService
+	modelConfigService *ModelConfigService
+	summarizeService   *SummarizeService
+}
+
+// NewSessionService creates a new session service
+func NewSessionService(
+	sessionRepo *repository.SessionRepository,
+	memoryRepo *repository.MemoryRepository,
+	personaRepo *repository.PersonaRepository,
+	workspaceService *WorkspaceService,
+	modelConfigService *ModelConfigService,
+	summarizeService *SummarizeService,
+) *SessionService {
+	return &SessionService{
+		sessionRepo:        sessionRepo,
+		memoryRepo:         memoryRepo,
+		personaRepo:        personaRepo,
+		workspaceService:   workspaceService,
+		modelConfigService: modelConfigService,
+		summarizeService:   summarizeService,
+	}
+}
+
+// Create creates an empty session of a user with its settings, ready for the first message
+func (s *SessionService) Create(userID string, req *model.CreateSessionRequest) (*model.Session, error) {
+	if err := s.validateSettings(userID, req.PersonaID, req.OverrideConfigID); err != nil {
+		return nil, err
+	}
+	if _, err := s.workspaceService.Scope(userID, req.WorkspaceID); err != nil {
+		return nil, err
+	}
+
+	title := req.Title
+	if title == "" {
+		title = constants.DefaultSessionTitle
+	}
+
+	now := time.Now()
+	session := &model.Session{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		Title:            title,
+		ConfigID:         req.OverrideConfigID,
+		WorkspaceID:      req.WorkspaceID,
+		SystemPrompt:     req.SystemPrompt,
+		PersonaID:        req.PersonaID,
+		OverrideConfigID: req.OverrideConfigID,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if req.Sampling != nil {
+		session.Sampling = *req.Sampling
+	}
+
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return session, nil
+}
+
+// Update updates a session's title, system prompt, persona, model override and sampling
+func (s *SessionService) Update(userID, id string, req *model.UpdateSessionRequest) (*model.Session, error) {
+	session, err := s.sessionRepo.GetByID(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	personaID, configID := session.PersonaID, session.OverrideConfigID
+	if req.PersonaID != nil {
+		personaID = *req.PersonaID
+	}
+	if req.OverrideConfigID != nil {
+		configID = *req.OverrideConfigID
+	}
+	if err := s.validateSettings(userID, personaID, configID); err != nil {
+		return nil, err
+	}
+	session.PersonaID = personaID
+	session.OverrideConfigID = configID
+
+	if req.Title != nil {
+		session.Title = *req.Title
+	}
+	if req.SystemPrompt != nil {
+		session.SystemPrompt = *req.SystemPrompt
+	}
+	if req.Sampling != nil {
+		session.Sampling = *req.Sampling
+	}
+	session.UpdatedAt = time.Now()
+
+	if err := s.sessionRepo.Update(session); err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+	return session, nil
+}
+
+// validateSettings checks that the referenced persona and model config exist and are
+// visible to the user
+func (s *SessionService) validateSettings(userID, personaID, configID string) error {
+	if personaID != "" {
+		persona, err := s.personaRepo.GetByID(userID, personaID)
+		if err != nil {
+			return fmt.Errorf("failed to get persona: %w", err)
+		}
+		if persona == nil {
+			return ErrPersonaNotFound
+		}
+	}
+	if configID != "" {
+		config, err := s.modelConfigService.GetByID(userID, configID)
+		if err != nil {
+			return fmt.Errorf("failed to get model config: %w", err)
+		}
+		if config == nil {
+			return ErrModelConfigNotFound
+		}
+	}
+	return nil
+}
+
+// Fork creates a new session with a copy of the conversation up to a message (by default
+// the end of the active branch), the origin's summary and its settings
+func (s *SessionService) Fork(userID, sessionID string, req *model.ForkSessionRequest) (*model.Session, error) {
+	origin, err := s.sessionRepo.GetByID(userID, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if origin == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	messageID := req.MessageID
+	if messageID == "" {
+		messageID = origin.ActiveLeafID
+	}
+	if messageID != "" {
+		message, err := s.memoryRepo.GetByID(messageID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get message: %w", err)
+		}
+		if message == nil || message.SessionID != origin.ID {
+			return nil, ErrMessageNotFound
+		}
+	}
+
+	title := req.Title
+	if title == "" {
+		title = origin.Title + constants.ForkTitleSuffix
+	}
+
+	now := time.Now()
+	fork := &model.Session{
+		ID:               uuid.New().String(),
+		UserID:           origin.UserID,
+		Title:            title,
+		ConfigID:         origin.ConfigID,
+		Summary:          origin.Summary,
+		WorkspaceID:      origin.WorkspaceID,
+		SystemPrompt:     origin.SystemPrompt,
+		PersonaID:        origin.PersonaID,
+		OverrideConfigID: origin.OverrideConfigID,
+		Sampling:         origin.Sampling,
+		ForkedFromID:     origin.ID,
+		ForkMessageID:    messageID,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	messages, err := s.memoryRepo.CopyBranch(origin.ID, messageID, fork.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy messages: %w", err)
+	}
+	if len(messages) > 0 {
+		fork.ActiveLeafID = messages[len(messages)-1].ID
+	}
+
+	if err := s.sessionRepo.CreateFork(fork, messages); err != nil {
+		return nil, fmt.Errorf("failed to create fork: %w", err)
+	}
+
+	log.Printf("[SessionService:Fork] Forked session %s at message %s into %s (%d messages)",
+		origin.ID, messageID, fork.ID, len(messages))
+	return fork, nil
+}
+
+// Merge summarizes the conversation a fork added and appends the summary to the summary
+// of the session it was forked from. A fork can be merged once. It returns the updated
+// origin session.
+func (s *SessionService) Merge(ctx context.Context, userID, forkID string) (*model.Session, error) {
+	fork, err := s.sessionRepo.GetByID(userID, forkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if fork == nil {
+		return nil, ErrSessionNotFound
+	}
+	if fork.ForkedFromID == "" {
+		return nil, ErrNotAFork
+	}
+	if fork.MergedAt != nil {
+		return nil, ErrForkMerged
+	}
+
+	origin, err := s.sessionRepo.GetByID(userID, fork.ForkedFromID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get origin session: %w", err)
+	}
+	if origin == nil {
+		return nil, fmt.Errorf("%w: origin session %s no longer exists", ErrSessionNotFound, fork.ForkedFromID)
+	}
+
+	// The origin's summary already covers the history copied into the fork
+	summary, err := s.summarizeService.SummarizeFork(ctx, userID, fork)
+	if errors.Is(err, ErrForkUnchanged) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize fork: %w", err)
+	}
+
+	merged := fmt.Sprintf(constants.ForkSummaryHeader, fork.Title) + summary
+	if origin.Summary != "" {
+		merged = strings.TrimRight(origin.Summary, "\n") + "\n\n" + merged
+	}
+	// Also rejects a concurrent merge of the same fork
+	ok, err := s.sessionRepo.MergeFork(fork.ID, origin.ID, merged, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge fork: %w", err)
+	}
+	if !ok {
+		return nil, ErrForkMerged
+	}
+	origin.Summary = merged
+
+	log.Printf("[SessionService:Merge] Merged summary of fork %s into session %s", fork.ID, origin.ID)
+	return origin, nil
+}
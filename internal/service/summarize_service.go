@@ -7,6 +7,7 @@ import (
 
 	"github.com/allwaysyou/llm-agent/internal/adapter"
 	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/prompt"
 	"github.com/allwaysyou/llm-agent/internal/repository"
 )
 
@@ -17,6 +18,8 @@ type SummarizeService struct {
 	modelConfigService *ModelConfigService
 	providerService    *ProviderService
 	adapterFactory     *adapter.AdapterFactory
+	usageService       *UsageService
+	prompts            *prompt.Library
 }
 
 // NewSummarizeService creates a new summarize service
@@ -26,6 +29,8 @@ func NewSummarizeService(
 	modelConfigService *ModelConfigService,
 	providerService *ProviderService,
 	adapterFactory *adapter.AdapterFactory,
+	usageService *UsageService,
+	prompts *prompt.Library,
 ) *SummarizeService {
 	return &SummarizeService{
 		sessionRepo:        sessionRepo,
@@ -33,13 +38,15 @@ func NewSummarizeService(
 		modelConfigService: modelConfigService,
 		providerService:    providerService,
 		adapterFactory:     adapterFactory,
+		usageService:       usageService,
+		prompts:            prompts,
 	}
 }
 
-// SummarizeSession generates a summary for a session's conversation
-func (s *SummarizeService) SummarizeSession(ctx context.Context, sessionID string) (string, error) {
+// SummarizeSession generates a summary for the conversation of a user's session
+func (s *SummarizeService) SummarizeSession(ctx context.Context, userID, sessionID string) (string, error) {
 	// Get session
-	session, err := s.sessionRepo.GetByID(sessionID)
+	session, err := s.sessionRepo.GetByID(userID, sessionID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get session: %w", err)
 	}
@@ -47,8 +54,8 @@ func (s *SummarizeService) SummarizeSession(ctx context.Context, sessionID strin
 		return "", fmt.Errorf("session not found")
 	}
 
-	// Get all memories for this session
-	memories, err := s.memoryRepo.GetBySessionID(sessionID, 0)
+	// Get the messages of the session's active branch
+	memories, err := s.memoryRepo.GetBranch(sessionID, session.ActiveLeafID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get memories: %w", err)
 	}
@@ -57,14 +64,53 @@ func (s *SummarizeService) SummarizeSession(ctx context.Context, sessionID strin
 		return "", fmt.Errorf("no messages to summarize")
 	}
 
+	summary, err := s.summarize(ctx, userID, session.ID, memories)
+	if err != nil {
+		return "", err
+	}
+
+	// Update session with summary
+	session.Summary = summary
+	if err := s.sessionRepo.Update(session); err != nil {
+		return "", fmt.Errorf("failed to update session: %w", err)
+	}
+
+	return summary, nil
+}
+
+// SummarizeFork generates a summary of the conversation a fork added after it was forked,
+// leaving out the history copied from its origin. The fork's own summary is not changed.
+func (s *SummarizeService) SummarizeFork(ctx context.Context, userID string, fork *model.Session) (string, error) {
+	memories, err := s.memoryRepo.GetBranch(fork.ID, fork.ActiveLeafID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get memories: %w", err)
+	}
+
+	// Copies keep the creation time of the origin's messages, so only the fork's own
+	// messages are newer than the fork
+	added := memories[:0]
+	for _, mem := range memories {
+		if mem.CreatedAt.After(fork.CreatedAt) {
+			added = append(added, mem)
+		}
+	}
+	if len(added) == 0 {
+		return "", ErrForkUnchanged
+	}
+
+	return s.summarize(ctx, userID, fork.ID, added)
+}
+
+// summarize generates a summary of the messages of a session
+func (s *SummarizeService) summarize(ctx context.Context, userID, sessionID string, memories []model.Memory) (string, error) {
 	// Get model config (use summarize type config)
-	modelConfig, err := s.modelConfigService.GetDefaultByType(model.ConfigTypeSummarize)
+	modelConfig, err := s.modelConfigService.GetDefaultByType(userID, model.ConfigTypeSummarize)
 	if err != nil {
 		return "", fmt.Errorf("failed to get config: %w", err)
 	}
 	if modelConfig == nil || modelConfig.Provider == nil {
 		// Fallback to chat config if no summarize config exists
-		modelConfig, err = s.modelConfigService.GetDefaultByType(model.ConfigTypeChat)
+		modelConfig, err = s.modelConfigService.GetDefaultByType(userID, model.ConfigTypeChat)
 		if err != nil {
 			return "", fmt.Errorf("failed to get config: %w", err)
 		}
@@ -74,23 +120,19 @@ func (s *SummarizeService) SummarizeSession(ctx context.Context, sessionID strin
 	}
 
 	// Create adapter
-	apiKey, err := s.providerService.GetDecryptedAPIKey(modelConfig.ProviderID)
+	adapterCfg, err := s.providerService.ModelAdapterConfig(modelConfig)
 	if err != nil {
-		return "", fmt.Errorf("failed to decrypt API key: %w", err)
-	}
-
-	adapterCfg := adapter.AdapterConfig{
-		APIKey:      apiKey,
-		BaseURL:     modelConfig.Provider.BaseURL,
-		Model:       modelConfig.Model,
-		MaxTokens:   1024, // Limit summary length
-		Temperature: 0.3,  // Lower temperature for more focused summary
+		return "", err
 	}
+	adapterCfg.MaxTokens = 1024 // Limit summary length
+	summaryTemperature := 0.3   // Lower temperature for more focused summary
+	adapterCfg.Sampling = modelConfig.EffectiveSampling().Merge(model.SamplingParams{Temperature: &summaryTemperature})
 
 	llmAdapter, err := s.adapterFactory.Create(modelConfig.Provider.Type, adapterCfg)
 	if err != nil {
 		return "", fmt.Errorf("failed to create adapter: %w", err)
 	}
+	llmAdapter = s.usageService.Meter(s.providerService.Redact(modelConfig.Provider, llmAdapter), modelConfig, sessionID)
 
 	// Build conversation text
 	var conversationParts []string
@@ -100,38 +142,24 @@ func (s *SummarizeService) SummarizeSession(ctx context.Context, sessionID strin
 	conversation := strings.Join(conversationParts, "\n\n")
 
 	// Create summarization prompt
+	systemPrompt, err := s.prompts.Render(prompt.SummarySystem, nil)
+	if err != nil {
+		return "", err
+	}
+	userPrompt, err := s.prompts.Render(prompt.SummaryUser, map[string]any{"Conversation": conversation})
+	if err != nil {
+		return "", err
+	}
 	messages := []model.Message{
-		{
-			Role: model.RoleSystem,
-			Content: `You are a helpful assistant that summarizes conversations.
-Create a concise summary that captures:
-1. Main topics discussed
-2. Key decisions or conclusions
-3. Important information shared
-4. Any action items or follow-ups
-
-Keep the summary brief but comprehensive. Write in a neutral, factual tone.`,
-		},
-		{
-			Role:    model.RoleUser,
-			Content: fmt.Sprintf("Please summarize the following conversation:\n\n%s", conversation),
-		},
+		{Role: model.RoleSystem, Content: systemPrompt},
+		{Role: model.RoleUser, Content: userPrompt},
 	}
 
 	// Generate summary
-	resp, err := llmAdapter.Chat(ctx, messages)
+	resp, err := llmAdapter.Chat(adapter.WithUsagePurpose(ctx, model.UsagePurposeSummarize), messages)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate summary: %w", err)
 	}
 
-	summary := resp.Message.Content
-
-	// Update session with summary
-	session.Summary = summary
-	if err := s.sessionRepo.Update(session); err != nil {
-		return "", fmt.Errorf("failed to update session: %w", err)
-	}
-
-	return summary, nil
+	return resp.Message.Content, nil
 }
-
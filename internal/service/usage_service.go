@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/adapter"
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/jsonschema"
+	"github.com/allwaysyou/llm-agent/internal/repository"
+	"github.com/google/uuid"
+)
+
+// UsageService handles token usage and cost accounting
+type UsageService struct {
+	repo   *repository.UsageRepository
+	limits *LimitService
+}
+
+// NewUsageService creates a new usage service
+func NewUsageService(repo *repository.UsageRepository, limits *LimitService) *UsageService {
+	return &UsageService{repo: repo, limits: limits}
+}
+
+// CheckLimits verifies the budgets and rate limits before an LLM call is made
+func (s *UsageService) CheckLimits(modelConfig *model.ModelConfig, purpose model.UsagePurpose) error {
+	return s.limits.Check(modelConfig, purpose)
+}
+
+// Record stores the usage of an LLM call made with the given model config
+func (s *UsageService) Record(modelConfig *model.ModelConfig, sessionID, memoryID string, purpose model.UsagePurpose, usage *model.Usage) {
+	if modelConfig == nil || usage == nil {
+		return
+	}
+
+	record := &model.UsageRecord{
+		ID:               uuid.New().String(),
+		SessionID:        sessionID,
+		MemoryID:         memoryID,
+		ConfigID:         modelConfig.ID,
+		ProviderID:       modelConfig.ProviderID,
+		Model:            modelConfig.Model,
+		Purpose:          purpose,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		Cost:             modelConfig.Cost(usage),
+		Currency:         modelConfig.Currency,
+		CreatedAt:        time.Now(),
+	}
+
+	if err := s.repo.Create(record); err != nil {
+		log.Printf("[UsageService:Record] Failed to save usage record: %v", err)
+	}
+}
+
+// Summarize aggregates usage according to the query
+func (s *UsageService) Summarize(q model.UsageQuery) ([]model.UsageSummary, error) {
+	return s.repo.Summarize(q)
+}
+
+// Meter wraps an adapter so that every call made through it is checked against
+// the usage limits and recorded.
+// The purpose of each call is taken from the context (see adapter.WithUsagePurpose).
+func (s *UsageService) Meter(llm adapter.LLMAdapter, modelConfig *model.ModelConfig, sessionID string) adapter.LLMAdapter {
+	return &meteredAdapter{LLMAdapter: llm, usage: s, modelConfig: modelConfig, sessionID: sessionID}
+}
+
+// meteredAdapter records the usage of calls made through the wrapped adapter
+type meteredAdapter struct {
+	adapter.LLMAdapter
+	usage       *UsageService
+	modelConfig *model.ModelConfig
+	sessionID   string
+}
+
+func (a *meteredAdapter) Chat(ctx context.Context, messages []model.Message) (*model.ChatResponse, error) {
+	if err := a.usage.CheckLimits(a.modelConfig, adapter.UsagePurposeFromContext(ctx)); err != nil {
+		return nil, err
+	}
+
+	resp, err := a.LLMAdapter.Chat(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	a.usage.Record(a.modelConfig, a.sessionID, "", adapter.UsagePurposeFromContext(ctx), resp.Usage)
+	return resp, nil
+}
+
+func (a *meteredAdapter) ChatJSON(ctx context.Context, messages []model.Message, format *jsonschema.Format) (*model.ChatResponse, error) {
+	if err := a.usage.CheckLimits(a.modelConfig, adapter.UsagePurposeFromContext(ctx)); err != nil {
+		return nil, err
+	}
+
+	resp, err := a.LLMAdapter.ChatJSON(ctx, messages, format)
+	if err != nil {
+		return nil, err
+	}
+	a.usage.Record(a.modelConfig, a.sessionID, "", adapter.UsagePurposeFromContext(ctx), resp.Usage)
+	return resp, nil
+}
+
+func (a *meteredAdapter) ChatStream(ctx context.Context, messages []model.Message) (<-chan model.StreamChunk, error) {
+	if err := a.usage.CheckLimits(a.modelConfig, adapter.UsagePurposeFromContext(ctx)); err != nil {
+		return nil, err
+	}
+
+	stream, err := a.LLMAdapter.ChatStream(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	purpose := adapter.UsagePurposeFromContext(ctx)
+	out := make(chan model.StreamChunk, cap(stream))
+	go func() {
+		defer close(out)
+		for chunk := range stream {
+			if chunk.Done {
+				a.usage.Record(a.modelConfig, a.sessionID, "", purpose, chunk.Usage)
+			}
+			out <- chunk
+		}
+	}()
+	return out, nil
+}
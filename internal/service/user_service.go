@@ -0,0 +1,372 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/config"
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/vector"
+	"github.com/allwaysyou/llm-agent/internal/repository"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minPasswordLength is the minimum length of a user password
+const minPasswordLength = 8
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserExists         = errors.New("username already exists")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrUnauthenticated    = errors.New("authentication required")
+	ErrInvalidUser        = errors.New("invalid user")
+)
+
+// UserService handles user accounts and login sessions
+type UserService struct {
+	repo        *repository.UserRepository
+	vectorStore *vector.VectorStore
+	config      config.AuthConfig
+}
+
+// NewUserService creates a new user service
+func NewUserService(repo *repository.UserRepository, vectorStore *vector.VectorStore, cfg config.AuthConfig) *UserService {
+	return &UserService{repo: repo, vectorStore: vectorStore, config: cfg}
+}
+
+// Enabled returns true if requests must be authenticated
+func (s *UserService) Enabled() bool {
+	return s.config.Enabled
+}
+
+// LocalUser returns the user all requests act as while authentication is disabled.
+// It owns the rows with an empty UserID.
+func (s *UserService) LocalUser() *model.User {
+	return &model.User{Username: "local", Role: model.UserRoleAdmin}
+}
+
+// Bootstrap creates the first admin when authentication is enabled and no user exists
+// yet, and hands the data created without accounts over to them
+func (s *UserService) Bootstrap() error {
+	if !s.config.Enabled {
+		return nil
+	}
+
+	count, err := s.repo.Count()
+	if err != nil {
+		return fmt.Errorf("failed to count users: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	if s.config.AdminPassword == "" {
+		return fmt.Errorf("auth.admin_password is required to create the first admin")
+	}
+
+	admin, err := s.Create(&model.CreateUserRequest{
+		Username: s.config.AdminUsername,
+		Password: s.config.AdminPassword,
+		Role:     model.UserRoleAdmin,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create admin: %w", err)
+	}
+
+	knowledgeIDs, err := s.repo.ClaimUnowned(admin.ID)
+	if err != nil {
+		return fmt.Errorf("failed to assign existing data to admin: %w", err)
+	}
+	for _, id := range knowledgeIDs {
+		doc, ok := s.vectorStore.Get(id)
+		if !ok || doc.MetaData == nil {
+			continue
+		}
+		metadata := *doc.MetaData
+		metadata.UserID = admin.ID
+		if err := s.vectorStore.UpdateMetadata(id, &metadata); err != nil {
+			log.Printf("[UserService:Bootstrap] Failed to update vector metadata of %s: %v", id, err)
+		}
+	}
+
+	log.Printf("[UserService:Bootstrap] Created admin %q, assigned %d knowledge entries", admin.Username, len(knowledgeIDs))
+	return nil
+}
+
+// Login checks the credentials and starts a login session
+func (s *UserService) Login(req *model.LoginRequest) (*model.LoginResponse, error) {
+	user, err := s.repo.GetByUsername(req.Username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || user.Disabled ||
+		bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	authToken := &model.AuthToken{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(time.Duration(s.config.TokenTTLHours) * time.Hour),
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateToken(authToken); err != nil {
+		return nil, fmt.Errorf("failed to create login session: %w", err)
+	}
+
+	return &model.LoginResponse{Token: token, ExpiresAt: authToken.ExpiresAt, User: *user}, nil
+}
+
+// Authenticate returns the user of a bearer token
+func (s *UserService) Authenticate(token string) (*model.User, error) {
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	authToken, err := s.repo.GetToken(hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login session: %w", err)
+	}
+	if authToken == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	user, err := s.repo.GetByID(authToken.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || user.Disabled {
+		return nil, ErrUnauthenticated
+	}
+
+	if err := s.repo.TouchToken(authToken.ID, time.Now()); err != nil {
+		log.Printf("[UserService:Authenticate] Failed to record token use: %v", err)
+	}
+	return user, nil
+}
+
+// Logout ends the login session of a bearer token
+func (s *UserService) Logout(token string) error {
+	return s.repo.DeleteToken(hashToken(token))
+}
+
+// Create creates a new user
+func (s *UserService) Create(req *model.CreateUserRequest) (*model.User, error) {
+	role := req.Role
+	if role == "" {
+		role = model.UserRoleMember
+	}
+	if role != model.UserRoleAdmin && role != model.UserRoleMember {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidUser, role)
+	}
+
+	existing, err := s.repo.GetByUsername(req.Username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrUserExists
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		ID:           uuid.New().String(),
+		Username:     req.Username,
+		PasswordHash: hash,
+		Role:         role,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if err := s.repo.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// GetAll returns all users
+func (s *UserService) GetAll() ([]model.User, error) {
+	return s.repo.GetAll()
+}
+
+// GetByID retrieves a user by ID
+func (s *UserService) GetByID(id string) (*model.User, error) {
+	user, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// Update updates a user. At least one enabled admin is kept.
+func (s *UserService) Update(id string, req *model.UpdateUserRequest) (*model.User, error) {
+	user, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	wasAdmin := user.IsAdmin() && !user.Disabled
+	endSessions := false
+	if req.Password != nil {
+		hash, err := hashPassword(*req.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.PasswordHash = hash
+		endSessions = true
+	}
+	if req.Role != nil {
+		if *req.Role != model.UserRoleAdmin && *req.Role != model.UserRoleMember {
+			return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidUser, *req.Role)
+		}
+		user.Role = *req.Role
+	}
+	if req.Disabled != nil {
+		endSessions = endSessions || (*req.Disabled && !user.Disabled)
+		user.Disabled = *req.Disabled
+	}
+
+	if wasAdmin && (!user.IsAdmin() || user.Disabled) {
+		if err := s.checkNotLastAdmin(); err != nil {
+			return nil, err
+		}
+	}
+
+	user.UpdatedAt = time.Now()
+	if err := s.repo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	if endSessions {
+		if err := s.repo.DeleteTokensByUser(user.ID); err != nil {
+			return nil, fmt.Errorf("failed to end login sessions: %w", err)
+		}
+	}
+	return user, nil
+}
+
+// Delete deletes a user and all of their data. Users cannot delete themselves and
+// the last admin is kept.
+func (s *UserService) Delete(currentUserID, id string) error {
+	if id == currentUserID {
+		return fmt.Errorf("%w: cannot delete yourself", ErrInvalidUser)
+	}
+	user, err := s.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if user.IsAdmin() && !user.Disabled {
+		if err := s.checkNotLastAdmin(); err != nil {
+			return err
+		}
+	}
+
+	knowledgeIDs, err := s.repo.Delete(id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	for _, knowledgeID := range knowledgeIDs {
+		s.vectorStore.Delete(knowledgeID)
+	}
+	return nil
+}
+
+// ChangePassword changes the password of a user after checking the old one. Their
+// other login sessions are ended.
+func (s *UserService) ChangePassword(userID, currentToken string, req *model.ChangePasswordRequest) error {
+	user, err := s.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.OldPassword)) != nil {
+		return ErrInvalidCredentials
+	}
+
+	hash, err := hashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	user.UpdatedAt = time.Now()
+	if err := s.repo.Update(user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	// Keep the session the change was made from
+	if err := s.repo.DeleteTokensByUser(user.ID); err != nil {
+		return fmt.Errorf("failed to end login sessions: %w", err)
+	}
+	if currentToken != "" {
+		token := &model.AuthToken{
+			ID:        uuid.New().String(),
+			UserID:    user.ID,
+			TokenHash: hashToken(currentToken),
+			ExpiresAt: time.Now().Add(time.Duration(s.config.TokenTTLHours) * time.Hour),
+			CreatedAt: time.Now(),
+		}
+		if err := s.repo.CreateToken(token); err != nil {
+			return fmt.Errorf("failed to keep login session: %w", err)
+		}
+	}
+	return nil
+}
+
+// CleanupExpiredTokens removes expired login sessions
+func (s *UserService) CleanupExpiredTokens() (int64, error) {
+	return s.repo.DeleteExpiredTokens()
+}
+
+// checkNotLastAdmin fails if there is at most one enabled admin left
+func (s *UserService) checkNotLastAdmin() error {
+	count, err := s.repo.CountAdmins()
+	if err != nil {
+		return fmt.Errorf("failed to count admins: %w", err)
+	}
+	if count <= 1 {
+		return fmt.Errorf("%w: the last admin cannot be removed", ErrInvalidUser)
+	}
+	return nil
+}
+
+// hashPassword checks the password length and hashes it with bcrypt
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("%w: password must be at least %d characters", ErrInvalidUser, minPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// randomToken returns a new random bearer token
+func randomToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// hashToken returns the SHA-256 hex digest under which a bearer token is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
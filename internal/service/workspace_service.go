@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/allwaysyou/llm-agent/internal/model"
+	"github.com/allwaysyou/llm-agent/internal/pkg/memory"
+	"github.com/allwaysyou/llm-agent/internal/pkg/vector"
+	"github.com/allwaysyou/llm-agent/internal/repository"
+	"github.com/google/uuid"
+)
+
+// ErrWorkspaceNotFound is returned when a workspace does not exist
+var ErrWorkspaceNotFound = errors.New("workspace not found")
+
+// WorkspaceService handles workspaces and the knowledge scope they give their sessions
+type WorkspaceService struct {
+	repo        *repository.WorkspaceRepository
+	vectorStore *vector.VectorStore
+}
+
+// NewWorkspaceService creates a new workspace service
+func NewWorkspaceService(repo *repository.WorkspaceRepository, vectorStore *vector.VectorStore) *WorkspaceService {
+	return &WorkspaceService{repo: repo, vectorStore: vectorStore}
+}
+
+// Create creates a new workspace of a user
+func (s *WorkspaceService) Create(userID string, req *model.CreateWorkspaceRequest) (*model.Workspace, error) {
+	includeGlobal := true
+	if req.IncludeGlobal != nil {
+		includeGlobal = *req.IncludeGlobal
+	}
+
+	workspace := &model.Workspace{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		Name:          req.Name,
+		Description:   req.Description,
+		IncludeGlobal: includeGlobal,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if err := s.repo.Create(workspace); err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+// GetByID retrieves a workspace of a user by ID
+func (s *WorkspaceService) GetByID(userID, id string) (*model.Workspace, error) {
+	workspace, err := s.repo.GetByID(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+	if workspace == nil {
+		return nil, ErrWorkspaceNotFound
+	}
+	return workspace, nil
+}
+
+// GetAll retrieves the workspaces of a user
+func (s *WorkspaceService) GetAll(userID string) ([]model.Workspace, error) {
+	return s.repo.GetAll(userID)
+}
+
+// Update updates a workspace
+func (s *WorkspaceService) Update(userID, id string, req *model.UpdateWorkspaceRequest) (*model.Workspace, error) {
+	workspace, err := s.GetByID(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		workspace.Name = req.Name
+	}
+	if req.Description != nil {
+		workspace.Description = *req.Description
+	}
+	if req.IncludeGlobal != nil {
+		workspace.IncludeGlobal = *req.IncludeGlobal
+	}
+	workspace.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(workspace); err != nil {
+		return nil, fmt.Errorf("failed to update workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+// Delete deletes a workspace with its sessions and knowledge
+func (s *WorkspaceService) Delete(userID, id string) error {
+	if _, err := s.GetByID(userID, id); err != nil {
+		return err
+	}
+
+	knowledgeIDs, err := s.repo.Delete(id)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+	for _, knowledgeID := range knowledgeIDs {
+		if err := s.vectorStore.Delete(knowledgeID); err != nil {
+			log.Printf("[WorkspaceService:Delete] Failed to delete embedding %s: %v", knowledgeID, err)
+		}
+	}
+
+	log.Printf("[WorkspaceService:Delete] Deleted workspace %s with %d knowledge entries", id, len(knowledgeIDs))
+	return nil
+}
+
+// Scope returns the knowledge scope of a user's workspace ("" = global pool)
+func (s *WorkspaceService) Scope(userID, workspaceID string) (memory.Scope, error) {
+	if workspaceID == "" {
+		return memory.Scope{UserID: userID}, nil
+	}
+	workspace, err := s.GetByID(userID, workspaceID)
+	if err != nil {
+		return memory.Scope{}, err
+	}
+	return memory.Scope{UserID: userID, WorkspaceID: workspace.ID, IncludeGlobal: workspace.IncludeGlobal}, nil
+}
@@ -9,6 +9,28 @@ function getApiBase(): string {
   return `http://127.0.0.1:${port}/api/v1`
 }
 
+// Bearer token of the login session, when the server requires accounts
+export function getAuthToken(): string | null {
+  return localStorage.getItem('authToken')
+}
+
+export function setAuthToken(token: string | null): void {
+  if (token) {
+    localStorage.setItem('authToken', token)
+  } else {
+    localStorage.removeItem('authToken')
+  }
+}
+
+// fetch with the login session's Authorization header
+function apiFetch(input: string, init: RequestInit = {}): Promise<Response> {
+  const token = getAuthToken()
+  if (!token) return fetch(input, init)
+  const headers = new Headers(init.headers)
+  headers.set('Authorization', `Bearer ${token}`)
+  return fetch(input, { ...init, headers })
+}
+
 // Export functions to get/set API port
 export function getApiPort(): string {
   return localStorage.getItem('apiPort') || '18080'
@@ -20,7 +42,7 @@ export function setApiPort(port: string): void {
 
 // Settings API - get port from backend
 export async function getServerPort(): Promise<number> {
-  const res = await fetch(`${getApiBase()}/settings/port`)
+  const res = await apiFetch(`${getApiBase()}/settings/port`)
   if (!res.ok) throw new Error('Failed to get port')
   const data = await res.json()
   return data.port
@@ -28,7 +50,7 @@ export async function getServerPort(): Promise<number> {
 
 // Settings API - save port to backend config
 export async function saveServerPort(port: number): Promise<void> {
-  const res = await fetch(`${getApiBase()}/settings/port`, {
+  const res = await apiFetch(`${getApiBase()}/settings/port`, {
     method: 'PUT',
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify({ port })
@@ -53,13 +75,13 @@ export async function getSystemConfigs(category?: string): Promise<SystemConfig[
   const url = category
     ? `${getApiBaseUrl()}/system-configs/category?category=${category}`
     : `${getApiBaseUrl()}/system-configs`
-  const res = await fetch(url)
+  const res = await apiFetch(url)
   if (!res.ok) throw new Error('Failed to fetch system configs')
   return res.json()
 }
 
 export async function updateSystemConfig(key: string, value: string): Promise<SystemConfig> {
-  const res = await fetch(`${getApiBaseUrl()}/system-configs/${key}`, {
+  const res = await apiFetch(`${getApiBaseUrl()}/system-configs/${key}`, {
     method: 'PUT',
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify({ value })
@@ -71,6 +93,138 @@ export async function updateSystemConfig(key: string, value: string): Promise<Sy
   return res.json()
 }
 
+// Auth API
+export type UserRole = 'admin' | 'member'
+
+export interface User {
+  id: string
+  username: string
+  role: UserRole
+  disabled: boolean
+  created_at: string
+  updated_at: string
+}
+
+export async function login(username: string, password: string): Promise<User> {
+  const res = await apiFetch(`${getApiBaseUrl()}/auth/login`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ username, password })
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to log in')
+  }
+  const data = await res.json()
+  setAuthToken(data.token)
+  return data.user
+}
+
+export async function logout(): Promise<void> {
+  await apiFetch(`${getApiBaseUrl()}/auth/logout`, { method: 'POST' })
+  setAuthToken(null)
+}
+
+export async function getCurrentUser(): Promise<{ user: User; auth_enabled: boolean }> {
+  const res = await apiFetch(`${getApiBaseUrl()}/auth/me`)
+  if (!res.ok) throw new Error('Not logged in')
+  return res.json()
+}
+
+export async function changePassword(oldPassword: string, newPassword: string): Promise<void> {
+  const res = await apiFetch(`${getApiBaseUrl()}/auth/password`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ old_password: oldPassword, new_password: newPassword })
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to change password')
+  }
+}
+
+// User management API (admins only)
+export async function getUsers(): Promise<User[]> {
+  const res = await apiFetch(`${getApiBaseUrl()}/users`)
+  if (!res.ok) throw new Error('Failed to fetch users')
+  return res.json()
+}
+
+export async function createUser(data: { username: string; password: string; role?: UserRole }): Promise<User> {
+  const res = await apiFetch(`${getApiBaseUrl()}/users`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(data)
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to create user')
+  }
+  return res.json()
+}
+
+export async function updateUser(id: string, data: { password?: string; role?: UserRole; disabled?: boolean }): Promise<User> {
+  const res = await apiFetch(`${getApiBaseUrl()}/users/${id}`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(data)
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to update user')
+  }
+  return res.json()
+}
+
+export async function deleteUser(id: string): Promise<void> {
+  const res = await apiFetch(`${getApiBaseUrl()}/users/${id}`, { method: 'DELETE' })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to delete user')
+  }
+}
+
+// Personal access token API
+export type TokenScope = 'chat' | 'knowledge:read' | 'knowledge:write' | 'admin'
+
+export interface AccessToken {
+  id: string
+  name: string
+  hint: string
+  scopes: TokenScope[]
+  expires_at: string | null
+  last_used_at: string | null
+  created_at: string
+  token?: string // Only returned on creation
+}
+
+export async function getAccessTokens(): Promise<AccessToken[]> {
+  const res = await apiFetch(`${getApiBaseUrl()}/tokens`)
+  if (!res.ok) throw new Error('Failed to fetch access tokens')
+  return res.json()
+}
+
+export async function createAccessToken(data: { name: string; scopes: TokenScope[]; expires_in_days?: number }): Promise<AccessToken> {
+  const res = await apiFetch(`${getApiBaseUrl()}/tokens`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(data)
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to create access token')
+  }
+  return res.json()
+}
+
+export async function deleteAccessToken(id: string): Promise<void> {
+  const res = await apiFetch(`${getApiBaseUrl()}/tokens/${id}`, { method: 'DELETE' })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to delete access token')
+  }
+}
+
 // Use getter to allow dynamic port changes
 const getApiBaseUrl = () => getApiBase()
 
@@ -79,8 +233,11 @@ let API_BASE = getApiBase()
 
 export interface Message {
   id?: string
+  parent_id?: string
   role: 'user' | 'assistant' | 'system'
   content: string
+  reasoning?: string
+  sibling_ids?: string[]
 }
 
 export interface Session {
@@ -90,12 +247,55 @@ export interface Session {
   summary: string
   created_at: string
   updated_at: string
+  active_leaf_id?: string
+  workspace_id?: string
+  forked_from_id?: string
+  fork_message_id?: string
+  merged_at?: string
+  fork_ids?: string[]
+  system_prompt?: string
+  persona_id?: string
+  override_config_id?: string
+  sampling?: SamplingParams
+  imported_from?: string // "chatgpt:<id>" or "claude:<id>" for imported conversations
+}
+
+export interface SessionSettings {
+  title?: string
+  workspace_id?: string // Only when creating a session
+  system_prompt?: string
+  persona_id?: string
+  override_config_id?: string
+  sampling?: SamplingParams
+}
+
+// Reusable system prompt and sampling template
+export interface Persona {
+  id: string
+  name: string
+  description: string
+  system_prompt: string
+  sampling: SamplingParams
+  shared: boolean
+  created_at: string
+  updated_at: string
+}
+
+export interface PersonaRequest {
+  name?: string
+  description?: string
+  system_prompt?: string
+  sampling?: SamplingParams
+  shared?: boolean // Admins only, when creating
 }
 
 export type ConfigType = 'chat' | 'summarize' | 'embedding'
-export type ProviderType = 'openai' | 'claude' | 'azure' | 'ollama' | 'custom'
+export type ProviderType = 'openai' | 'claude' | 'azure' | 'ollama' | 'gemini' | 'custom'
 
 // Provider types
+// PII redaction of prompts: '' follows the server default (cloud providers only)
+export type RedactionMode = '' | 'on' | 'off'
+
 export interface Provider {
   id: string
   name: string
@@ -103,6 +303,8 @@ export interface Provider {
   base_url: string
   enabled: boolean
   has_api_key: boolean
+  shared: boolean
+  redaction: RedactionMode
   created_at: string
   updated_at: string
   models?: ModelConfig[]
@@ -111,9 +313,11 @@ export interface Provider {
 export interface CreateProviderRequest {
   name: string
   type: ProviderType
-  api_key: string
+  api_key?: string // Not needed for ollama and custom providers with auth_scheme none
   base_url?: string
   enabled?: boolean
+  redaction?: RedactionMode
+  shared?: boolean // Admins only
 }
 
 export interface UpdateProviderRequest {
@@ -122,6 +326,19 @@ export interface UpdateProviderRequest {
   api_key?: string
   base_url?: string
   enabled?: boolean
+  redaction?: RedactionMode
+}
+
+export interface SamplingParams {
+  temperature?: number
+  top_p?: number
+  top_k?: number
+  stop?: string[]
+  presence_penalty?: number
+  frequency_penalty?: number
+  seed?: number
+  reasoning_effort?: 'low' | 'medium' | 'high'
+  thinking_budget?: number
 }
 
 // New ModelConfig types
@@ -131,6 +348,7 @@ export interface ModelConfig {
   model: string
   max_tokens: number
   temperature: number
+  sampling?: SamplingParams
   config_type: ConfigType
   is_default: boolean
   created_at: string
@@ -143,6 +361,7 @@ export interface CreateModelConfigRequest {
   model: string
   max_tokens?: number
   temperature?: number
+  sampling?: SamplingParams
   config_type?: ConfigType
   is_default?: boolean
 }
@@ -151,6 +370,7 @@ export interface UpdateModelConfigRequest {
   model?: string
   max_tokens?: number
   temperature?: number
+  sampling?: SamplingParams
   config_type?: ConfigType
   is_default?: boolean
 }
@@ -158,8 +378,11 @@ export interface UpdateModelConfigRequest {
 export interface ChatRequest {
   session_id?: string
   config_id?: string
+  parent_id?: string
+  workspace_id?: string
   messages: Message[]
   stream?: boolean
+  sampling?: SamplingParams
 }
 
 export interface ChatResponse {
@@ -177,6 +400,9 @@ export interface StreamChunk {
   id: string
   delta: string
   done: boolean
+  reasoning?: string
+  error?: string // Set on the final chunk when the provider stopped the answer; it is not saved
+  error_kind?: string
 }
 
 export interface TestResult {
@@ -185,15 +411,23 @@ export interface TestResult {
   error?: string
 }
 
+export interface ProviderModel {
+  id: string
+  display_name?: string
+  context_window?: number
+  chat: boolean
+  embedding: boolean
+}
+
 // Provider API
 export async function getProviders(): Promise<Provider[]> {
-  const res = await fetch(`${getApiBaseUrl()}/providers`)
+  const res = await apiFetch(`${getApiBaseUrl()}/providers`)
   if (!res.ok) throw new Error('Failed to fetch providers')
   return res.json()
 }
 
 export async function createProvider(provider: CreateProviderRequest): Promise<Provider> {
-  const res = await fetch(`${getApiBaseUrl()}/providers`, {
+  const res = await apiFetch(`${getApiBaseUrl()}/providers`, {
     method: 'POST',
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify(provider)
@@ -206,7 +440,7 @@ export async function createProvider(provider: CreateProviderRequest): Promise<P
 }
 
 export async function updateProvider(id: string, provider: UpdateProviderRequest): Promise<Provider> {
-  const res = await fetch(`${getApiBaseUrl()}/providers/${id}`, {
+  const res = await apiFetch(`${getApiBaseUrl()}/providers/${id}`, {
     method: 'PUT',
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify(provider)
@@ -219,12 +453,12 @@ export async function updateProvider(id: string, provider: UpdateProviderRequest
 }
 
 export async function deleteProvider(id: string): Promise<void> {
-  const res = await fetch(`${getApiBaseUrl()}/providers/${id}`, { method: 'DELETE' })
+  const res = await apiFetch(`${getApiBaseUrl()}/providers/${id}`, { method: 'DELETE' })
   if (!res.ok) throw new Error('Failed to delete provider')
 }
 
 export async function testProvider(id: string): Promise<TestResult> {
-  const res = await fetch(`${getApiBaseUrl()}/providers/${id}/test`, { method: 'POST' })
+  const res = await apiFetch(`${getApiBaseUrl()}/providers/${id}/test`, { method: 'POST' })
   if (!res.ok) {
     const error = await res.json()
     throw new Error(error.error || 'Test failed')
@@ -232,18 +466,28 @@ export async function testProvider(id: string): Promise<TestResult> {
   return res.json()
 }
 
+export async function listProviderModels(id: string, refresh = false): Promise<ProviderModel[]> {
+  const query = refresh ? '?refresh=true' : ''
+  const res = await apiFetch(`${getApiBaseUrl()}/providers/${id}/models${query}`)
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to list models')
+  }
+  return res.json()
+}
+
 // Model Config API
 export async function getModels(providerId?: string): Promise<ModelConfig[]> {
   const url = providerId
     ? `${getApiBaseUrl()}/models?provider_id=${providerId}`
     : `${getApiBaseUrl()}/models`
-  const res = await fetch(url)
+  const res = await apiFetch(url)
   if (!res.ok) throw new Error('Failed to fetch models')
   return res.json()
 }
 
 export async function createModel(model: CreateModelConfigRequest): Promise<ModelConfig> {
-  const res = await fetch(`${getApiBaseUrl()}/models`, {
+  const res = await apiFetch(`${getApiBaseUrl()}/models`, {
     method: 'POST',
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify(model)
@@ -256,7 +500,7 @@ export async function createModel(model: CreateModelConfigRequest): Promise<Mode
 }
 
 export async function updateModel(id: string, model: UpdateModelConfigRequest): Promise<ModelConfig> {
-  const res = await fetch(`${getApiBaseUrl()}/models/${id}`, {
+  const res = await apiFetch(`${getApiBaseUrl()}/models/${id}`, {
     method: 'PUT',
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify(model)
@@ -269,17 +513,17 @@ export async function updateModel(id: string, model: UpdateModelConfigRequest):
 }
 
 export async function deleteModel(id: string): Promise<void> {
-  const res = await fetch(`${getApiBaseUrl()}/models/${id}`, { method: 'DELETE' })
+  const res = await apiFetch(`${getApiBaseUrl()}/models/${id}`, { method: 'DELETE' })
   if (!res.ok) throw new Error('Failed to delete model')
 }
 
 export async function setDefaultModel(id: string): Promise<void> {
-  const res = await fetch(`${getApiBaseUrl()}/models/${id}/default`, { method: 'POST' })
+  const res = await apiFetch(`${getApiBaseUrl()}/models/${id}/default`, { method: 'POST' })
   if (!res.ok) throw new Error('Failed to set default model')
 }
 
 export async function testModel(id: string): Promise<TestResult> {
-  const res = await fetch(`${getApiBaseUrl()}/models/${id}/test`, { method: 'POST' })
+  const res = await apiFetch(`${getApiBaseUrl()}/models/${id}/test`, { method: 'POST' })
   if (!res.ok) {
     const error = await res.json()
     throw new Error(error.error || 'Test failed')
@@ -288,39 +532,293 @@ export async function testModel(id: string): Promise<TestResult> {
 }
 
 // Session API
-export async function getSessions(): Promise<Session[]> {
-  const res = await fetch(`${getApiBaseUrl()}/sessions`)
+// Workspace groups sessions and knowledge; '' selects the global pool, undefined all workspaces
+export interface Workspace {
+  id: string
+  name: string
+  description: string
+  include_global: boolean
+  created_at: string
+  updated_at: string
+}
+
+export async function getWorkspaces(): Promise<Workspace[]> {
+  const res = await apiFetch(`${getApiBaseUrl()}/workspaces`)
+  if (!res.ok) throw new Error('Failed to fetch workspaces')
+  return res.json()
+}
+
+export async function createWorkspace(data: { name: string; description?: string; include_global?: boolean }): Promise<Workspace> {
+  const res = await apiFetch(`${getApiBaseUrl()}/workspaces`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(data)
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to create workspace')
+  }
+  return res.json()
+}
+
+export async function updateWorkspace(id: string, data: { name?: string; description?: string; include_global?: boolean }): Promise<Workspace> {
+  const res = await apiFetch(`${getApiBaseUrl()}/workspaces/${id}`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(data)
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to update workspace')
+  }
+  return res.json()
+}
+
+// Delete a workspace together with its sessions and knowledge
+export async function deleteWorkspace(id: string): Promise<void> {
+  const res = await apiFetch(`${getApiBaseUrl()}/workspaces/${id}`, { method: 'DELETE' })
+  if (!res.ok) throw new Error('Failed to delete workspace')
+}
+
+function workspaceParam(workspaceId?: string): string {
+  return workspaceId === undefined ? '' : `workspace_id=${encodeURIComponent(workspaceId)}`
+}
+
+export async function getSessions(workspaceId?: string): Promise<Session[]> {
+  const res = await apiFetch(`${getApiBaseUrl()}/sessions?${workspaceParam(workspaceId)}`)
   if (!res.ok) throw new Error('Failed to fetch sessions')
   return res.json()
 }
 
 export async function getSession(id: string): Promise<{ session: Session; messages: Message[] }> {
-  const res = await fetch(`${getApiBaseUrl()}/sessions/${id}`)
+  const res = await apiFetch(`${getApiBaseUrl()}/sessions/${id}`)
   if (!res.ok) throw new Error('Failed to fetch session')
   return res.json()
 }
 
+// Create an empty session with its settings before sending the first message
+export async function createSession(settings: SessionSettings = {}): Promise<Session> {
+  const res = await apiFetch(`${getApiBaseUrl()}/sessions`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(settings)
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to create session')
+  }
+  return res.json()
+}
+
+// Update a session's title, system prompt, persona, model and sampling overrides.
+// An empty string clears the persona or model override.
+export async function updateSession(id: string, settings: SessionSettings): Promise<Session> {
+  const res = await apiFetch(`${getApiBaseUrl()}/sessions/${id}`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(settings)
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to update session')
+  }
+  return res.json()
+}
+
 export async function deleteSession(id: string): Promise<void> {
-  const res = await fetch(`${getApiBaseUrl()}/sessions/${id}`, { method: 'DELETE' })
+  const res = await apiFetch(`${getApiBaseUrl()}/sessions/${id}`, { method: 'DELETE' })
   if (!res.ok) throw new Error('Failed to delete session')
 }
 
 export async function deleteMessage(sessionId: string, messageId: string): Promise<void> {
-  const res = await fetch(`${getApiBaseUrl()}/sessions/${sessionId}/messages/${messageId}`, { method: 'DELETE' })
+  const res = await apiFetch(`${getApiBaseUrl()}/sessions/${sessionId}/messages/${messageId}`, { method: 'DELETE' })
   if (!res.ok) throw new Error('Failed to delete message')
 }
 
+export async function switchBranch(sessionId: string, messageId: string): Promise<{ session: Session; messages: Message[] }> {
+  const res = await apiFetch(`${getApiBaseUrl()}/sessions/${sessionId}/branch`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ message_id: messageId })
+  })
+  if (!res.ok) throw new Error('Failed to switch branch')
+  return res.json()
+}
+
+// Copy a session's conversation up to a message (default: end of the active branch) into a new session
+export async function forkSession(sessionId: string, messageId?: string, title?: string): Promise<Session> {
+  const res = await apiFetch(`${getApiBaseUrl()}/sessions/${sessionId}/fork`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ message_id: messageId, title })
+  })
+  if (!res.ok) throw new Error('Failed to fork session')
+  return res.json()
+}
+
+// Summarize a fork and append the summary to the session it was forked from
+export async function mergeSession(forkId: string): Promise<Session> {
+  const res = await apiFetch(`${getApiBaseUrl()}/sessions/${forkId}/merge`, { method: 'POST' })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to merge session')
+  }
+  return res.json()
+}
+
+// Persona API
+export async function getPersonas(): Promise<Persona[]> {
+  const res = await apiFetch(`${getApiBaseUrl()}/personas`)
+  if (!res.ok) throw new Error('Failed to fetch personas')
+  return res.json()
+}
+
+export async function createPersona(data: PersonaRequest): Promise<Persona> {
+  const res = await apiFetch(`${getApiBaseUrl()}/personas`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(data)
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to create persona')
+  }
+  return res.json()
+}
+
+export async function updatePersona(id: string, data: PersonaRequest): Promise<Persona> {
+  const res = await apiFetch(`${getApiBaseUrl()}/personas/${id}`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(data)
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to update persona')
+  }
+  return res.json()
+}
+
+export async function deletePersona(id: string): Promise<void> {
+  const res = await apiFetch(`${getApiBaseUrl()}/personas/${id}`, { method: 'DELETE' })
+  if (!res.ok) throw new Error('Failed to delete persona')
+}
+
+// Prompt template API
+export interface PromptTemplate {
+  id: string
+  name: string
+  language: string
+  version: number
+  kind: 'builtin' | 'slash'
+  description: string
+  content: string
+  active: boolean
+  seeded: boolean // Stored from the compiled-in default of a built-in prompt
+  created_at: string
+}
+
+export async function getPrompts(kind?: 'builtin' | 'slash', language?: string): Promise<PromptTemplate[]> {
+  const params = new URLSearchParams()
+  if (kind) params.set('kind', kind)
+  if (language) params.set('language', language)
+  const res = await apiFetch(`${getApiBaseUrl()}/prompts?${params}`)
+  if (!res.ok) throw new Error('Failed to fetch prompts')
+  return res.json()
+}
+
+export async function createPrompt(data: { name: string; language: string; description?: string; content: string }): Promise<PromptTemplate> {
+  const res = await apiFetch(`${getApiBaseUrl()}/prompts`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(data)
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to create prompt')
+  }
+  return res.json()
+}
+
+// Save a new version of a prompt
+export async function updatePrompt(id: string, data: { description?: string; content: string }): Promise<PromptTemplate> {
+  const res = await apiFetch(`${getApiBaseUrl()}/prompts/${id}`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(data)
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to update prompt')
+  }
+  return res.json()
+}
+
+export async function getPromptVersions(id: string): Promise<PromptTemplate[]> {
+  const res = await apiFetch(`${getApiBaseUrl()}/prompts/${id}/versions`)
+  if (!res.ok) throw new Error('Failed to fetch prompt versions')
+  return res.json()
+}
+
+export async function activatePrompt(id: string): Promise<PromptTemplate> {
+  const res = await apiFetch(`${getApiBaseUrl()}/prompts/${id}/activate`, { method: 'POST' })
+  if (!res.ok) throw new Error('Failed to activate prompt')
+  return res.json()
+}
+
+// Fill in a prompt's variables, e.g. for a slash prompt typed in the chat input
+export async function renderPrompt(id: string, variables: Record<string, unknown>): Promise<string> {
+  const res = await apiFetch(`${getApiBaseUrl()}/prompts/${id}/render`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ variables })
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to render prompt')
+  }
+  const data = await res.json()
+  return data.content
+}
+
+export async function deletePrompt(id: string): Promise<void> {
+  const res = await apiFetch(`${getApiBaseUrl()}/prompts/${id}`, { method: 'DELETE' })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to delete prompt')
+  }
+}
+
 // Chat API
 export interface StreamResult {
   stream: AsyncGenerator<StreamChunk>
   sessionId: string | null
 }
 
+export interface BranchOptions {
+  config_id?: string
+  sampling?: SamplingParams
+}
+
 export async function chatStream(request: ChatRequest): Promise<StreamResult> {
-  const res = await fetch(`${getApiBaseUrl()}/chat`, {
+  return openChatStream(`${getApiBaseUrl()}/chat`, { ...request, stream: true })
+}
+
+// Generate another reply to a message on a new branch
+export async function regenerateMessage(sessionId: string, messageId: string, options: BranchOptions = {}): Promise<StreamResult> {
+  return openChatStream(`${getApiBaseUrl()}/sessions/${sessionId}/messages/${messageId}/regenerate`, { ...options, stream: true })
+}
+
+// Send edited content for a user message on a new branch
+export async function editMessage(sessionId: string, messageId: string, content: string, options: BranchOptions = {}): Promise<StreamResult> {
+  return openChatStream(`${getApiBaseUrl()}/sessions/${sessionId}/messages/${messageId}/edit`, { ...options, content, stream: true })
+}
+
+async function openChatStream(url: string, body: object): Promise<StreamResult> {
+  const res = await apiFetch(url, {
     method: 'POST',
     headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ ...request, stream: true })
+    body: JSON.stringify(body)
   })
 
   if (!res.ok) {
@@ -336,6 +834,7 @@ export async function chatStream(request: ChatRequest): Promise<StreamResult> {
 
   async function* generateChunks(): AsyncGenerator<StreamChunk> {
     let buffer = ''
+    let event = 'message'
 
     while (true) {
       const { done, value } = await reader!.read()
@@ -346,11 +845,19 @@ export async function chatStream(request: ChatRequest): Promise<StreamResult> {
       buffer = lines.pop() || ''
 
       for (const line of lines) {
-        if (line.startsWith('event: message')) continue
+        if (line.startsWith('event: ')) {
+          event = line.slice(7).trim()
+          continue
+        }
         if (line.startsWith('data: ')) {
           try {
             const data = JSON.parse(line.slice(6))
-            yield data as StreamChunk
+            // Reasoning events carry thinking in delta; keep it apart from the answer
+            if (event === 'reasoning') {
+              yield { id: data.id, delta: '', done: false, reasoning: data.delta } as StreamChunk
+            } else {
+              yield data as StreamChunk
+            }
           } catch {
             // Skip invalid JSON
           }
@@ -369,22 +876,23 @@ export async function chatStream(request: ChatRequest): Promise<StreamResult> {
 export interface Knowledge {
   id: string
   content: string
+  workspace_id: string
   superseded_by: string
   created_at: string
   updated_at: string
 }
 
-export async function getKnowledge(activeOnly = true, limit = 100): Promise<Knowledge[]> {
-  const res = await fetch(`${getApiBaseUrl()}/knowledge?active_only=${activeOnly}&limit=${limit}`)
+export async function getKnowledge(activeOnly = true, limit = 100, workspaceId?: string): Promise<Knowledge[]> {
+  const res = await apiFetch(`${getApiBaseUrl()}/knowledge?active_only=${activeOnly}&limit=${limit}&${workspaceParam(workspaceId)}`)
   if (!res.ok) throw new Error('Failed to fetch knowledge')
   return res.json()
 }
 
-export async function createKnowledge(content: string): Promise<Knowledge> {
-  const res = await fetch(`${getApiBaseUrl()}/knowledge`, {
+export async function createKnowledge(content: string, workspaceId = ''): Promise<Knowledge> {
+  const res = await apiFetch(`${getApiBaseUrl()}/knowledge`, {
     method: 'POST',
     headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ content })
+    body: JSON.stringify({ content, workspace_id: workspaceId })
   })
   if (!res.ok) {
     const error = await res.json()
@@ -394,7 +902,7 @@ export async function createKnowledge(content: string): Promise<Knowledge> {
 }
 
 export async function updateKnowledge(id: string, content: string): Promise<Knowledge> {
-  const res = await fetch(`${getApiBaseUrl()}/knowledge/${id}`, {
+  const res = await apiFetch(`${getApiBaseUrl()}/knowledge/${id}`, {
     method: 'PUT',
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify({ content })
@@ -407,6 +915,148 @@ export async function updateKnowledge(id: string, content: string): Promise<Know
 }
 
 export async function deleteKnowledge(id: string): Promise<void> {
-  const res = await fetch(`${getApiBaseUrl()}/knowledge/${id}`, { method: 'DELETE' })
+  const res = await apiFetch(`${getApiBaseUrl()}/knowledge/${id}`, { method: 'DELETE' })
   if (!res.ok) throw new Error('Failed to delete knowledge')
 }
+
+export type KnowledgeFormat = 'jsonl' | 'markdown' | 'csv'
+
+export interface KnowledgeImportResult {
+  total: number
+  created: number
+  superseded: number
+  skipped: number
+  errors?: string[]
+  stopped?: boolean // Canceled or a usage or rate limit ended the import early
+}
+
+// Downloads the knowledge, including superseded entries unless activeOnly is set
+export async function exportKnowledge(format: KnowledgeFormat = 'jsonl', workspaceId?: string, activeOnly = false): Promise<Blob> {
+  const res = await apiFetch(`${getApiBaseUrl()}/knowledge/export?format=${format}&active_only=${activeOnly}&${workspaceParam(workspaceId)}`)
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to export knowledge')
+  }
+  return res.blob()
+}
+
+// Imports a knowledge file, skipping duplicates; the format follows the file extension.
+// Without a workspace, entries go to the workspace they name if it exists.
+export async function importKnowledge(file: File, workspaceId?: string, configId = ''): Promise<KnowledgeImportResult> {
+  const form = new FormData()
+  form.append('file', file)
+  const config = configId ? `&config_id=${encodeURIComponent(configId)}` : ''
+  const res = await apiFetch(`${getApiBaseUrl()}/knowledge/import?${workspaceParam(workspaceId)}${config}`, {
+    method: 'POST',
+    body: form
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to import knowledge')
+  }
+  return res.json()
+}
+
+// Chat history import API
+
+export interface ExtractionJob {
+  id: string
+  workspace_id: string
+  status: 'running' | 'completed' | 'failed' | 'canceled'
+  turns: number
+  processed: number
+  failed: number
+  error?: string
+  created_at: string
+  finished_at?: string
+}
+
+export interface HistoryImportResult {
+  source: 'chatgpt' | 'claude'
+  conversations: number
+  imported: number
+  skipped: number
+  messages: number
+  errors?: string[]
+  job?: ExtractionJob
+}
+
+// Imports conversations.json of a ChatGPT or Claude data export, or the export zip.
+// With extract set, knowledge is extracted from the imported turns in the background.
+export async function importHistory(file: File, workspaceId = '', extract = false, configId = ''): Promise<HistoryImportResult> {
+  const form = new FormData()
+  form.append('file', file)
+  const params = new URLSearchParams({ workspace_id: workspaceId, extract: String(extract) })
+  if (configId) params.set('config_id', configId)
+  const res = await apiFetch(`${getApiBaseUrl()}/history/import?${params}`, {
+    method: 'POST',
+    body: form
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to import chat history')
+  }
+  return res.json()
+}
+
+export async function getExtractionJobs(): Promise<ExtractionJob[]> {
+  const res = await apiFetch(`${getApiBaseUrl()}/history/import/jobs`)
+  if (!res.ok) throw new Error('Failed to fetch extraction jobs')
+  return res.json()
+}
+
+export async function getExtractionJob(id: string): Promise<ExtractionJob> {
+  const res = await apiFetch(`${getApiBaseUrl()}/history/import/jobs/${id}`)
+  if (!res.ok) throw new Error('Failed to fetch extraction job')
+  return res.json()
+}
+
+export async function cancelExtractionJob(id: string): Promise<ExtractionJob> {
+  const res = await apiFetch(`${getApiBaseUrl()}/history/import/jobs/${id}`, { method: 'DELETE' })
+  if (!res.ok) throw new Error('Failed to cancel extraction job')
+  return res.json()
+}
+
+// Backup API (admins only)
+
+export interface BackupManifest {
+  format_version: number
+  created_at: string
+  key_id: string
+  passphrase_salt?: string
+  at_rest: boolean
+  documents: number
+}
+
+export interface RestoreResult {
+  manifest: BackupManifest
+  documents: number
+}
+
+// Downloads a zip archive of all data, encrypted with the passphrase if given
+export async function downloadBackup(passphrase = ''): Promise<Blob> {
+  const headers: Record<string, string> = {}
+  if (passphrase) headers['X-Backup-Passphrase'] = passphrase
+  const res = await apiFetch(`${getApiBaseUrl()}/admin/backup`, { headers })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to create backup')
+  }
+  return res.blob()
+}
+
+// Replaces all data with a backup archive
+export async function restoreBackup(file: File, passphrase = ''): Promise<RestoreResult> {
+  const form = new FormData()
+  form.append('file', file)
+  if (passphrase) form.append('passphrase', passphrase)
+  const res = await apiFetch(`${getApiBaseUrl()}/admin/restore`, {
+    method: 'POST',
+    body: form
+  })
+  if (!res.ok) {
+    const error = await res.json()
+    throw new Error(error.error || 'Failed to restore backup')
+  }
+  return res.json()
+}